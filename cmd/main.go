@@ -4,13 +4,24 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/hmsoft0815/wollmilchsau/internal/bundler"
+	"github.com/hmsoft0815/wollmilchsau/internal/config"
+	"github.com/hmsoft0815/wollmilchsau/internal/executor"
 	mcpserver "github.com/hmsoft0815/wollmilchsau/internal/server"
+	"github.com/hmsoft0815/wollmilchsau/internal/typecheck"
 	"github.com/mark3labs/mcp-go/server"
 	v8 "rogchap.com/v8go"
 )
@@ -18,10 +29,31 @@ import (
 func main() {
 	versionFlag := flag.Bool("version", false, "Show version information")
 	dumpFlag := flag.Bool("dump", false, "Dump MCP tool schema")
+	dumpTypesFlag := flag.Bool("dump-types", false, "Dump the TypeScript declarations of the sandbox globals (wollmilchsau.d.ts)")
 	addrFlag := flag.String("addr", "", "Listen address for SSE (e.g. ':8080'). If empty, uses stdio.")
+	publicURLFlag := flag.String("public-url", "", "URL under which clients reach the SSE server (e.g. 'https://sandbox.example.com'; default http://<addr> with localhost for an empty or unspecified host)")
 	logDirFlag := flag.String("log-dir", "", "Directory to store complete request/response ZIP archives (optional)")
 	enableArtifactsFlag := flag.Bool("enable-artifacts", false, "Enable the artifact service integration (artifact global object and execute_artifact tool)")
 	artifactAddrFlag := flag.String("artifact-addr", "", "Address of the mlcartifact gRPC server (optional, default uses local or env)")
+	artifactStoreFlag := flag.String("artifact-store", "service", "Artifact backend: service (mlcartifact), dir or memory")
+	artifactDirFlag := flag.String("artifact-dir", "", "Directory of the dir artifact store")
+	poolSizeFlag := flag.Int("pool-size", mcpserver.DefaultPoolSize, "Number of warm V8 isolates kept ready for execution (0 disables pooling)")
+	bundleCacheFlag := flag.Int("bundle-cache-size", bundler.DefaultCacheSize, "Number of bundles cached for repeated runs of the same code (0 disables the cache)")
+	moduleDirFlag := flag.String("module-dir", "", "Directory of vetted npm packages that scripts may import, laid out like node_modules (optional)")
+	stdlibDirFlag := flag.String("stdlib-dir", "", "Directory of helper modules that scripts may import as @std/<name> (optional)")
+	typescriptDirFlag := flag.String("typescript-dir", "", "Directory of the npm typescript package, enables the typecheck option (optional)")
+	configFlag := flag.String("config", "", "Path to a JSON config file (optional, flags take precedence)")
+	maxHeapMBFlag := flag.Int("max-heap-mb", 0, "Maximum V8 heap size per execution in MB (default 128)")
+	maxStackKBFlag := flag.Int("max-stack-kb", 0, "V8 stack size in KB, bounds the maximum call depth (default 984)")
+	maxStdoutFlag := flag.Int("max-stdout-bytes", 0, "Maximum captured stdout per execution in bytes (default 4 MB)")
+	maxStderrFlag := flag.Int("max-stderr-bytes", 0, "Maximum captured stderr per execution in bytes (default 1 MB)")
+	maxWallTimeFlag := flag.Int("max-wall-time-ms", 0, "Maximum wall time per execution in milliseconds (default 30000)")
+	maxCPUTimeFlag := flag.Int("max-cpu-time-ms", 0, "Maximum JavaScript CPU time per execution in milliseconds (default 30000)")
+	outputBudgetBytesFlag := flag.Int("output-budget-bytes", 0, "Stdout/stderr bytes returned per stream and maximum return value size, the rest is truncated (default 64 KB)")
+	outputBudgetLinesFlag := flag.Int("output-budget-lines", 0, "Stdout/stderr lines returned per stream, the rest is truncated (default 2000)")
+	maxInputFlag := flag.Int("max-input-bytes", 0, "Maximum size of the input parameter in bytes (default 8 MB)")
+	maxArtifactFlag := flag.Int("max-artifact-bytes", 0, "Maximum size written to one openArtifact handle in bytes (default 64 MB)")
+	spillOutputFlag := flag.Bool("spill-output", false, "Save truncated stdout/stderr and return values in full as an artifact (requires -enable-artifacts)")
 	flag.Parse()
 
 	if *versionFlag {
@@ -31,40 +63,228 @@ func main() {
 		return
 	}
 
+	var modules *bundler.Registry
+	if *moduleDirFlag != "" {
+		var err error
+		modules, err = bundler.OpenRegistry(*moduleDirFlag)
+		if err != nil {
+			slog.Error("failed to open module registry", "err", err)
+			os.Exit(1)
+		}
+		slog.Info("module registry opened", "dir", modules.Dir(), "packages", len(modules.Packages()))
+	}
+
+	var stdlib *bundler.Stdlib
+	if *stdlibDirFlag != "" {
+		var err error
+		stdlib, err = bundler.OpenStdlib(*stdlibDirFlag)
+		if err != nil {
+			slog.Error("failed to open stdlib", "err", err)
+			os.Exit(1)
+		}
+		slog.Info("stdlib opened", "dir", stdlib.Dir(), "modules", len(stdlib.Modules()))
+	}
+
+	if *dumpTypesFlag {
+		fmt.Print(mcpserver.GetDeclarations(*enableArtifactsFlag, stdlib))
+		return
+	}
+
+	var checker *typecheck.Checker
+	if *typescriptDirFlag != "" {
+		modulesDir := ""
+		if modules != nil {
+			modulesDir = modules.Dir()
+		}
+		var err error
+		checker, err = typecheck.New(*typescriptDirFlag, modulesDir)
+		if err != nil {
+			slog.Error("failed to load the TypeScript compiler", "err", err)
+			os.Exit(1)
+		}
+		defer checker.Close()
+		slog.Info("TypeScript compiler loaded", "dir", checker.Dir(), "version", checker.Version())
+	}
+
+	var cfg config.File
+	if *configFlag != "" {
+		loaded, err := config.Load(*configFlag)
+		if err != nil {
+			slog.Error("failed to load config", "err", err)
+			os.Exit(1)
+		}
+		cfg = *loaded
+	}
+
 	if *dumpFlag {
-		tools := mcpserver.GetTools(*enableArtifactsFlag)
+		tools := mcpserver.GetTools(*enableArtifactsFlag, bundler.Imports{Modules: modules, Stdlib: stdlib}, checker != nil, cfg.Lint)
 		b, _ := json.MarshalIndent(tools, "", "  ")
 		fmt.Println(string(b))
 		return
 	}
 
-	ws := mcpserver.New(*logDirFlag, *enableArtifactsFlag, *artifactAddrFlag)
+	// Limits: defaults < config file < explicitly set flags.
+	limits := cfg.Limits
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "max-heap-mb":
+			limits.MaxHeapBytes = uint64(*maxHeapMBFlag) * 1024 * 1024
+		case "max-stack-kb":
+			limits.MaxStackKB = *maxStackKBFlag
+		case "max-stdout-bytes":
+			limits.MaxStdoutBytes = *maxStdoutFlag
+		case "max-stderr-bytes":
+			limits.MaxStderrBytes = *maxStderrFlag
+		case "max-wall-time-ms":
+			limits.MaxWallTimeMs = *maxWallTimeFlag
+		case "max-cpu-time-ms":
+			limits.MaxCPUTimeMs = *maxCPUTimeFlag
+		case "output-budget-bytes":
+			limits.OutputBudgetBytes = *outputBudgetBytesFlag
+		case "output-budget-lines":
+			limits.OutputBudgetLines = *outputBudgetLinesFlag
+		case "max-input-bytes":
+			limits.MaxInputBytes = *maxInputFlag
+		case "max-artifact-bytes":
+			limits.MaxArtifactBytes = *maxArtifactFlag
+		}
+	})
+	if err := limits.Validate(); err != nil {
+		slog.Error("invalid limits", "err", err)
+		os.Exit(1)
+	}
+	limits = limits.WithDefaults()
+	executor.ApplyProcessLimits(limits)
+
+	opts := []mcpserver.Option{
+		mcpserver.WithPoolSize(*poolSizeFlag),
+		mcpserver.WithBundleCacheSize(*bundleCacheFlag),
+		mcpserver.WithModules(modules),
+		mcpserver.WithStdlib(stdlib),
+		mcpserver.WithTypeChecker(checker),
+		mcpserver.WithLimits(limits),
+		mcpserver.WithSpillOutput(*spillOutputFlag),
+		mcpserver.WithLintRules(cfg.Lint),
+	}
+
+	// The URL of the SSE server, also used for the links to dir artifacts.
+	baseURL := ""
+	if *addrFlag != "" {
+		var err error
+		baseURL, err = publicBaseURL(*addrFlag, *publicURLFlag)
+		if err != nil {
+			slog.Error("invalid server URL", "err", err)
+			os.Exit(1)
+		}
+	} else if *publicURLFlag != "" {
+		slog.Error("-public-url requires -addr")
+		os.Exit(1)
+	}
+
+	// Artifact backend. In SSE mode the dir store is served over HTTP under
+	// /artifacts/, otherwise its artifacts have file:// URIs.
+	var dirStore *executor.DirArtifactStore
+	switch *artifactStoreFlag {
+	case "service":
+	case "memory":
+		opts = append(opts, mcpserver.WithArtifactStore(executor.NewMemoryArtifactStore()))
+	case "dir":
+		if *artifactDirFlag == "" {
+			slog.Error("-artifact-store dir requires -artifact-dir")
+			os.Exit(1)
+		}
+		artifactURL := ""
+		if baseURL != "" {
+			artifactURL = baseURL + "/artifacts"
+		}
+		var err error
+		dirStore, err = executor.NewDirArtifactStore(*artifactDirFlag, artifactURL)
+		if err != nil {
+			slog.Error("failed to open artifact directory", "err", err)
+			os.Exit(1)
+		}
+		opts = append(opts, mcpserver.WithArtifactStore(dirStore))
+	default:
+		slog.Error("unknown artifact store", "store", *artifactStoreFlag)
+		os.Exit(1)
+	}
+
+	ws := mcpserver.New(*logDirFlag, *enableArtifactsFlag, *artifactAddrFlag, opts...)
+	defer ws.Close()
 
 	if *addrFlag != "" {
 		// SSE Mode
 		sse := server.NewSSEServer(ws.MCPServer,
-			server.WithBaseURL(fmt.Sprintf("http://localhost%s", *addrFlag)),
+			server.WithBaseURL(baseURL),
 			server.WithSSEContextFunc(func(ctx context.Context, r *http.Request) context.Context {
 				return mcpserver.WithRemoteIP(ctx, r.RemoteAddr)
 			}),
 		)
 
+		var handler http.Handler = sse
+		if dirStore != nil {
+			mux := http.NewServeMux()
+			mux.Handle("/artifacts/", http.StripPrefix("/artifacts", dirStore))
+			mux.Handle("/", sse)
+			handler = mux
+		}
+
+		// Shut down on SIGINT/SIGTERM, so that the deferred Close releases
+		// the artifact connection.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		httpServer := &http.Server{Addr: *addrFlag, Handler: handler}
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := httpServer.Shutdown(shutdownCtx); err != nil {
+				slog.Warn("http server shutdown failed", "err", err)
+			}
+		}()
+
 		slog.Info("SSE server started", "addr", *addrFlag, "name", mcpserver.ServerName, "log_dir", *logDirFlag)
-		if err := http.ListenAndServe(*addrFlag, sse); err != nil {
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			slog.Error("http server failed", "err", err)
+			ws.Close()
 			os.Exit(1)
 		}
 	} else {
 		// Stdio Mode
 		slog.Info("stdio server started", "name", mcpserver.ServerName, "version", mcpserver.ServerVersion, "log_dir", *logDirFlag)
 
+		// ServeStdio stops on SIGINT/SIGTERM by itself.
 		err := server.ServeStdio(ws.MCPServer, server.WithStdioContextFunc(func(ctx context.Context) context.Context {
 			return mcpserver.WithRemoteIP(ctx, "stdio")
 		}))
 
-		if err != nil {
+		if err != nil && !errors.Is(err, context.Canceled) {
 			slog.Error("fatal error", "err", err)
+			ws.Close()
 			os.Exit(1)
 		}
 	}
+	slog.Info("server stopped")
+}
+
+// publicBaseURL returns the URL of the SSE server listening on addr, without a
+// trailing slash: publicURL if set, otherwise http://host:port of addr, with
+// localhost for an empty or unspecified host such as 0.0.0.0.
+func publicBaseURL(addr, publicURL string) (string, error) {
+	if publicURL != "" {
+		u, err := url.Parse(publicURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+			return "", fmt.Errorf("-public-url must be an absolute http(s) URL without query, got %q", publicURL)
+		}
+		return strings.TrimSuffix(publicURL, "/"), nil
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid -addr %q: %w", addr, err)
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port), nil
 }
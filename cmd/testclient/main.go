@@ -161,6 +161,22 @@ func main() {
 		server.ParamCode: "const x: = ;",
 	})
 
+	// Case 9: Project Check (circular import and unused file)
+	runTest("Project Check", server.ToolCheckProject, map[string]any{
+		server.ParamFiles: []map[string]any{
+			{"name": "main.ts", "content": "import { a } from './a'; console.log(a);"},
+			{"name": "a.ts", "content": "import { b } from './b'; export const a = 1 + b;"},
+			{"name": "b.ts", "content": "import './a'; export const b = 2;"},
+			{"name": "old.ts", "content": "export const old = 0;"},
+		},
+		server.ParamEntryPoint: "main.ts",
+	})
+
+	// Case 10: Lint (loose comparison and floating promise)
+	runTest("Lint", server.ToolLint, map[string]any{
+		server.ParamCode: "async function save() {}\nlet n = 1;\nif (n == 1) save();",
+	})
+
 	fmt.Println("\n🏁 All tests completed.")
 }
 
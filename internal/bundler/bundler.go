@@ -4,9 +4,6 @@ package bundler
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
-	"strings"
 
 	"github.com/evanw/esbuild/pkg/api"
 	"github.com/hmsoft0815/wollmilchsau/internal/parser"
@@ -46,61 +43,44 @@ func (e *BundleError) Error() string {
 }
 
 // Bundle transpiles and bundles a project from an ExecutionPlan.
-// It uses a temporary directory to store virtual files for esbuild.
+// The files are served to esbuild from memory; nothing is written to disk.
 //
 // @Summary Bundles a TypeScript project
-// @Description Writes virtual files to a temporary directory and uses esbuild to bundle them.
+// @Description Resolves the virtual files in memory and uses esbuild to bundle them.
 // @Accept object
 // @Produce object
 // @Param plan body parser.ExecutionPlan true "Execution plan containing virtual files"
 // @Success 200 {object} BundleResult
 func Bundle(plan *parser.ExecutionPlan) (*BundleResult, error) {
-	// 1. Setup temporary directory for esbuild.
-	// Since esbuild works on files, we materialize our virtual project here.
-	tmpDir, err := os.MkdirTemp("", "ts_mcp_*")
-	if err != nil {
-		return nil, fmt.Errorf("creating temp dir: %w", err)
-	}
-	// Always cleanup the temp files after bundling.
-	defer os.RemoveAll(tmpDir)
-
-	// 2. Write each virtual file to the temp directory.
-	for _, vf := range plan.Files {
-		dest := filepath.Join(tmpDir, filepath.FromSlash(vf.Name))
-		if err := os.MkdirAll(filepath.Dir(dest), 0o700); err != nil {
-			return nil, fmt.Errorf("creating dir for %q: %w", vf.Name, err)
-		}
-		if err := os.WriteFile(dest, []byte(vf.Content), 0o600); err != nil {
-			return nil, fmt.Errorf("writing %q: %w", vf.Name, err)
-		}
-	}
+	return BundleWith(plan, Imports{})
+}
 
-	entryPath := filepath.Join(tmpDir, filepath.FromSlash(plan.EntryPoint))
-
-	// 3. Invoke esbuild in-process to bundle the TypeScript project.
-	result := api.Build(api.BuildOptions{
-		EntryPoints:    []string{entryPath},
-		Bundle:         true,
-		Platform:       api.PlatformNode,
-		Target:         api.ES2020,
-		Format:         api.FormatIIFE,
-		GlobalName:     "__entry__", // ensure the bundle is wrapped in an IIFE
-		Write:          false,       // don't write to disk, keep result in memory
-		LogLevel:       api.LogLevelSilent,
-		Sourcemap:      api.SourceMapInline,       // append base64 source map to result JS
-		SourcesContent: api.SourcesContentExclude, // don't embed original TS source in map
-	})
+// Imports are the modules a project can import besides its own files.
+type Imports struct {
+	Modules *Registry // npm packages for bare imports such as "lodash"; may be nil
+	Stdlib  *Stdlib   // operator helper modules under StdPrefix; may be nil
+}
+
+// BundleWith is like Bundle, but serves imports of packages and standard
+// library modules from imp. Other packages do not resolve.
+func BundleWith(plan *parser.ExecutionPlan, imp Imports) (*BundleResult, error) {
+	vfs := newVirtualFS(plan.Files, imp)
+
+	// 1. Invoke esbuild in-process to bundle the TypeScript project.
+	opts := buildOptions(plan, imp)
+	opts.Plugins = []api.Plugin{vfs.plugin()}
+	result := api.Build(opts)
 
 	warnings := make([]BundleMessage, 0, len(result.Warnings))
 	for _, w := range result.Warnings {
-		warnings = append(warnings, toBundleMessage(w, tmpDir))
+		warnings = append(warnings, toBundleMessage(w))
 	}
 
-	// 4. Handle compilation errors.
+	// 2. Handle compilation errors.
 	if len(result.Errors) > 0 {
 		msgs := make([]BundleMessage, 0, len(result.Errors))
 		for _, e := range result.Errors {
-			msgs = append(msgs, toBundleMessage(e, tmpDir))
+			msgs = append(msgs, toBundleMessage(e))
 		}
 		return nil, &BundleError{Messages: msgs}
 	}
@@ -109,9 +89,10 @@ func Bundle(plan *parser.ExecutionPlan) (*BundleResult, error) {
 		return nil, &BundleError{Messages: []BundleMessage{{Text: "esbuild produced no output"}}}
 	}
 
-	// 5. Extract and parse the inline source map.
+	// 3. Extract and parse the inline source map.
 	rawJS := string(result.OutputFiles[0].Contents)
 	js, sm, _ := extractSourceMap /* nolint:errcheck */ (rawJS) // failures here are non-fatal
+	js = rewriteEntryExports(js)
 
 	return &BundleResult{
 		JS:        js,
@@ -120,37 +101,43 @@ func Bundle(plan *parser.ExecutionPlan) (*BundleResult, error) {
 	}, nil
 }
 
-func toBundleMessage(msg api.Message, tmpDir string) BundleMessage {
+// buildOptions returns the esbuild options for plan, except for the plugins.
+// Everything that influences the output must be set here, since the options
+// are part of the cache key.
+//
+// The bundle is emitted as an ES module and wrapped in an async function so
+// that top-level await works. The wrapper is added via Banner/Footer so that
+// esbuild accounts for it in the source map.
+func buildOptions(plan *parser.ExecutionPlan, imp Imports) api.BuildOptions {
+	opts := api.BuildOptions{
+		EntryPoints: []string{plan.EntryPoint},
+		Bundle:      true,
+		Platform:    api.PlatformNode,
+		Target:      api.ES2020,
+		Format:      api.FormatESModule,
+		Supported: map[string]bool{
+			"top-level-await": true, // executed inside the async wrapper below
+		},
+		Banner:         map[string]string{"js": asyncWrapperStart},
+		Footer:         map[string]string{"js": asyncWrapperEnd},
+		Write:          false, // don't write to disk, keep result in memory
+		LogLevel:       api.LogLevelSilent,
+		Sourcemap:      api.SourceMapInline,       // append base64 source map to result JS
+		SourcesContent: api.SourcesContentExclude, // don't embed original TS source in map
+	}
+	if imp.Modules != nil {
+		// Packages and stdlib modules import packages from the registry, too.
+		opts.NodePaths = []string{imp.Modules.Dir()}
+	}
+	return opts
+}
+
+func toBundleMessage(msg api.Message) BundleMessage {
 	bm := BundleMessage{Text: msg.Text}
 	if msg.Location != nil {
-		bm.Source = stripTmpDir(msg.Location.File, tmpDir)
+		bm.Source = virtualName(msg.Location.File)
 		bm.Line = msg.Location.Line
 		bm.Column = msg.Location.Column + 1 // esbuild is 0-based
 	}
 	return bm
 }
-
-func stripTmpDir(path, tmpDir string) string {
-	// 1. Try absolute path comparison
-	absPath, err1 := filepath.Abs(path)
-	absTmp, err2 := filepath.Abs(tmpDir)
-	if err1 == nil && err2 == nil {
-		if strings.HasPrefix(absPath, absTmp) {
-			rel := strings.TrimPrefix(absPath, absTmp)
-			return strings.TrimPrefix(rel, string(filepath.Separator))
-		}
-	}
-
-	// 2. Fallback: search for our unique prefix pattern in the path
-	const pattern = "ts_mcp_"
-	if idx := strings.Index(path, pattern); idx >= 0 {
-		// Find the next slash after the pattern segment
-		rest := path[idx:]
-		if firstSlash := strings.Index(rest, string(filepath.Separator)); firstSlash >= 0 {
-			return rest[firstSlash+1:]
-		}
-	}
-
-	// 3. Last resort: just return the base name if it's still look like a temp path
-	return filepath.Base(path)
-}
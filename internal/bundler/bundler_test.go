@@ -4,7 +4,9 @@ package bundler
 import (
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/hmsoft0815/wollmilchsau/internal/parser"
@@ -102,3 +104,123 @@ func TestBundle_SourceMap(t *testing.T) {
 		t.Error("could not resolve any position back to 'main.ts' in bundled JS")
 	}
 }
+
+func TestBundle_TopLevelAwait(t *testing.T) {
+	plan := &parser.ExecutionPlan{
+		Files: []parser.VirtualFile{
+			{
+				Name:    "main.ts",
+				Content: "const x: number = await Promise.resolve(41);\nexport default x + 1;\nexport const y = 2;",
+			},
+		},
+		EntryPoint: "main.ts",
+	}
+
+	result, err := Bundle(plan)
+	if err != nil {
+		t.Fatalf("bundle failed: %v", err)
+	}
+
+	if !strings.HasPrefix(result.JS, asyncWrapperStart) || !strings.HasSuffix(result.JS, asyncWrapperEnd) {
+		t.Errorf("bundle is not wrapped in an async function:\n%s", result.JS)
+	}
+	if strings.Contains(result.JS, "export {") {
+		t.Errorf("export clause was not rewritten:\n%s", result.JS)
+	}
+	if !strings.Contains(result.JS, `"default": main_default`) || !strings.Contains(result.JS, `"y": y`) {
+		t.Errorf("expected exports to be returned from the wrapper:\n%s", result.JS)
+	}
+}
+
+func TestBundle_VirtualFS(t *testing.T) {
+	plan := &parser.ExecutionPlan{
+		Files: []parser.VirtualFile{
+			{Name: "main.ts", Content: "import { add } from './lib/math.js';\nimport greet from './lib';\nimport data from '/data.json';\nconsole.log(greet(), add(data.a, 2));"},
+			{Name: "lib/math.ts", Content: "export const add = (a: number, b: number): number => a + b;"},
+			{Name: "lib/index.ts", Content: "import { add } from '../lib/math';\nexport default () => 'sum: ' + add(1, 1);"},
+			{Name: "data.json", Content: `{"a": 40}`},
+		},
+		EntryPoint: "main.ts",
+	}
+	result, err := Bundle(plan)
+	if err != nil {
+		t.Fatalf("bundle failed: %v", err)
+	}
+	for _, want := range []string{"sum: ", "a + b", "40"} {
+		if !strings.Contains(result.JS, want) {
+			t.Errorf("bundle does not contain %q:\n%s", want, result.JS)
+		}
+	}
+
+	// Source names in the source map are the virtual file names.
+	sources := map[string]bool{}
+	for line := range strings.Split(result.JS, "\n") {
+		for col := 1; col <= 120; col++ {
+			if pos := result.SourceMap.Resolve(line+1, col); pos != nil {
+				sources[pos.Source] = true
+			}
+		}
+	}
+	for _, want := range []string{"main.ts", "lib/math.ts", "lib/index.ts"} {
+		if !sources[want] {
+			t.Errorf("source map does not resolve to %s: %v", want, sources)
+		}
+	}
+}
+
+func TestBundle_VirtualFSErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  []parser.VirtualFile
+		source string
+		text   string
+	}{
+		{"syntax error", []parser.VirtualFile{{Name: "main.ts", Content: "import './lib/broken';"}, {Name: "lib/broken.ts", Content: "const x = ;"}}, "lib/broken.ts", "Unexpected"},
+		{"missing file", []parser.VirtualFile{{Name: "main.ts", Content: "import { x } from './missing';\nconsole.log(x);"}}, "main.ts", `Could not resolve "./missing"`},
+		{"package", []parser.VirtualFile{{Name: "main.ts", Content: "import fs from 'fs';\nconsole.log(fs);"}}, "main.ts", `Could not resolve "fs"`},
+		{"outside the project", []parser.VirtualFile{{Name: "main.ts", Content: "import '../etc/passwd';"}}, "main.ts", "Could not resolve"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Bundle(&parser.ExecutionPlan{Files: tt.files, EntryPoint: "main.ts"})
+			be, ok := err.(*BundleError)
+			if !ok || len(be.Messages) == 0 {
+				t.Fatalf("expected a BundleError, got %v", err)
+			}
+			if m := be.Messages[0]; m.Source != tt.source || m.Line != 1 || !strings.Contains(m.Text, tt.text) {
+				t.Errorf("unexpected message: %+v", m)
+			}
+		})
+	}
+}
+
+func TestBundle_Concurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			want := fmt.Sprintf("value-%d", i)
+			plan := &parser.ExecutionPlan{
+				Files: []parser.VirtualFile{
+					{Name: "main.ts", Content: "import { v } from './v';\nconsole.log(v);"},
+					{Name: "v.ts", Content: fmt.Sprintf("export const v = %q;", want)},
+				},
+				EntryPoint: "main.ts",
+			}
+			result, err := Bundle(plan)
+			if err == nil && !strings.Contains(result.JS, want) {
+				err = fmt.Errorf("bundle %d does not contain %s", i, want)
+			}
+			if err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
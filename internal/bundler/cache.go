@@ -0,0 +1,165 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package bundler
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hmsoft0815/wollmilchsau/internal/parser"
+)
+
+// DefaultCacheSize is the default number of bundles kept by a Cache.
+const DefaultCacheSize = 64
+
+// CacheStats is a snapshot of the cache's metrics.
+type CacheStats struct {
+	Size    int     `json:"size"`    // maximum number of bundles
+	Entries int     `json:"entries"` // bundles currently cached
+	Hits    int64   `json:"hits"`    // bundles served from the cache
+	Misses  int64   `json:"misses"`  // bundles built by esbuild
+	HitRate float64 `json:"hitRate"` // Hits / (Hits + Misses)
+}
+
+// Cache keeps the most recently used bundles, keyed by a hash of the files,
+// the entry point and the build options. Agents often run the same project
+// again after a failed attempt; those runs skip esbuild entirely.
+//
+// Only successful bundles are cached. The cached results are shared and
+// must not be modified.
+type Cache struct {
+	size    int
+	imports Imports
+
+	mu      sync.Mutex
+	lru     *list.List               // of *cacheEntry, most recently used first
+	entries map[string]*list.Element // key → element of lru
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type cacheEntry struct {
+	key    string
+	result *BundleResult
+}
+
+// NewCache creates a cache for size bundles, built with imp.
+// A size <= 0 disables caching.
+func NewCache(size int, imp Imports) *Cache {
+	if size < 0 {
+		size = 0
+	}
+	return &Cache{size: size, imports: imp, lru: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// Imports returns the imports available to the bundles.
+func (c *Cache) Imports() Imports {
+	if c == nil {
+		return Imports{}
+	}
+	return c.imports
+}
+
+// Bundle is like BundleWith, but returns a cached result for a plan that
+// was bundled before. The second result reports a cache hit.
+func (c *Cache) Bundle(plan *parser.ExecutionPlan) (*BundleResult, bool, error) {
+	if c == nil || c.size == 0 {
+		res, err := BundleWith(plan, c.Imports())
+		return res, false, err
+	}
+
+	key := CacheKey(plan, c.imports)
+	if res := c.get(key); res != nil {
+		c.hits.Add(1)
+		slog.Debug("bundle cache hit", "key", key[:12], "entry", plan.EntryPoint)
+		return res, true, nil
+	}
+	c.misses.Add(1)
+	slog.Debug("bundle cache miss", "key", key[:12], "entry", plan.EntryPoint)
+
+	res, err := BundleWith(plan, c.imports)
+	if err != nil {
+		return nil, false, err
+	}
+	c.put(key, res)
+	return res, false, nil
+}
+
+// Stats returns a snapshot of the cache metrics.
+func (c *Cache) Stats() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+	c.mu.Lock()
+	entries := c.lru.Len()
+	c.mu.Unlock()
+
+	hits, misses := c.hits.Load(), c.misses.Load()
+	st := CacheStats{Size: c.size, Entries: entries, Hits: hits, Misses: misses}
+	if total := hits + misses; total > 0 {
+		st.HitRate = float64(hits) / float64(total)
+	}
+	return st
+}
+
+func (c *Cache) get(key string) *BundleResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	return el.Value.(*cacheEntry).result
+}
+
+func (c *Cache) put(key string, res *BundleResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		// Bundled concurrently by another call.
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, result: res})
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// CacheKey returns the hex SHA-256 of everything that determines the bundle
+// of plan: the build options, the entry point, the files and the imports.
+// The order of the files does not matter.
+func CacheKey(plan *parser.ExecutionPlan, imp Imports) string {
+	h := sha256.New()
+	writeField(h, fmt.Sprintf("%#v", buildOptions(plan, imp)))
+	writeField(h, imp.Modules.fingerprint())
+	writeField(h, imp.Stdlib.fingerprint())
+
+	files := make([]parser.VirtualFile, len(plan.Files))
+	copy(files, plan.Files)
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	for _, f := range files {
+		writeField(h, f.Name)
+		writeField(h, f.Content)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeField writes s with its length, so that field boundaries are unambiguous.
+func writeField(h hash.Hash, s string) {
+	var n [8]byte
+	binary.LittleEndian.PutUint64(n[:], uint64(len(s)))
+	h.Write(n[:])
+	h.Write([]byte(s))
+}
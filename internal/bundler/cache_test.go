@@ -0,0 +1,84 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package bundler
+
+import (
+	"testing"
+
+	"github.com/hmsoft0815/wollmilchsau/internal/parser"
+)
+
+func cachePlan(files ...string) *parser.ExecutionPlan {
+	plan := &parser.ExecutionPlan{EntryPoint: "main.ts"}
+	for i := 0; i+1 < len(files); i += 2 {
+		plan.Files = append(plan.Files, parser.VirtualFile{Name: files[i], Content: files[i+1]})
+	}
+	return plan
+}
+
+func TestCacheKey(t *testing.T) {
+	a := cachePlan("main.ts", "import './b';", "b.ts", "console.log(1);")
+	b := cachePlan("b.ts", "console.log(1);", "main.ts", "import './b';")
+	if CacheKey(a, Imports{}) != CacheKey(b, Imports{}) {
+		t.Error("the order of the files changed the key")
+	}
+	for name, plan := range map[string]*parser.ExecutionPlan{
+		"content":     cachePlan("main.ts", "import './b';", "b.ts", "console.log(2);"),
+		"name":        cachePlan("main.ts", "import './b';", "c.ts", "console.log(1);"),
+		"boundary":    cachePlan("main.ts", "import './b';b.ts", "", "console.log(1);"),
+		"entry point": {Files: a.Files, EntryPoint: "b.ts"},
+	} {
+		if CacheKey(plan, Imports{}) == CacheKey(a, Imports{}) {
+			t.Errorf("a different %s has the same key", name)
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	c := NewCache(2, Imports{})
+	one := cachePlan("main.ts", "console.log(1);")
+	two := cachePlan("main.ts", "console.log(2);")
+	three := cachePlan("main.ts", "console.log(3);")
+
+	first, hit, err := c.Bundle(one)
+	if err != nil || hit {
+		t.Fatalf("first Bundle = %v, %v", hit, err)
+	}
+	again, hit, err := c.Bundle(one)
+	if err != nil || !hit || again != first {
+		t.Fatalf("second Bundle = %v, %v; want the cached result", hit, err)
+	}
+
+	// two and three evict one, the least recently used bundle.
+	c.Bundle(two)
+	c.Bundle(three)
+	if _, hit, _ := c.Bundle(one); hit {
+		t.Error("evicted bundle was served from the cache")
+	}
+	if _, hit, _ := c.Bundle(three); !hit {
+		t.Error("recent bundle was not served from the cache")
+	}
+
+	// Failed builds are not cached.
+	broken := cachePlan("main.ts", "const = ;")
+	for range 2 {
+		if _, hit, err := c.Bundle(broken); err == nil || hit {
+			t.Errorf("broken Bundle = %v, %v", hit, err)
+		}
+	}
+
+	st := c.Stats()
+	if st.Size != 2 || st.Entries != 2 || st.Hits != 2 || st.Misses != 6 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+}
+
+func TestCache_Disabled(t *testing.T) {
+	for _, c := range []*Cache{nil, NewCache(0, Imports{})} {
+		plan := cachePlan("main.ts", "console.log(1);")
+		for range 2 {
+			if res, hit, err := c.Bundle(plan); err != nil || hit || res == nil {
+				t.Errorf("Bundle = %v, %v, %v", res, hit, err)
+			}
+		}
+	}
+}
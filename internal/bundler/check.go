@@ -0,0 +1,206 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package bundler
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/hmsoft0815/wollmilchsau/internal/parser"
+)
+
+// Report is the result of Check.
+type Report struct {
+	Errors   []BundleMessage // build errors, e.g. syntax errors and unresolved imports
+	Warnings []BundleMessage // esbuild warnings and circular imports
+	Unused   []string        // files of the plan that the entry point never imports
+}
+
+// Check builds plan like BundleWith without keeping the output and reports
+// the problems of the whole project: the build errors and warnings of all
+// files, including imports that do not resolve, circular imports between
+// the files and files that are not imported at all.
+func Check(plan *parser.ExecutionPlan, imp Imports) *Report {
+	vfs := newVirtualFS(plan.Files, imp)
+	vfs.graph = &importGraph{imports: map[string]map[string]string{}, loaded: map[string]bool{}}
+
+	opts := buildOptions(plan, imp)
+	opts.Plugins = []api.Plugin{vfs.plugin()}
+	opts.Sourcemap = api.SourceMapNone
+	result := api.Build(opts)
+
+	report := &Report{}
+	for _, e := range result.Errors {
+		report.Errors = append(report.Errors, toBundleMessage(e))
+	}
+	for _, w := range result.Warnings {
+		report.Warnings = append(report.Warnings, toBundleMessage(w))
+	}
+	report.Warnings = append(report.Warnings, vfs.circularImports(cleanName(plan.EntryPoint))...)
+	if len(vfs.graph.loaded) > 0 {
+		// Otherwise the entry point itself is missing, which is an error.
+		report.Unused = vfs.unusedFiles()
+	}
+	return report
+}
+
+// importGraph records the imports between the project files during a build.
+// esbuild runs the plugin callbacks concurrently.
+type importGraph struct {
+	mu      sync.Mutex
+	imports map[string]map[string]string // importer → imported file → import path
+	loaded  map[string]bool
+}
+
+// addImport records the import of file by args.Importer. Only imports that
+// are evaluated when the importer is loaded count: a dynamic import runs
+// after the importer finished loading and cannot be part of a cycle.
+func (g *importGraph) addImport(args api.OnResolveArgs, file string) {
+	if g == nil || (args.Kind != api.ResolveJSImportStatement && args.Kind != api.ResolveJSRequireCall) {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.imports[args.Importer] == nil {
+		g.imports[args.Importer] = map[string]string{}
+	}
+	if _, ok := g.imports[args.Importer][file]; !ok {
+		g.imports[args.Importer][file] = args.Path
+	}
+}
+
+func (g *importGraph) addFile(name string) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.loaded[name] = true
+}
+
+// circularImports reports each import cycle reachable from entry once, at
+// the import that closes it, e.g. "Circular import: a.ts -> b.ts -> a.ts".
+// It must be called after the build.
+func (fs *virtualFS) circularImports(entry string) []BundleMessage {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	var (
+		msgs  []BundleMessage
+		state = map[string]int{}
+		seen  = map[string]bool{}
+		stack []string
+	)
+	var visit func(file string)
+	visit = func(file string) {
+		state[file] = visiting
+		stack = append(stack, file)
+		targets := make([]string, 0, len(fs.graph.imports[file]))
+		for target := range fs.graph.imports[file] {
+			targets = append(targets, target)
+		}
+		sort.Strings(targets)
+		for _, target := range targets {
+			switch state[target] {
+			case 0:
+				visit(target)
+			case visiting:
+				i := len(stack) - 1
+				for stack[i] != target {
+					i--
+				}
+				cycle := append(append([]string{}, stack[i:]...), target)
+				if key := cycleKey(cycle[:len(cycle)-1]); !seen[key] {
+					seen[key] = true
+					msg := fs.importPosition(file, fs.graph.imports[file][target])
+					msg.Text = "Circular import: " + strings.Join(cycle, " -> ")
+					msgs = append(msgs, msg)
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[file] = done
+	}
+	if fs.graph.loaded[entry] {
+		visit(entry)
+	}
+	return msgs
+}
+
+// cycleKey identifies a cycle independently of the file it starts at.
+func cycleKey(cycle []string) string {
+	start := 0
+	for i, f := range cycle {
+		if f < cycle[start] {
+			start = i
+		}
+	}
+	return strings.Join(append(append([]string{}, cycle[start:]...), cycle[:start]...), "\x00")
+}
+
+// importPosition returns the position of the first import of importPath
+// in file.
+func (fs *virtualFS) importPosition(file, importPath string) BundleMessage {
+	content := fs.files[file]
+	msg := BundleMessage{Source: file}
+	for _, quote := range []string{`"`, `'`, "`"} {
+		if i := strings.Index(content, quote+importPath+quote); i >= 0 {
+			msg.Line = strings.Count(content[:i], "\n") + 1
+			msg.Column = i - strings.LastIndex(content[:i], "\n")
+			break
+		}
+	}
+	return msg
+}
+
+// importPathRe matches the module of import and export declarations,
+// import() and require() calls.
+var importPathRe = regexp.MustCompile(`(?:\bfrom|\bimport|\brequire)\s*\(?\s*["']([^"'\n]+)["']`)
+
+// unusedFiles returns the files of the plan that were not loaded by the
+// build, sorted by name. esbuild drops imports of types only before they
+// are resolved, so the files imported by the loaded files are followed in
+// the sources, too. Declaration files are never bundled and not reported.
+func (fs *virtualFS) unusedFiles() []string {
+	reached := map[string]bool{}
+	var queue []string
+	for name := range fs.graph.loaded {
+		reached[name] = true
+		queue = append(queue, name)
+	}
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+		for _, m := range importPathRe.FindAllStringSubmatch(fs.files[file], -1) {
+			if isBareImport(m[1]) {
+				continue
+			}
+			name, ok := fs.resolve(api.OnResolveArgs{Path: m[1], Importer: file, Kind: api.ResolveJSImportStatement})
+			if ok && !reached[name] {
+				reached[name] = true
+				queue = append(queue, name)
+			}
+		}
+	}
+
+	var unused []string
+	for name := range fs.files {
+		if !reached[name] && !isDeclarationFile(name) {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+func isDeclarationFile(name string) bool {
+	for _, ext := range []string{".d.ts", ".d.mts", ".d.cts"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package bundler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hmsoft0815/wollmilchsau/internal/parser"
+)
+
+func TestCheck(t *testing.T) {
+	plan := &parser.ExecutionPlan{
+		Files: []parser.VirtualFile{
+			{Name: "main.ts", Content: "import { a } from './a';\nimport { nope } from './missing';\nconsole.log(a, nope);"},
+			{Name: "a.ts", Content: "import { b } from './b';\nexport const a = () => b();"},
+			{Name: "b.ts", Content: "import type { T } from './types';\nimport { a } from \"./a\";\nexport const b = (): T => a;"},
+			{Name: "types.ts", Content: "export type T = unknown;"},
+			{Name: "globals.d.ts", Content: "declare const x: number;"},
+			{Name: "lazy.ts", Content: "export const lazy = 1;"},
+			{Name: "old.ts", Content: "export const old = 1;"},
+			{Name: "main2.ts", Content: "import('./lazy');"},
+		},
+		EntryPoint: "main.ts",
+	}
+	format := func(msgs []BundleMessage) []string {
+		var out []string
+		for _, m := range msgs {
+			out = append(out, fmt.Sprintf("%s:%d:%d %s", m.Source, m.Line, m.Column, m.Text))
+		}
+		return out
+	}
+
+	report := Check(plan, Imports{})
+	if errs := format(report.Errors); len(errs) != 1 || !strings.HasPrefix(errs[0], `main.ts:2:22 Could not resolve "./missing"`) {
+		t.Errorf("errors = %q", errs)
+	}
+	if warnings := format(report.Warnings); strings.Join(warnings, "\n") != "b.ts:2:19 Circular import: a.ts -> b.ts -> a.ts" {
+		t.Errorf("warnings = %q", warnings)
+	}
+	// types.ts is only imported for types, which esbuild drops.
+	if got := strings.Join(report.Unused, ","); got != "lazy.ts,main2.ts,old.ts" {
+		t.Errorf("unused = %s", got)
+	}
+
+	// Dynamic imports count as uses, but not as part of a cycle.
+	plan.EntryPoint = "main2.ts"
+	plan.Files[7].Content = "import('./lazy');\nimport './old.js';"
+	plan.Files[6].Content = "import './main2';"
+	report = Check(plan, Imports{})
+	if len(report.Errors) != 0 || len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0].Text, "main2.ts -> old.ts -> main2.ts") {
+		t.Errorf("report = %+v", report)
+	}
+	if got := strings.Join(report.Unused, ","); got != "a.ts,b.ts,main.ts,types.ts" {
+		t.Errorf("unused = %s", got)
+	}
+
+	// Without the entry point nothing is reported as unused.
+	plan.EntryPoint = "nope.ts"
+	if report = Check(plan, Imports{}); len(report.Errors) != 1 || len(report.Unused) != 0 {
+		t.Errorf("report without the entry point = %+v", report)
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package bundler
+
+import (
+	"regexp"
+	"strings"
+)
+
+// identRe matches an identifier at the start of a string.
+var identRe = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*`)
+
+// deriveDeclarations derives the TypeScript declarations of a module, as in
+// the body of a "declare module" block, from the top-level export statements
+// of its source. Function signatures, annotated constants, interfaces, type
+// aliases and enums are declared exactly; everything else is declared as any.
+// Modules that need precise types for such exports ship a .d.ts file instead.
+func deriveDeclarations(src string, exports []string) string {
+	var decls []string
+	declared := make(map[string]bool)
+	for _, stmt := range exportStatements(src) {
+		name, decl := declareExport(stmt)
+		if decl == "" {
+			continue
+		}
+		declared[name] = true
+		decls = append(decls, decl)
+	}
+	for _, name := range exports {
+		if declared[name] {
+			continue
+		}
+		if name == "default" {
+			decls = append(decls, "const _default: any;\nexport default _default;")
+		} else {
+			decls = append(decls, "export const "+name+": any;")
+		}
+	}
+	return strings.Join(decls, "\n")
+}
+
+// exportStatements returns the source following each "export " at the start
+// of a line.
+func exportStatements(src string) []string {
+	var stmts []string
+	for i := 0; i < len(src); {
+		if strings.HasPrefix(src[i:], "export ") {
+			stmts = append(stmts, src[i+len("export "):])
+		}
+		nl := strings.IndexByte(src[i:], '\n')
+		if nl < 0 {
+			break
+		}
+		i += nl + 1
+	}
+	return stmts
+}
+
+// declareExport returns the exported name and the declaration of an export
+// statement, or "" for statements it does not understand.
+func declareExport(stmt string) (string, string) {
+	rest, async := strings.CutPrefix(stmt, "async ")
+	switch {
+	case strings.HasPrefix(rest, "function "):
+		rest = strings.TrimPrefix(rest, "function ")
+		name := identRe.FindString(rest)
+		if name == "" {
+			return "", ""
+		}
+		return name, "export function " + functionSignature(rest, async) + ";"
+
+	case !async && (strings.HasPrefix(stmt, "const ") || strings.HasPrefix(stmt, "let ") || strings.HasPrefix(stmt, "var ")):
+		_, rest, _ = strings.Cut(stmt, " ")
+		name := identRe.FindString(rest)
+		if name == "" {
+			return "", ""
+		}
+		return name, "export const " + name + ": " + constType(strings.TrimLeft(rest[len(name):], " \t")) + ";"
+
+	case !async && (strings.HasPrefix(stmt, "interface ") || strings.HasPrefix(stmt, "enum ") || strings.HasPrefix(stmt, "const enum ")):
+		end := scanTo(stmt, func(s string, i int) bool { return s[i] == '{' })
+		if end == len(stmt) {
+			return "", ""
+		}
+		end += 1 + scanTo(stmt[end+1:], func(s string, i int) bool { return s[i] == '}' })
+		if end >= len(stmt) {
+			return "", ""
+		}
+		return declName(stmt), "export " + stmt[:end+1]
+
+	case !async && strings.HasPrefix(stmt, "type "):
+		end := scanTo(stmt, func(s string, i int) bool {
+			if s[i] == ';' {
+				return true
+			}
+			// A newline ends the alias unless the type continues with | or &.
+			next := strings.TrimLeft(s[i+1:], " \t")
+			return s[i] == '\n' && !strings.HasPrefix(next, "|") && !strings.HasPrefix(next, "&")
+		})
+		return declName(stmt), "export " + strings.TrimRight(stmt[:end], " \t\r;") + ";"
+
+	case !async && (strings.HasPrefix(stmt, "class ") || strings.HasPrefix(stmt, "abstract class ")):
+		name := identRe.FindString(strings.TrimPrefix(strings.TrimPrefix(stmt, "abstract "), "class "))
+		if name == "" {
+			return "", ""
+		}
+		return name, "export class " + name + " {\n  constructor(...args: any[]);\n  [key: string]: any;\n}"
+	}
+	return "", ""
+}
+
+// declName returns the name declared by "interface X", "type X", "enum X" or
+// "const enum X".
+func declName(stmt string) string {
+	fields := strings.Fields(strings.TrimPrefix(stmt, "const "))
+	if len(fields) < 2 {
+		return ""
+	}
+	return identRe.FindString(fields[1])
+}
+
+// functionSignature returns the signature of a function declaration, i.e.
+// everything from the name up to the body. A missing return type becomes any.
+func functionSignature(decl string, async bool) string {
+	open := scanTo(decl, func(s string, i int) bool { return s[i] == '(' })
+	if open == len(decl) {
+		return identRe.FindString(decl) + "(...args: any[]): any"
+	}
+	params := open + 1 + scanTo(decl[open+1:], func(s string, i int) bool { return s[i] == ')' })
+	if params >= len(decl) {
+		return identRe.FindString(decl) + "(...args: any[]): any"
+	}
+	sig := decl[:open] + "(" + declareParams(decl[open+1:params]) + ")"
+	after := strings.TrimLeft(decl[params+1:], " \t")
+	if !strings.HasPrefix(after, ":") {
+		return sig + ": " + anyResult(async)
+	}
+	ret := after[1:]
+	body := scanTo(ret, isBodyBrace)
+	return sig + ": " + strings.TrimSpace(ret[:body])
+}
+
+// declareParams turns parameters with default values, which declarations
+// cannot have, into optional ones: "sep = ','" → "sep?: any".
+func declareParams(params string) string {
+	var out []string
+	for params != "" {
+		end := scanTo(params, func(s string, i int) bool { return s[i] == ',' })
+		p := strings.TrimSpace(params[:end])
+		if eq := scanTo(p, func(s string, i int) bool { return s[i] == '=' && !strings.HasPrefix(s[i:], "=>") }); eq < len(p) {
+			name, typ, ok := strings.Cut(strings.TrimSpace(p[:eq]), ":")
+			if !ok {
+				typ = "any"
+			}
+			p = strings.TrimSpace(name) + "?: " + strings.TrimSpace(typ)
+		}
+		if p != "" {
+			out = append(out, p)
+		}
+		if end == len(params) {
+			break
+		}
+		params = params[end+1:]
+	}
+	return strings.Join(out, ", ")
+}
+
+// constType returns the type of a constant from its annotation or, for
+// arrow functions and literals, its initializer.
+func constType(rest string) string {
+	if ann, ok := strings.CutPrefix(rest, ":"); ok {
+		end := scanTo(ann, func(s string, i int) bool {
+			return s[i] == '=' && !strings.HasPrefix(s[i:], "=>") || s[i] == ';'
+		})
+		return strings.TrimSpace(ann[:end])
+	}
+	init, ok := strings.CutPrefix(rest, "=")
+	if !ok {
+		return "any"
+	}
+	init = strings.TrimLeft(init, " \t")
+	init, async := strings.CutPrefix(init, "async ")
+	init = strings.TrimLeft(init, " \t")
+	switch {
+	case strings.HasPrefix(init, "("):
+		params := 1 + scanTo(init[1:], func(s string, i int) bool { return s[i] == ')' })
+		if params >= len(init) {
+			return "any"
+		}
+		sig := "(" + declareParams(init[1:params]) + ")"
+		after := strings.TrimLeft(init[params+1:], " \t")
+		if ret, ok := strings.CutPrefix(after, ":"); ok {
+			end := scanTo(ret, func(s string, i int) bool { return strings.HasPrefix(s[i:], "=>") })
+			return sig + " => " + strings.TrimSpace(ret[:end])
+		}
+		return sig + " => " + anyResult(async)
+	case async:
+		return "any"
+	case strings.HasPrefix(init, `"`) || strings.HasPrefix(init, "'") || strings.HasPrefix(init, "`"):
+		return "string"
+	case strings.HasPrefix(init, "true") || strings.HasPrefix(init, "false"):
+		return "boolean"
+	case init != "" && (init[0] >= '0' && init[0] <= '9' || init[0] == '-' || init[0] == '.'):
+		return "number"
+	}
+	return "any"
+}
+
+func anyResult(async bool) string {
+	if async {
+		return "Promise<any>"
+	}
+	return "any"
+}
+
+// isBodyBrace reports whether the "{" at s[i] opens a function body rather
+// than an object type in the return type before it.
+func isBodyBrace(s string, i int) bool {
+	if s[i] != '{' {
+		return false
+	}
+	prev := strings.TrimRight(s[:i], " \t\r\n")
+	if prev == "" {
+		return false
+	}
+	return !strings.ContainsAny(prev[len(prev)-1:], ":|&,(<=")
+}
+
+// scanTo returns the index of the first byte of s at bracket depth 0 for
+// which stop returns true, skipping strings and comments, or len(s).
+func scanTo(s string, stop func(s string, i int) bool) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\'' || c == '`':
+			end := strings.IndexByte(s[i+1:], c)
+			if end < 0 {
+				return len(s)
+			}
+			i += end + 1
+			continue
+		case strings.HasPrefix(s[i:], "//"):
+			end := strings.IndexByte(s[i:], '\n')
+			if end < 0 {
+				return len(s)
+			}
+			i += end - 1
+			continue
+		case strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i:], "*/")
+			if end < 0 {
+				return len(s)
+			}
+			i += end + 1
+			continue
+		}
+		if depth == 0 && stop(s, i) {
+			return i
+		}
+		switch s[i] {
+		case '(', '[', '{', '<':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case '>':
+			if i == 0 || s[i-1] != '=' {
+				depth--
+			}
+		}
+		if depth < 0 {
+			depth = 0
+		}
+	}
+	return len(s)
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package bundler
+
+import (
+	"strconv"
+	"strings"
+)
+
+// The bundle is wrapped in an async arrow function so that top-level await is
+// available. Running the bundle therefore always yields a Promise which the
+// executor awaits; it resolves to the exports of the entry module.
+const (
+	asyncWrapperStart = "(async () => {"
+	asyncWrapperEnd   = "})()"
+)
+
+// rewriteEntryExports replaces the trailing `export { ... };` clause that
+// esbuild emits for the entry module with an equivalent `return { ... };`,
+// since export statements are not valid inside the async wrapper.
+//
+// Only a clause directly followed by the wrapper footer is rewritten, so
+// occurrences inside string literals are never touched.
+func rewriteEntryExports(js string) string {
+	idx := strings.LastIndex(js, "\nexport {")
+	if idx < 0 {
+		return js
+	}
+	end := strings.Index(js[idx:], "};")
+	if end < 0 {
+		return js
+	}
+	end += idx
+	if strings.TrimSpace(js[end+2:]) != asyncWrapperEnd {
+		return js
+	}
+
+	clause := js[idx+len("\nexport {") : end]
+	var props []string
+	for _, spec := range strings.Split(clause, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		local, exported := spec, spec
+		if parts := strings.SplitN(spec, " as ", 2); len(parts) == 2 {
+			local, exported = strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		}
+		if !strings.HasPrefix(exported, `"`) {
+			exported = strconv.Quote(exported)
+		}
+		props = append(props, exported+": "+local)
+	}
+
+	return js[:idx] + "\nreturn { " + strings.Join(props, ", ") + " };" + js[end+2:]
+}
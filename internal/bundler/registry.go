@@ -0,0 +1,192 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package bundler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Package is a package of a Registry.
+type Package struct {
+	Name        string `json:"name"`
+	Version     string `json:"version,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// String returns name@version, or only the name if the version is unknown.
+func (p Package) String() string {
+	if p.Version == "" {
+		return p.Name
+	}
+	return p.Name + "@" + p.Version
+}
+
+// Registry is an offline directory of vetted npm packages that scripts may
+// import by name. It has the layout of a node_modules directory, so it can be
+// created with e.g. "npm install --prefix /opt/wms lodash date-fns zod" and
+// used as /opt/wms/node_modules:
+//
+//	lodash/package.json, lodash/...      → import "lodash"
+//	@scope/name/package.json, ...        → import "@scope/name"
+//	decimal.js/package.json, ...         → import "decimal.js" (npm's decimal.js)
+//	money.js                             → import "money"
+//
+// A single pre-bundled file at the top is a package, too, named without its
+// extension.
+//
+// esbuild resolves the imports of the packages itself, with the Node
+// algorithm (package.json "exports", "module", "main", index files). The
+// directory is scanned once; a changed registry requires a restart.
+type Registry struct {
+	dir      string
+	packages []Package
+	names    map[string]bool
+}
+
+// OpenRegistry scans dir for packages.
+func OpenRegistry(dir string) (*Registry, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("module registry %s: %w", dir, err)
+	}
+	r := &Registry{dir: abs, names: make(map[string]bool)}
+	if err := r.scan(abs, ""); err != nil {
+		return nil, fmt.Errorf("module registry %s: %w", dir, err)
+	}
+	sort.Slice(r.packages, func(i, j int) bool { return r.packages[i].Name < r.packages[j].Name })
+	return r, nil
+}
+
+// scan adds the packages in dir; scope is "@scope/" inside a scope directory.
+func (r *Registry) scan(dir, scope string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		isDir := e.IsDir()
+		if e.Type()&os.ModeSymlink != 0 {
+			// npm links workspace packages; follow them.
+			fi, err := os.Stat(filepath.Join(dir, name))
+			if err != nil {
+				continue
+			}
+			isDir = fi.IsDir()
+		}
+		switch {
+		case isDir && scope == "" && strings.HasPrefix(name, "@"):
+			if err := r.scan(filepath.Join(dir, name), name+"/"); err != nil {
+				return err
+			}
+		case isDir:
+			r.add(readPackageJSON(filepath.Join(dir, name), scope+name))
+		case isModuleFile(name):
+			r.add(Package{Name: scope + strings.TrimSuffix(name, filepath.Ext(name))})
+		}
+	}
+	return nil
+}
+
+func (r *Registry) add(p Package) {
+	if r.names[p.Name] {
+		return
+	}
+	r.names[p.Name] = true
+	r.packages = append(r.packages, p)
+}
+
+// readPackageJSON describes the package in dir. A missing or invalid
+// package.json only loses the version and description.
+func readPackageJSON(dir, name string) Package {
+	p := Package{Name: name}
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return p
+	}
+	var pkg struct {
+		Version     string `json:"version"`
+		Description string `json:"description"`
+	}
+	if json.Unmarshal(data, &pkg) == nil {
+		p.Version, p.Description = pkg.Version, pkg.Description
+	}
+	return p
+}
+
+// isModuleFile reports whether a file at the top of the registry is a
+// single-file package.
+func isModuleFile(name string) bool {
+	switch filepath.Ext(name) {
+	case ".js", ".mjs", ".cjs":
+		return true
+	}
+	return false
+}
+
+// Dir returns the absolute path of the registry.
+func (r *Registry) Dir() string {
+	return r.dir
+}
+
+// resolveDir returns the esbuild resolve directory of the project files, or
+// "" without a registry.
+func (r *Registry) resolveDir() string {
+	if r == nil {
+		return ""
+	}
+	return r.dir
+}
+
+// Packages returns the packages of the registry, sorted by name.
+func (r *Registry) Packages() []Package {
+	if r == nil {
+		return nil
+	}
+	return r.packages
+}
+
+// has reports whether a bare import such as "lodash/fp" or "@scope/pkg"
+// refers to a package of the registry. Imports leaving the package via ".."
+// never do.
+func (r *Registry) has(importPath string) bool {
+	if r == nil {
+		return false
+	}
+	for _, seg := range strings.Split(importPath, "/") {
+		if seg == ".." {
+			return false
+		}
+	}
+	return r.names[packageName(importPath)]
+}
+
+// fingerprint identifies the registry and its package versions for CacheKey.
+func (r *Registry) fingerprint() string {
+	if r == nil {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(r.dir)
+	for _, p := range r.packages {
+		b.WriteString("\n" + p.String())
+	}
+	return b.String()
+}
+
+// packageName returns the package of a bare import: "lodash/fp" → "lodash",
+// "@scope/pkg/sub" → "@scope/pkg".
+func packageName(importPath string) string {
+	parts := strings.SplitN(importPath, "/", 3)
+	if strings.HasPrefix(importPath, "@") && len(parts) > 1 {
+		return parts[0] + "/" + parts[1]
+	}
+	return parts[0]
+}
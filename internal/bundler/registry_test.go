@@ -0,0 +1,109 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package bundler
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hmsoft0815/wollmilchsau/internal/parser"
+)
+
+// testRegistry creates a registry with a package that depends on another
+// one, a scoped package and a single-file package.
+func testRegistry(t *testing.T) *Registry {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"pad/package.json":             `{"name": "pad", "version": "1.2.3", "main": "lib/index.js"}`,
+		"pad/lib/index.js":             `const repeat = require("repeat"); exports.pad = (s, n) => repeat(" ", n - s.length) + s;`,
+		"pad/lib/right.js":             `exports.padRight = (s, n) => s + " ".repeat(n - s.length);`,
+		"repeat/package.json":          `{"name": "repeat", "version": "0.1.0"}`,
+		"repeat/index.js":              `module.exports = (s, n) => s.repeat(Math.max(n, 0));`,
+		"@acme/money/package.json":     `{"name": "@acme/money", "version": "2.0.0", "description": "Money", "module": "money.mjs"}`,
+		"@acme/money/money.mjs":        `export const cents = (x) => Math.round(x * 100);`,
+		"decimal.js":                   `export default class Decimal {}`,
+		".package-lock.json":           `{}`,
+		"secret.txt":                   `top secret`,
+		"@acme/money/node_modules/.ok": ``,
+	})
+	r, err := OpenRegistry(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestOpenRegistry(t *testing.T) {
+	r := testRegistry(t)
+	var got []string
+	for _, p := range r.Packages() {
+		got = append(got, p.String())
+	}
+	want := "@acme/money@2.0.0 decimal pad@1.2.3 repeat@0.1.0"
+	if strings.Join(got, " ") != want {
+		t.Errorf("packages = %v, want %s", got, want)
+	}
+
+	if _, err := OpenRegistry(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("a missing directory was opened")
+	}
+}
+
+func TestBundle_Modules(t *testing.T) {
+	r := testRegistry(t)
+	plan := &parser.ExecutionPlan{
+		Files: []parser.VirtualFile{{Name: "main.ts", Content: `
+import { pad } from "pad";
+import { padRight } from "pad/lib/right";
+import { cents } from "@acme/money";
+import Decimal from "decimal";
+console.log(pad("1", 3), padRight("1", 3), cents(1.5), new Decimal());
+`}},
+		EntryPoint: "main.ts",
+	}
+	result, err := BundleWith(plan, Imports{Modules: r})
+	if err != nil {
+		t.Fatalf("BundleWith failed: %v", err)
+	}
+	for _, want := range []string{"Math.max(n, 0)", "padRight", "Math.round(x * 100)", "Decimal = class"} {
+		if !strings.Contains(result.JS, want) {
+			t.Errorf("bundle does not contain %q", want)
+		}
+	}
+
+	for _, tt := range []struct{ name, code, text string }{
+		{"unknown package", `import x from "zod"; console.log(x);`, `"zod": the package is not available (available packages: @acme/money, decimal, pad, repeat)`},
+		{"single file with extension", `import x from "decimal.js"; console.log(x);`, `"decimal.js": single-file packages are named without the extension, import "decimal" instead`},
+		{"leaving the package", `import x from "pad/../secret.txt"; console.log(x);`, "Could not resolve"},
+		{"not a package", `import x from "secret.txt"; console.log(x);`, "Could not resolve"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &parser.ExecutionPlan{Files: []parser.VirtualFile{{Name: "main.ts", Content: tt.code}}, EntryPoint: "main.ts"}
+			_, err := BundleWith(plan, Imports{Modules: r})
+			be, ok := err.(*BundleError)
+			if !ok || !strings.Contains(be.Messages[0].Text, tt.text) {
+				t.Errorf("expected a BundleError containing %q, got %v", tt.text, err)
+			}
+		})
+	}
+
+	// Without a registry, packages do not resolve at all.
+	if _, err := Bundle(plan); err == nil || !strings.Contains(err.Error(), "npm packages are not available") {
+		t.Errorf("Bundle without registry = %v", err)
+	}
+}
+
+func TestPackageName(t *testing.T) {
+	for in, want := range map[string]string{
+		"lodash":             "lodash",
+		"lodash/fp":          "lodash",
+		"@scope/pkg":         "@scope/pkg",
+		"@scope/pkg/sub/x":   "@scope/pkg",
+		"decimal.js/decimal": "decimal.js",
+	} {
+		if got := packageName(in); got != want {
+			t.Errorf("packageName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package bundler
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -22,9 +23,35 @@ func extractSourceMap(js string) (cleanJS string, sm *sourcemap.SourceMap, err e
 	if decErr != nil {
 		return cleanJS, nil, fmt.Errorf("base64 decode: %w", decErr)
 	}
+	mapJSON, srcErr := virtualSources(mapJSON)
+	if srcErr != nil {
+		return cleanJS, nil, srcErr
+	}
 	sm, parseErr := sourcemap.Parse(mapJSON)
 	if parseErr != nil {
 		return cleanJS, nil, parseErr
 	}
 	return cleanJS, sm, nil
 }
+
+// virtualSources replaces the esbuild paths in the "sources" of a source map
+// by the names of the virtual files.
+func virtualSources(mapJSON []byte) ([]byte, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(mapJSON, &raw); err != nil {
+		return nil, fmt.Errorf("sourcemap: invalid JSON: %w", err)
+	}
+	var sources []string
+	if err := json.Unmarshal(raw["sources"], &sources); err != nil {
+		return mapJSON, nil // left to sourcemap.Parse
+	}
+	for i, src := range sources {
+		sources[i] = virtualName(src)
+	}
+	encoded, err := json.Marshal(sources)
+	if err != nil {
+		return nil, err
+	}
+	raw["sources"] = encoded
+	return json.Marshal(raw)
+}
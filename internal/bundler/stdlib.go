@@ -0,0 +1,220 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package bundler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// StdPrefix is the reserved import prefix of the standard library modules,
+// e.g. import { mean } from "@std/stats".
+const StdPrefix = "@std/"
+
+// StdModule is a module of a Stdlib.
+type StdModule struct {
+	Name         string   `json:"name"`    // import path, e.g. "@std/stats"
+	Exports      []string `json:"exports"` // exported names, "default" for a default export
+	Declarations string   `json:"-"`       // TypeScript declarations of the exports
+	file         string
+}
+
+// Stdlib is a directory of operator-provided helper modules that every
+// script can import under StdPrefix. A module is a TypeScript or JavaScript
+// file, or a directory with an index file:
+//
+//	stats.ts        → "@std/stats"
+//	csv/index.ts    → "@std/csv"
+//	text/table.ts   → "@std/text/table"
+//
+// The declarations of a module are read from a .d.ts file next to it
+// (stats.d.ts); without one they are derived from the export statements.
+// The directory is scanned once; a changed stdlib requires a restart.
+type Stdlib struct {
+	dir     string
+	modules []StdModule
+	byName  map[string]int
+	hash    string // of all module sources, for CacheKey
+}
+
+// OpenStdlib scans dir for modules. A module that esbuild cannot parse is
+// an error, so that broken helpers are found at startup.
+func OpenStdlib(dir string) (*Stdlib, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("stdlib %s: %w", dir, err)
+	}
+	s := &Stdlib{dir: abs, byName: make(map[string]int)}
+	h := sha256.New()
+	err = filepath.WalkDir(abs, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != abs && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name, ok := stdModuleName(abs, p)
+		if !ok {
+			return nil
+		}
+		if _, dup := s.byName[name]; dup {
+			return fmt.Errorf("module %s is defined twice (%s)", name, p)
+		}
+		src, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		mod, err := loadStdModule(name, p, string(src))
+		if err != nil {
+			return err
+		}
+		writeField(h, name)
+		writeField(h, string(src))
+		writeField(h, mod.Declarations)
+		s.byName[name] = len(s.modules)
+		s.modules = append(s.modules, mod)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("stdlib %s: %w", dir, err)
+	}
+	sort.Slice(s.modules, func(i, j int) bool { return s.modules[i].Name < s.modules[j].Name })
+	for i, m := range s.modules {
+		s.byName[m.Name] = i
+	}
+	s.hash = hex.EncodeToString(h.Sum(nil))
+	return s, nil
+}
+
+// stdModuleName returns the import path of the module file p in dir.
+func stdModuleName(dir, p string) (string, bool) {
+	ext := filepath.Ext(p)
+	if strings.HasSuffix(p, ".d.ts") || (ext != ".ts" && ext != ".js" && ext != ".mts" && ext != ".mjs") {
+		return "", false
+	}
+	rel, err := filepath.Rel(dir, strings.TrimSuffix(p, ext))
+	if err != nil {
+		return "", false
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "index" {
+		return "", false
+	}
+	return StdPrefix + strings.TrimSuffix(rel, "/index"), true
+}
+
+// loadStdModule determines the exports of a module with esbuild and its
+// declarations.
+func loadStdModule(name, file, src string) (StdModule, error) {
+	result := api.Build(api.BuildOptions{
+		EntryPoints: []string{file},
+		Bundle:      false,
+		Format:      api.FormatESModule,
+		Metafile:    true,
+		Write:       false,
+		Outdir:      "out",
+		LogLevel:    api.LogLevelSilent,
+	})
+	if len(result.Errors) > 0 {
+		m := toBundleMessage(result.Errors[0])
+		return StdModule{}, fmt.Errorf("%s: %s:%d:%d: %s", name, file, m.Line, m.Column, m.Text)
+	}
+	var meta struct {
+		Outputs map[string]struct {
+			Exports []string `json:"exports"`
+		} `json:"outputs"`
+	}
+	if err := json.Unmarshal([]byte(result.Metafile), &meta); err != nil {
+		return StdModule{}, fmt.Errorf("%s: %w", name, err)
+	}
+	var exports []string
+	for _, out := range meta.Outputs {
+		exports = append(exports, out.Exports...)
+	}
+	sort.Strings(exports)
+
+	decl, err := os.ReadFile(strings.TrimSuffix(file, filepath.Ext(file)) + ".d.ts")
+	if err != nil {
+		decl = []byte(deriveDeclarations(src, exports))
+	}
+	return StdModule{Name: name, Exports: exports, Declarations: strings.TrimSpace(string(decl)), file: file}, nil
+}
+
+// Dir returns the absolute path of the stdlib.
+func (s *Stdlib) Dir() string {
+	return s.dir
+}
+
+// Modules returns the modules of the stdlib, sorted by name.
+func (s *Stdlib) Modules() []StdModule {
+	if s == nil {
+		return nil
+	}
+	return s.modules
+}
+
+// Declarations returns an ambient declaration of every module, e.g.
+//
+//	declare module "@std/stats" {
+//	  export function mean(xs: number[]): number;
+//	}
+func (s *Stdlib) Declarations() string {
+	var b strings.Builder
+	for _, m := range s.Modules() {
+		fmt.Fprintf(&b, "declare module %q {\n", m.Name)
+		for _, line := range strings.Split(m.Declarations, "\n") {
+			if line != "" {
+				// The block is ambient already; "declare" is not allowed inside.
+				if rest, ok := strings.CutPrefix(line, "export declare "); ok {
+					line = "export " + rest
+				} else if rest, ok := strings.CutPrefix(line, "declare "); ok {
+					line = rest
+				}
+				b.WriteString("  " + line)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+// resolve returns the file of the module imported as importPath.
+func (s *Stdlib) resolve(importPath string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	i, ok := s.byName[importPath]
+	if !ok {
+		return "", false
+	}
+	return s.modules[i].file, true
+}
+
+// names returns the import paths of the modules.
+func (s *Stdlib) names() []string {
+	names := make([]string, len(s.Modules()))
+	for i, m := range s.Modules() {
+		names[i] = m.Name
+	}
+	return names
+}
+
+// fingerprint identifies the stdlib and its sources for CacheKey.
+func (s *Stdlib) fingerprint() string {
+	if s == nil {
+		return ""
+	}
+	return s.dir + "\n" + s.hash
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package bundler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hmsoft0815/wollmilchsau/internal/parser"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func testStdlib(t *testing.T) *Stdlib {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"stats.ts": `
+export function mean(xs: number[]): number {
+  return xs.reduce((a, b) => a + b, 0) / xs.length;
+}
+export const median = (xs: number[]): number => [...xs].sort((a, b) => a - b)[xs.length >> 1];
+`,
+		"csv/index.ts": `
+import { mean } from "@std/stats";
+export interface Row {
+  [column: string]: string;
+}
+export function parse(text: string, sep = ","): Row[] {
+  const [head, ...lines] = text.trim().split("\n").map((l) => l.split(sep));
+  return lines.map((l) => Object.fromEntries(head.map((h, i) => [h, l[i]])));
+}
+export function meanOf(rows: Row[], column: string) { return mean(rows.map((r) => Number(r[column]))); }
+`,
+		"text/table.js":   `export default function table(rows) { return rows.join("\n"); }`,
+		"text/table.d.ts": `export default function table(rows: string[]): string;`,
+	})
+	s, err := OpenStdlib(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestOpenStdlib(t *testing.T) {
+	s := testStdlib(t)
+	var got []string
+	for _, m := range s.Modules() {
+		got = append(got, m.Name+"("+strings.Join(m.Exports, ",")+")")
+	}
+	if want := "@std/csv(meanOf,parse) @std/stats(mean,median) @std/text/table(default)"; strings.Join(got, " ") != want {
+		t.Errorf("modules = %v, want %s", got, want)
+	}
+
+	decl := s.Declarations()
+	for _, want := range []string{
+		`declare module "@std/stats" {`,
+		"  export function mean(xs: number[]): number;",
+		"  export const median: (xs: number[]) => number;",
+		"  export interface Row {",
+		"  export function parse(text: string, sep?: any): Row[];",
+		"  export function meanOf(rows: Row[], column: string): any;",
+		"  export default function table(rows: string[]): string;",
+	} {
+		if !strings.Contains(decl, want) {
+			t.Errorf("declarations do not contain %q:\n%s", want, decl)
+		}
+	}
+
+	broken := t.TempDir()
+	writeFiles(t, broken, map[string]string{"bad.ts": "export const = 1;"})
+	if _, err := OpenStdlib(broken); err == nil || !strings.Contains(err.Error(), "bad.ts") {
+		t.Errorf("OpenStdlib with a broken module = %v", err)
+	}
+}
+
+func TestBundle_Stdlib(t *testing.T) {
+	s := testStdlib(t)
+	plan := &parser.ExecutionPlan{
+		Files: []parser.VirtualFile{{Name: "main.ts", Content: `
+import { parse, meanOf } from "@std/csv";
+import table from "@std/text/table";
+console.log(meanOf(parse("a\n1\n3"), "a"), table(["x"]));
+`}},
+		EntryPoint: "main.ts",
+	}
+	result, err := BundleWith(plan, Imports{Stdlib: s})
+	if err != nil {
+		t.Fatalf("BundleWith failed: %v", err)
+	}
+	for _, want := range []string{"function mean(", "function parse(", "function table("} {
+		if !strings.Contains(result.JS, want) {
+			t.Errorf("bundle does not contain %q", want)
+		}
+	}
+
+	plan.Files[0].Content = `import { x } from "@std/nope"; console.log(x);`
+	if _, err := BundleWith(plan, Imports{Stdlib: s}); err == nil || !strings.Contains(err.Error(), "available modules: @std/csv, @std/stats, @std/text/table") {
+		t.Errorf("unknown module = %v", err)
+	}
+	if _, err := Bundle(plan); err == nil || !strings.Contains(err.Error(), "no standard library is available") {
+		t.Errorf("without stdlib = %v", err)
+	}
+}
+
+func TestDeriveDeclarations(t *testing.T) {
+	src := `
+export async function load(id: string): Promise<{ id: string }> { return { id }; }
+export const PI = 3.14;
+export const name = "std";
+export let flags: Record<string, boolean> = {};
+export type Pair<T> = [T, T];
+export class Matrix { rows = 0; }
+export const plus = async (a: number, b = 1) => a + b;
+const hidden = 1;
+export { hidden };
+`
+	got := deriveDeclarations(src, []string{"Matrix", "PI", "flags", "hidden", "load", "name", "plus"})
+	want := `export function load(id: string): Promise<{ id: string }>;
+export const PI: number;
+export const name: string;
+export const flags: Record<string, boolean>;
+export type Pair<T> = [T, T];
+export class Matrix {
+  constructor(...args: any[]);
+  [key: string]: any;
+}
+export const plus: (a: number, b?: any) => Promise<any>;
+export const hidden: any;`
+	if got != want {
+		t.Errorf("deriveDeclarations =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package bundler
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/hmsoft0815/wollmilchsau/internal/parser"
+)
+
+// vfsNamespace is the esbuild namespace of the virtual files. esbuild
+// prefixes paths outside the "file" namespace with it in diagnostics and
+// source maps; virtualName removes it again.
+const vfsNamespace = "vfs"
+
+// resolveExtensions are tried in this order for imports without extension,
+// as by esbuild's own resolver.
+var resolveExtensions = []string{".tsx", ".ts", ".jsx", ".js", ".mts", ".cts", ".mjs", ".cjs", ".json"}
+
+// tsExtensions maps JavaScript extensions to the TypeScript ones tried in
+// their place, since TypeScript imports name the compiled file.
+var tsExtensions = map[string][]string{
+	".js":  {".ts", ".tsx"},
+	".jsx": {".tsx"},
+	".mjs": {".mts"},
+	".cjs": {".cts"},
+}
+
+// loaders selects the esbuild loader by file extension.
+var loaders = map[string]api.Loader{
+	".ts":   api.LoaderTS,
+	".mts":  api.LoaderTS,
+	".cts":  api.LoaderTS,
+	".tsx":  api.LoaderTSX,
+	".js":   api.LoaderJS,
+	".mjs":  api.LoaderJS,
+	".cjs":  api.LoaderJS,
+	".jsx":  api.LoaderJSX,
+	".json": api.LoaderJSON,
+	".txt":  api.LoaderText,
+}
+
+// virtualFS holds the files of an ExecutionPlan for a single build. esbuild
+// resolves and loads the project files through its plugin, so bundling never
+// touches the disk and concurrent builds share nothing. Only the packages of
+// the module registry and the stdlib modules are read from disk, by esbuild
+// itself.
+type virtualFS struct {
+	files   map[string]string // cleaned file name → content
+	modules *Registry         // packages for bare imports; may be nil
+	stdlib  *Stdlib           // modules under StdPrefix; may be nil
+	graph   *importGraph      // records the imports for Check; nil when bundling
+}
+
+func newVirtualFS(files []parser.VirtualFile, imp Imports) *virtualFS {
+	fs := &virtualFS{files: make(map[string]string, len(files)), modules: imp.Modules, stdlib: imp.Stdlib}
+	for _, f := range files {
+		fs.files[cleanName(f.Name)] = f.Content
+	}
+	return fs
+}
+
+// cleanName normalizes a file name of the plan, e.g. "./lib//a.ts" → "lib/a.ts".
+func cleanName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// virtualName returns the file name of the plan for a path reported by esbuild.
+func virtualName(file string) string {
+	return strings.TrimPrefix(file, vfsNamespace+":")
+}
+
+// plugin returns the esbuild plugin that serves the virtual files.
+func (fs *virtualFS) plugin() api.Plugin {
+	return api.Plugin{
+		Name: "wollmilchsau-vfs",
+		Setup: func(build api.PluginBuild) {
+			build.OnResolve(api.OnResolveOptions{Filter: ".*"}, fs.onResolve)
+			build.OnLoad(api.OnLoadOptions{Filter: ".*", Namespace: vfsNamespace}, fs.onLoad)
+		},
+	}
+}
+
+func (fs *virtualFS) onResolve(args api.OnResolveArgs) (api.OnResolveResult, error) {
+	// Stdlib modules can be imported from anywhere, including other stdlib modules.
+	if strings.HasPrefix(args.Path, StdPrefix) && args.Kind != api.ResolveEntryPoint {
+		if file, ok := fs.stdlib.resolve(args.Path); ok {
+			return api.OnResolveResult{Path: file, Namespace: "file"}, nil
+		}
+		return api.OnResolveResult{Errors: []api.Message{{
+			Text: fmt.Sprintf("Could not resolve %q: %s", args.Path, fs.stdlibHint()),
+		}}}, nil
+	}
+
+	// Imports of the registry packages, and imports inside them, are left to
+	// esbuild's resolver (an empty result), which finds them via NodePaths.
+	if args.Kind != api.ResolveEntryPoint && args.Namespace == "file" {
+		return api.OnResolveResult{}, nil
+	}
+	if isBareImport(args.Path) && args.Kind != api.ResolveEntryPoint {
+		if fs.modules.has(args.Path) {
+			return api.OnResolveResult{}, nil
+		}
+		return api.OnResolveResult{Errors: []api.Message{{
+			Text: fmt.Sprintf("Could not resolve %q: %s", args.Path, fs.packagesHint(args.Path)),
+		}}}, nil
+	}
+
+	if name, ok := fs.resolve(args); ok {
+		if args.Namespace == vfsNamespace {
+			fs.graph.addImport(args, name)
+		}
+		return api.OnResolveResult{Path: name, Namespace: vfsNamespace}, nil
+	}
+	return api.OnResolveResult{Errors: []api.Message{{
+		Text: fmt.Sprintf("Could not resolve %q", args.Path),
+	}}}, nil
+}
+
+// packagesHint explains which packages could have been imported instead of
+// importPath.
+func (fs *virtualFS) packagesHint(importPath string) string {
+	pkgs := fs.modules.Packages()
+	if len(pkgs) == 0 {
+		return "npm packages are not available, only the provided files can be imported"
+	}
+	if name := packageName(importPath); isModuleFile(name) {
+		if stem := strings.TrimSuffix(name, path.Ext(name)); fs.modules.has(stem) {
+			return fmt.Sprintf("single-file packages are named without the extension, import %q instead", stem)
+		}
+	}
+	names := make([]string, len(pkgs))
+	for i, p := range pkgs {
+		names[i] = p.Name
+	}
+	return "the package is not available (available packages: " + strings.Join(names, ", ") + ")"
+}
+
+// stdlibHint explains which stdlib modules could have been imported instead.
+func (fs *virtualFS) stdlibHint() string {
+	if fs.stdlib == nil {
+		return "no standard library is available"
+	}
+	return "the module is not in the standard library (available modules: " + strings.Join(fs.stdlib.names(), ", ") + ")"
+}
+
+// isBareImport reports whether an import names a package rather than a file.
+func isBareImport(importPath string) bool {
+	return !strings.HasPrefix(importPath, "/") && !strings.HasPrefix(importPath, "./") && !strings.HasPrefix(importPath, "../") &&
+		importPath != "." && importPath != ".."
+}
+
+// resolve maps an import to a virtual file. Relative and absolute imports
+// are resolved against the project root.
+func (fs *virtualFS) resolve(args api.OnResolveArgs) (string, bool) {
+	var name string
+	switch {
+	case args.Kind == api.ResolveEntryPoint:
+		name = cleanName(args.Path)
+	case strings.HasPrefix(args.Path, "/"):
+		name = cleanName(args.Path)
+	default:
+		joined := path.Join(path.Dir(args.Importer), args.Path)
+		if joined == ".." || strings.HasPrefix(joined, "../") {
+			return "", false
+		}
+		name = cleanName(joined)
+	}
+	return fs.lookup(name)
+}
+
+// lookup finds name like esbuild's resolver: as is, with one of the
+// resolveExtensions, with a TypeScript extension instead of a JavaScript
+// one ("./util.js" → "util.ts"), or as the index file of a directory.
+func (fs *virtualFS) lookup(name string) (string, bool) {
+	if _, ok := fs.files[name]; ok {
+		return name, true
+	}
+	for _, ext := range resolveExtensions {
+		if _, ok := fs.files[name+ext]; ok {
+			return name + ext, true
+		}
+	}
+	ext := path.Ext(name)
+	for _, tsExt := range tsExtensions[ext] {
+		candidate := strings.TrimSuffix(name, ext) + tsExt
+		if _, ok := fs.files[candidate]; ok {
+			return candidate, true
+		}
+	}
+	for _, ext := range resolveExtensions {
+		index := path.Join(name, "index"+ext)
+		if _, ok := fs.files[index]; ok {
+			return index, true
+		}
+	}
+	return "", false
+}
+
+func (fs *virtualFS) onLoad(args api.OnLoadArgs) (api.OnLoadResult, error) {
+	content, ok := fs.files[args.Path]
+	if !ok {
+		return api.OnLoadResult{}, fmt.Errorf("file %q not found", args.Path)
+	}
+	fs.graph.addFile(args.Path)
+	loader, ok := loaders[path.Ext(args.Path)]
+	if !ok {
+		return api.OnLoadResult{Errors: []api.Message{{
+			Text: fmt.Sprintf("No loader is configured for %q files: %s", path.Ext(args.Path), args.Path),
+		}}}, nil
+	}
+	// The resolve directory lets esbuild look up registry packages for
+	// imports of this file; it has no effect on the virtual files.
+	return api.OnLoadResult{Contents: &content, Loader: loader, ResolveDir: fs.modules.resolveDir()}, nil
+}
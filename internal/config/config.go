@@ -0,0 +1,57 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+// Package config loads the optional operator configuration file.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/hmsoft0815/wollmilchsau/internal/executor"
+	"github.com/hmsoft0815/wollmilchsau/internal/lint"
+)
+
+// File is the JSON structure of the operator configuration file.
+//
+// Example:
+//
+//	{
+//	  "limits": {
+//	    "maxHeapBytes": 67108864,
+//	    "maxWallTimeMs": 15000,
+//	    "maxCpuTimeMs": 5000
+//	  },
+//	  "lint": {
+//	    "no-unused-vars": "off",
+//	    "eqeqeq": "info"
+//	  }
+//	}
+type File struct {
+	Limits executor.Limits `json:"limits"` // upper bounds for every execution
+	Lint   lint.Config     `json:"lint"`   // levels of the lint rules, see lint.Rules
+}
+
+// Load reads and validates the configuration file at path.
+// Unknown fields are rejected to catch typos early.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var f File
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("parsing config %q: %w", path, err)
+	}
+	if err := f.Limits.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid limits in %q: %w", path, err)
+	}
+	if err := f.Lint.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid lint rules in %q: %w", path, err)
+	}
+	return &f, nil
+}
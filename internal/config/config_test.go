@@ -0,0 +1,50 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hmsoft0815/wollmilchsau/internal/lint"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `{"limits": {"maxHeapBytes": 67108864, "maxCpuTimeMs": 5000}, "lint": {"eqeqeq": "off"}}`)
+	f, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.Limits.MaxHeapBytes != 64*1024*1024 || f.Limits.MaxCPUTimeMs != 5000 {
+		t.Errorf("unexpected limits: %+v", f.Limits)
+	}
+	if f.Lint.Level("eqeqeq") != lint.LevelOff {
+		t.Errorf("unexpected lint rules: %v", f.Lint)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown field": `{"limits": {"maxHeap": 1}}`,
+		"invalid limit": `{"limits": {"maxStackKb": 100000}}`,
+		"unknown rule":  `{"lint": {"no-var": "warning"}}`,
+		"invalid level": `{"lint": {"eqeqeq": "error"}}`,
+		"not json":      `limits: {}`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Load(writeConfig(t, content)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -4,231 +4,324 @@ package executor
 import (
 	"context"
 	"encoding/json"
-	"log/slog"
-	"strings"
+	"errors"
 	"time"
 
+	"connectrpc.com/connect"
 	mlcartifact "github.com/hmsoft0815/mlcartifact/client"
 	v8 "rogchap.com/v8go"
 )
 
+const (
+	// artifactTimeout bounds a single artifact service call.
+	artifactTimeout = 10 * time.Second
+	// artifactUploadTimeout bounds the uploads of an openArtifact() handle.
+	artifactUploadTimeout = 30 * time.Second
+	// artifactGraceTimeout bounds the final upload or cleanup of the handles
+	// left open when the execution ends, which may happen after its limits
+	// expired.
+	artifactGraceTimeout = 5 * time.Second
+)
+
 // InjectArtifactService adds the global 'artifact' object to the V8 context using a default client.
 func InjectArtifactService(iso *v8.Isolate, v8ctx *v8.Context) error {
-	cli, err := mlcartifact.NewClient()
+	store, err := NewServiceArtifactStore("")
 	if err != nil {
 		return err
 	}
-	return InjectArtifactServiceWithClient(iso, v8ctx, cli)
+	return InjectArtifactStore(iso, v8ctx, store)
 }
 
 // InjectArtifactServiceWithClient adds the global 'artifact' object to the V8 context
 // using the provided client. Useful for testing.
 func InjectArtifactServiceWithClient(iso *v8.Isolate, v8ctx *v8.Context, cli *mlcartifact.Client) error {
-	global := v8ctx.Global()
-	artObj := v8.NewObjectTemplate(iso)
-
-	_ = artObj.Set("write", v8.NewFunctionTemplate(iso, artifactWriteCallback(iso, v8ctx, cli)))
-	_ = artObj.Set("read", v8.NewFunctionTemplate(iso, artifactReadCallback(iso, v8ctx, cli)))
-	_ = artObj.Set("list", v8.NewFunctionTemplate(iso, artifactListCallback(iso, v8ctx, cli)))
-	_ = artObj.Set("delete", v8.NewFunctionTemplate(iso, artifactDeleteCallback(iso, v8ctx, cli)))
-
-	inst, _ := artObj.NewInstance(v8ctx)
-	_ = global.Set("artifact", inst)
+	return InjectArtifactStore(iso, v8ctx, NewArtifactStoreWithClient(cli))
+}
 
-	return nil
+// InjectArtifactStore adds the global 'artifact' object backed by store to
+// the V8 context.
+//
+// All methods return Promises that reject with ArtifactError subclasses.
+// Without an event loop the calls block and the Promises are settled when the
+// method returns; the sandbox runs them in the background instead.
+//
+// Contents are binary-safe: write accepts strings (stored as UTF-8), typed
+// arrays and ArrayBuffers, and read returns the content as a Uint8Array.
+func InjectArtifactStore(iso *v8.Isolate, v8ctx *v8.Context, store ArtifactStore) error {
+	a, err := newArtifactBridge(context.Background(), iso, v8ctx, store, nil)
+	if err != nil {
+		return err
+	}
+	return a.injectService()
 }
 
-// InjectOpenArtifact adds wollmilchsau.openArtifact(name, mimeType) to the V8 context.
+// InjectOpenArtifact adds wollmilchsau.openArtifact(name, mimeType, options) to the V8 context.
 //
 // Usage from JS:
 //
 //	const fh = wollmilchsau.openArtifact("results.csv", "text/csv");
-//	fh.write(csvData);
-//	const meta = fh.close(); // → { id, uri, name, mimeType, fileSize }
+//	fh.write(csvData); // string, typed array or ArrayBuffer
+//	const meta = await fh.close(); // → { id, uri, name, mimeType, fileSize, status }
 //	console.log(`Saved ${meta.fileSize} bytes → ${meta.uri}`);
 //
-// When close() is called the buffer is uploaded via gRPC and the ArtifactRef
+// When close() is called the buffer is written to store and the ArtifactRef
 // is appended to res.CreatedArtifacts so the MCP handler can automatically
 // add a resource_link content item to the tool response.
-func InjectOpenArtifact(iso *v8.Isolate, v8ctx *v8.Context, cli *mlcartifact.Client, res *Result) error {
-	global := v8ctx.Global()
+//
+// With {stream: true} the content is uploaded in parts of chunkBytes
+// (default 1 MB) while it is written, see artifactHandle. Each handle may
+// receive at most DefaultLimits().MaxArtifactBytes.
+func InjectOpenArtifact(iso *v8.Isolate, v8ctx *v8.Context, store ArtifactStore, res *Result) error {
+	a, err := newArtifactBridge(context.Background(), iso, v8ctx, store, nil)
+	if err != nil {
+		return err
+	}
+	return a.injectOpenArtifact(res, DefaultLimits().MaxArtifactBytes)
+}
 
-	// Retrieve or create the `wollmilchsau` namespace object.
-	wmVal, err := global.Get("wollmilchsau")
-	var wmInst *v8.Object
-	if err != nil || wmVal.IsUndefined() || wmVal.IsNull() {
-		tmpl := v8.NewObjectTemplate(iso)
-		wmInst, _ = tmpl.NewInstance(v8ctx)
-		_ = global.Set("wollmilchsau", wmInst)
-	} else {
-		wmInst = wmVal.Object()
+// artifactBridge implements the artifact APIs of one V8 context.
+type artifactBridge struct {
+	iso    *v8.Isolate
+	ctx    *v8.Context
+	runCtx context.Context // the execution; host operations are cancelled with it
+	store  ArtifactStore
+	bin    *binaryBridge
+	loop   *eventLoop   // runs the service calls in the background; nil blocks
+	wrap   *v8.Function // __artifact_wrap from the artifacts prelude
+
+	// openArtifact state, see injectOpenArtifact.
+	res      *Result
+	maxBytes int
+	handles  []*artifactHandle
+}
+
+// newArtifactBridge returns the bridge for v8ctx. Its host operations are
+// cancelled when runCtx is done, i.e. when the execution hits its wall time
+// limit or the client cancels the call.
+func newArtifactBridge(runCtx context.Context, iso *v8.Isolate, v8ctx *v8.Context, store ArtifactStore, loop *eventLoop) (*artifactBridge, error) {
+	bin, err := newBinaryBridge(iso, v8ctx)
+	if err != nil {
+		return nil, err
+	}
+	global := v8ctx.Global()
+	if !global.Has("__artifact_wrap") {
+		if err := runPrelude(v8ctx, "artifacts.js", artifactsJS); err != nil {
+			return nil, err
+		}
+	}
+	wrapVal, err := global.Get("__artifact_wrap")
+	if err != nil {
+		return nil, err
 	}
+	wrap, err := wrapVal.AsFunction()
+	if err != nil {
+		return nil, err
+	}
+	return &artifactBridge{iso: iso, ctx: v8ctx, runCtx: runCtx, store: store, bin: bin, loop: loop, wrap: wrap}, nil
+}
 
-	openFn := v8.NewFunctionTemplate(iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
-		args := info.Args()
-		if len(args) < 1 {
-			return wrapError(iso, v8ctx, "wollmilchsau.openArtifact requires (name, optional mimeType)")
+// injectService adds the global 'artifact' object.
+func (a *artifactBridge) injectService() error {
+	inst, err := v8.NewObjectTemplate(a.iso).NewInstance(a.ctx)
+	if err != nil {
+		return err
+	}
+	for name, cb := range map[string]v8.FunctionCallback{
+		"write":  a.write,
+		"read":   a.read,
+		"list":   a.list,
+		"delete": a.delete,
+	} {
+		fn, err := a.function(cb)
+		if err != nil {
+			return err
 		}
-		name := args[0].String()
-		mimeType := "application/octet-stream"
-		if len(args) >= 2 {
-			mimeType = args[1].String()
+		if err := inst.Set(name, fn); err != nil {
+			return err
 		}
+	}
+	return a.ctx.Global().Set("artifact", inst)
+}
 
-		// Buffer that accumulates write() calls — allocated once per openArtifact call.
-		var buf strings.Builder
+func (a *artifactBridge) write(info *v8.FunctionCallbackInfo) *v8.Value {
+	const op = "artifact.write"
+	args := info.Args()
+	if len(args) < 2 {
+		return a.reject(op, invalidArgument("requires (filename, content)"))
+	}
 
-		handle := v8.NewObjectTemplate(iso)
+	req := ArtifactWrite{Filename: args[0].String()}
+	var err error
+	if req.Content, err = a.bin.bytes(args[1]); err != nil {
+		return a.reject(op, invalidArgument(err.Error()))
+	}
+	if len(args) >= 3 && !args[2].IsUndefined() {
+		req.MimeType = args[2].String()
+	}
+	if len(args) >= 4 && !args[3].IsUndefined() {
+		req.ExpiresHours = int(args[3].Integer())
+	}
+	if len(args) >= 5 && !args[4].IsUndefined() {
+		req.Description = args[4].String()
+	}
+	if len(args) >= 6 && !args[5].IsUndefined() {
+		req.UserID = args[5].String()
+	}
 
-		// fh.write(data string) — appends to the in-memory buffer.
-		_ = handle.Set("write", v8.NewFunctionTemplate(iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
-			if len(info.Args()) > 0 {
-				buf.WriteString(info.Args()[0].String())
-			}
-			return v8.Undefined(iso)
-		}))
-
-		// fh.close() — uploads buffer, registers ArtifactRef, returns metadata to JS.
-		_ = handle.Set("close", v8.NewFunctionTemplate(iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
-			content := []byte(buf.String())
-
-			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-			defer cancel()
-
-			resp, err := cli.Write(ctx, name, content,
-				mlcartifact.WithMimeType(mimeType),
-				mlcartifact.WithSource("wollmilchsau"),
-			)
-			if err != nil {
-				slog.Error("wollmilchsau.openArtifact close() failed", "error", err, "filename", name)
-				return wrapError(iso, v8ctx, "wollmilchsau.openArtifact close() failed: "+err.Error())
-			}
+	return a.call(op, artifactTimeout, func(ctx context.Context) (any, error) {
+		return a.store.Write(ctx, req)
+	}, a.json)
+}
 
-			ref := ArtifactRef{
-				ID:       resp.Id,
-				URI:      resp.Uri,
-				Name:     resp.Filename,
-				MimeType: mimeType,
-				FileSize: int64(len(content)),
-			}
-			// Register in Result so the MCP handler can add resource_link items.
-			res.CreatedArtifacts = append(res.CreatedArtifacts, ref)
+func (a *artifactBridge) read(info *v8.FunctionCallbackInfo) *v8.Value {
+	const op = "artifact.read"
+	args := info.Args()
+	if len(args) < 1 {
+		return a.reject(op, invalidArgument("requires (id)"))
+	}
 
-			return wrapResult(iso, v8ctx, ref)
-		}))
+	id := args[0].String()
+	userID := ""
+	if len(args) >= 2 && !args[1].IsUndefined() {
+		userID = args[1].String()
+	}
 
-		inst, _ := handle.NewInstance(v8ctx)
-		return inst.Value
+	return a.call(op, artifactTimeout, func(ctx context.Context) (any, error) {
+		return a.store.Read(ctx, id, userID)
+	}, func(v any) (*v8.Value, error) {
+		// JSON would turn the content into base64, so it is set separately.
+		res := v.(*Artifact)
+		obj := wrapResult(a.iso, a.ctx, res)
+		u8, err := a.bin.uint8Array(res.Content)
+		if err != nil {
+			return nil, err
+		}
+		if err := obj.Object().Set("content", u8); err != nil {
+			return nil, err
+		}
+		return obj, nil
 	})
-
-	_ = wmInst.Set("openArtifact", openFn.GetFunction(v8ctx))
-	return nil
 }
 
-func artifactWriteCallback(iso *v8.Isolate, v8ctx *v8.Context, cli *mlcartifact.Client) v8.FunctionCallback {
-	return func(info *v8.FunctionCallbackInfo) *v8.Value {
-		args := info.Args()
-		if len(args) < 2 {
-			return v8.Undefined(iso)
-		}
+func (a *artifactBridge) list(info *v8.FunctionCallbackInfo) *v8.Value {
+	args := info.Args()
+	userID := ""
+	if len(args) >= 1 && !args[0].IsUndefined() {
+		userID = args[0].String()
+	}
 
-		filename := args[0].String()
-		content := []byte(args[1].String())
+	return a.call("artifact.list", artifactTimeout, func(ctx context.Context) (any, error) {
+		return a.store.List(ctx, userID)
+	}, a.json)
+}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
+func (a *artifactBridge) delete(info *v8.FunctionCallbackInfo) *v8.Value {
+	const op = "artifact.delete"
+	args := info.Args()
+	if len(args) < 1 {
+		return a.reject(op, invalidArgument("requires (id)"))
+	}
 
-		opts := []mlcartifact.WriteOption{}
-		if len(args) >= 3 && !args[2].IsUndefined() {
-			opts = append(opts, mlcartifact.WithMimeType(args[2].String()))
-		}
-		if len(args) >= 4 && !args[3].IsUndefined() {
-			opts = append(opts, mlcartifact.WithExpiresHours(int32(args[3].Integer())))
-		}
-		if len(args) >= 5 && !args[4].IsUndefined() {
-			opts = append(opts, mlcartifact.WithDescription(args[4].String()))
-		}
-		if len(args) >= 6 && !args[5].IsUndefined() {
-			opts = append(opts, mlcartifact.WithUserID(args[5].String()))
-		}
+	id := args[0].String()
+	userID := ""
+	if len(args) >= 2 && !args[1].IsUndefined() {
+		userID = args[1].String()
+	}
 
-		res, err := cli.Write(ctx, filename, content, opts...)
+	return a.call(op, artifactTimeout, func(ctx context.Context) (any, error) {
+		deleted, err := a.store.Delete(ctx, id, userID)
 		if err != nil {
-			return wrapError(iso, v8ctx, "artifact.write failed: "+err.Error())
+			return nil, err
 		}
+		return map[string]bool{"deleted": deleted}, nil
+	}, a.json)
+}
 
-		return wrapResult(iso, v8ctx, res)
-	}
+// function creates a JS function for cb, wrapped by the prelude so that
+// rejections and failures thrown via throw become ArtifactErrors with the
+// stack trace of the caller. cb may return any value, not only Promises.
+func (a *artifactBridge) function(cb v8.FunctionCallback) (*v8.Value, error) {
+	native := v8.NewFunctionTemplate(a.iso, cb).GetFunction(a.ctx)
+	return a.wrap.Call(v8.Undefined(a.iso), native)
 }
 
-func artifactReadCallback(iso *v8.Isolate, v8ctx *v8.Context, cli *mlcartifact.Client) v8.FunctionCallback {
-	return func(info *v8.FunctionCallbackInfo) *v8.Value {
-		args := info.Args()
-		if len(args) < 1 {
-			return v8.Undefined(iso)
-		}
+// call runs work with the given timeout, bounded by the execution, and
+// returns a Promise for its result. work runs without access to V8, result
+// converts its value back on the JS thread.
+func (a *artifactBridge) call(op string, timeout time.Duration, work func(ctx context.Context) (any, error), result func(any) (*v8.Value, error)) *v8.Value {
+	resolver, err := v8.NewPromiseResolver(a.ctx)
+	if err != nil {
+		return throwTypeError(a.iso, a.ctx, op+" failed: "+err.Error())
+	}
 
-		id := args[0].String()
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	run := func() (any, error) {
+		ctx, cancel := context.WithTimeout(a.runCtx, timeout)
 		defer cancel()
-
-		opts := []mlcartifact.ReadOption{}
-		if len(args) >= 2 && !args[1].IsUndefined() {
-			opts = append(opts, mlcartifact.WithReadUserID(args[1].String()))
-		}
-
-		res, err := cli.Read(ctx, id, opts...)
-		if err != nil {
-			return wrapError(iso, v8ctx, "artifact.read failed: "+err.Error())
+		return work(ctx)
+	}
+	done := func(v any, err error) {
+		if err == nil {
+			var val *v8.Value
+			if val, err = result(v); err == nil {
+				_ = resolver.Resolve(val)
+				return
+			}
 		}
+		_ = resolver.Reject(a.failure(op, err))
+	}
 
-		return wrapResult(iso, v8ctx, res)
+	if a.loop != nil {
+		a.loop.async(run, done)
+	} else {
+		done(run())
 	}
+	return resolver.GetPromise().Value
 }
 
-func artifactListCallback(iso *v8.Isolate, v8ctx *v8.Context, cli *mlcartifact.Client) v8.FunctionCallback {
-	return func(info *v8.FunctionCallbackInfo) *v8.Value {
-		args := info.Args()
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		userID := ""
-		if len(args) >= 1 && !args[0].IsUndefined() {
-			userID = args[0].String()
-		}
-
-		res, err := cli.List(ctx, userID)
-		if err != nil {
-			return wrapError(iso, v8ctx, "artifact.list failed: "+err.Error())
-		}
-
-		return wrapResult(iso, v8ctx, res.Items)
+// resolved returns a Promise that is already resolved with v.
+func (a *artifactBridge) resolved(v *v8.Value) *v8.Value {
+	resolver, err := v8.NewPromiseResolver(a.ctx)
+	if err != nil {
+		return throwTypeError(a.iso, a.ctx, err.Error())
 	}
+	_ = resolver.Resolve(v)
+	return resolver.GetPromise().Value
 }
 
-func artifactDeleteCallback(iso *v8.Isolate, v8ctx *v8.Context, cli *mlcartifact.Client) v8.FunctionCallback {
-	return func(info *v8.FunctionCallbackInfo) *v8.Value {
-		args := info.Args()
-		if len(args) < 1 {
-			return v8.Undefined(iso)
-		}
+// throw throws err synchronously; the prelude turns it into an ArtifactError.
+func (a *artifactBridge) throw(op string, err error) *v8.Value {
+	return a.iso.ThrowException(a.failure(op, err))
+}
 
-		id := args[0].String()
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
+// reject returns a Promise that is already rejected with err.
+func (a *artifactBridge) reject(op string, err error) *v8.Value {
+	resolver, rerr := v8.NewPromiseResolver(a.ctx)
+	if rerr != nil {
+		return throwTypeError(a.iso, a.ctx, op+" failed: "+err.Error())
+	}
+	_ = resolver.Reject(a.failure(op, err))
+	return resolver.GetPromise().Value
+}
 
-		opts := []mlcartifact.DeleteOption{}
-		if len(args) >= 2 && !args[1].IsUndefined() {
-			opts = append(opts, mlcartifact.WithDeleteUserID(args[1].String()))
-		}
+// failure describes err as {code, message} with the Connect error code,
+// which the prelude turns into the matching ArtifactError subclass.
+func (a *artifactBridge) failure(op string, err error) *v8.Value {
+	code := connect.CodeUnknown.String()
+	msg := err.Error()
+	var connectErr *connect.Error
+	if errors.As(err, &connectErr) {
+		code = connectErr.Code().String()
+		msg = connectErr.Message()
+	}
+	return wrapResult(a.iso, a.ctx, map[string]string{"code": code, "message": op + " failed: " + msg})
+}
 
-		res, err := cli.Delete(ctx, id, opts...)
-		if err != nil {
-			return wrapError(iso, v8ctx, "artifact.delete failed: "+err.Error())
-		}
+func (a *artifactBridge) json(v any) (*v8.Value, error) {
+	return wrapResult(a.iso, a.ctx, v), nil
+}
 
-		return wrapResult(iso, v8ctx, res)
-	}
+func invalidArgument(msg string) error {
+	return connect.NewError(connect.CodeInvalidArgument, errors.New(msg))
 }
 
 func wrapResult(iso *v8.Isolate, ctx *v8.Context, val any) *v8.Value {
@@ -237,10 +330,104 @@ func wrapResult(iso *v8.Isolate, ctx *v8.Context, val any) *v8.Value {
 	return v
 }
 
-func wrapError(iso *v8.Isolate, ctx *v8.Context, msg string) *v8.Value {
-	// For simplicity, we return an object with 'error' property
-	obj, _ := v8.NewObjectTemplate(iso).NewInstance(ctx)
-	errVal, _ := v8.NewValue(iso, msg)
-	_ = obj.Set("error", errVal)
-	return obj.Value
+// artifactsJS defines the ArtifactError classes and __artifact_wrap, which
+// turns the {code, message} failures of the Go functions into errors.
+//
+// The stack trace is captured when the operation starts, because the
+// rejection itself happens later without any script frames on the stack.
+const artifactsJS = `(function() {
+	class ArtifactError extends Error {
+		constructor(message, code) {
+			super(message);
+			this.code = code;
+		}
+	}
+	class ArtifactNotFoundError extends ArtifactError {}
+	class ArtifactPermissionError extends ArtifactError {}
+	class ArtifactUnavailableError extends ArtifactError {}
+
+	const classes = [ArtifactError, ArtifactNotFoundError, ArtifactPermissionError, ArtifactUnavailableError];
+	for (const cls of classes) {
+		Object.defineProperty(cls.prototype, 'name', { value: cls.name, writable: true, configurable: true });
+		Object.defineProperty(globalThis, cls.name, { value: cls, writable: true, configurable: true });
+	}
+
+	const byCode = {
+		not_found: ArtifactNotFoundError,
+		permission_denied: ArtifactPermissionError,
+		unauthenticated: ArtifactPermissionError,
+		unavailable: ArtifactUnavailableError,
+		deadline_exceeded: ArtifactUnavailableError,
+	};
+
+	const NativePromise = Promise;
+	const isFailure = (v) => v !== null && typeof v === 'object' && !(v instanceof Error) && typeof v.code === 'string';
+
+	function toError(failure, site) {
+		const Cls = byCode[failure.code] || ArtifactError;
+		const err = new Cls(failure.message, failure.code);
+		const frames = site.stack.indexOf('\n');
+		err.stack = err.name + ': ' + err.message + (frames >= 0 ? site.stack.slice(frames) : '');
+		return err;
+	}
+
+	Object.defineProperty(globalThis, '__artifact_wrap', {
+		value: function(native) {
+			const op = function(...args) {
+				const site = {};
+				Error.captureStackTrace(site, op);
+				let result;
+				try {
+					result = native(...args);
+				} catch (failure) {
+					throw isFailure(failure) ? toError(failure, site) : failure;
+				}
+				if (!(result instanceof NativePromise)) return result;
+				return result.catch((failure) => { throw toError(failure, site); });
+			};
+			return op;
+		},
+	});
+})();
+`
+
+// artifactErrorsDeclarations declares the error classes of artifactsJS. The
+// prelude runs in every sandbox, so they exist without an artifact store, too.
+const artifactErrorsDeclarations = `/** The error of failed artifact operations. */
+declare class ArtifactError extends Error {
+  constructor(message?: string, code?: string);
+  /** The Connect error code, e.g. "not_found". */
+  code: string;
+}
+declare class ArtifactNotFoundError extends ArtifactError {}
+declare class ArtifactPermissionError extends ArtifactError {}
+declare class ArtifactUnavailableError extends ArtifactError {}
+`
+
+// artifactsDeclarations declares the globals of InjectArtifactStore and the
+// fields of ArtifactInfo.
+const artifactsDeclarations = `/** Metadata of a stored artifact. */
+interface ArtifactInfo {
+  id: string;
+  filename: string;
+  uri?: string;
+  mime_type?: string;
+  source?: string;
+  /** RFC 3339 */
+  created_at?: string;
+  /** RFC 3339, missing if the artifact does not expire */
+  expires_at?: string;
+  size_bytes?: number;
+  user_id?: string;
+  description?: string;
+  metadata?: { [key: string]: string };
 }
+
+/** The artifact service. Failures reject with an ArtifactError. */
+declare var artifact: {
+  write(filename: string, content: string | Uint8Array | ArrayBuffer | ArrayBufferView, mimeType?: string, expiresHours?: number, description?: string, userId?: string): Promise<ArtifactInfo>;
+  read(idOrFilename: string, userId?: string): Promise<ArtifactInfo & { content: Uint8Array }>;
+  list(userId?: string): Promise<ArtifactInfo[]>;
+  delete(idOrFilename: string, userId?: string): Promise<{ deleted: boolean }>;
+};
+`
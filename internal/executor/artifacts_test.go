@@ -4,31 +4,54 @@ package executor
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"connectrpc.com/connect"
 	mlcartifact "github.com/hmsoft0815/mlcartifact/client"
 	pb "github.com/hmsoft0815/mlcartifact/proto"
+	"github.com/hmsoft0815/wollmilchsau/internal/bundler"
+	"github.com/hmsoft0815/wollmilchsau/internal/parser"
 	v8 "rogchap.com/v8go"
 )
 
 type mockArtifactService struct {
+	mu        sync.Mutex // uploads of streamed handles run concurrently
 	lastWrite *pb.WriteRequest
+	writes    []*pb.WriteRequest
+	writeErr  error
+	deleted   []string
 	readData  []byte
+	readErr   error
 	listItems []*pb.ArtifactInfo
 }
 
 func (m *mockArtifactService) Write(ctx context.Context, req *connect.Request[pb.WriteRequest]) (*connect.Response[pb.WriteResponse], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.writeErr != nil {
+		return nil, m.writeErr
+	}
 	m.lastWrite = req.Msg
+	m.writes = append(m.writes, req.Msg)
+	id := "test-id"
+	if part := req.Msg.Metadata["part"]; part != "" {
+		id += "-" + part
+	}
 	return connect.NewResponse(&pb.WriteResponse{
-		Id:       "test-id",
+		Id:       id,
 		Filename: req.Msg.Filename,
 		Uri:      "mcp:///test-id",
 	}), nil
 }
 
 func (m *mockArtifactService) Read(ctx context.Context, req *connect.Request[pb.ReadRequest]) (*connect.Response[pb.ReadResponse], error) {
+	if m.readErr != nil {
+		return nil, m.readErr
+	}
 	return connect.NewResponse(&pb.ReadResponse{
 		Content:  m.readData,
 		MimeType: "text/plain",
@@ -43,6 +66,9 @@ func (m *mockArtifactService) List(ctx context.Context, req *connect.Request[pb.
 }
 
 func (m *mockArtifactService) Delete(ctx context.Context, req *connect.Request[pb.DeleteRequest]) (*connect.Response[pb.DeleteResponse], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleted = append(m.deleted, req.Msg.Id)
 	return connect.NewResponse(&pb.DeleteResponse{
 		Deleted: true,
 	}), nil
@@ -70,18 +96,12 @@ func TestArtifactBridge(t *testing.T) {
 
 	// Test Write
 	t.Run("write", func(t *testing.T) {
-		js := `
-			(() => {
-				const res = artifact.write("test.txt", "content", "text/plain", 24, "This is a test description");
-				return JSON.stringify(res);
-			})()
-		`
+		js := `artifact.write("test.txt", "content", "text/plain", 24, "This is a test description").then(JSON.stringify)`
 		val, err := v8ctx.RunScript(js, "test_write.js")
 		if err != nil {
 			t.Fatalf("Script failed: %v", err)
 		}
-		// ... parser logic ...
-		resStr := val.String()
+		resStr := settled(t, v8ctx, val).String()
 		var res map[string]any
 		if err := json.Unmarshal([]byte(resStr), &res); err != nil {
 			t.Fatalf("Failed to parse response: %v", err)
@@ -98,22 +118,184 @@ func TestArtifactBridge(t *testing.T) {
 		}
 	})
 
-	// Test List
-	t.Run("list", func(t *testing.T) {
+	// Test binary write: typed arrays, views and ArrayBuffers are stored as raw bytes.
+	t.Run("write binary", func(t *testing.T) {
+		tests := []struct {
+			name string
+			expr string
+			want []byte
+		}{
+			{"Uint8Array", `new Uint8Array([0, 137, 80, 78, 71, 255])`, []byte{0, 137, 80, 78, 71, 255}},
+			{"subarray", `new Uint8Array([1, 2, 3, 4]).subarray(1, 3)`, []byte{2, 3}},
+			{"ArrayBuffer", `new Uint16Array([0xffee]).buffer`, []byte{0xee, 0xff}},
+			{"DataView", `new DataView(new Uint8Array([9, 8, 7]).buffer, 1)`, []byte{8, 7}},
+			{"string", `"grüße"`, []byte("grüße")},
+		}
+		for _, tt := range tests {
+			val, err := v8ctx.RunScript(`artifact.write("bin.dat", `+tt.expr+`)`, "test_write_binary.js")
+			if err != nil {
+				t.Fatalf("%s: script failed: %v", tt.name, err)
+			}
+			settled(t, v8ctx, val)
+			if got := mockSvc.lastWrite.Content; string(got) != string(tt.want) {
+				t.Errorf("%s: content = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	})
+
+	// Test binary read: the content is returned as a Uint8Array.
+	t.Run("read binary", func(t *testing.T) {
+		mockSvc.readData = []byte{0, 128, 255, 10}
+		js := `artifact.read("test-id").then((res) =>
+			(res.content instanceof Uint8Array) + " " + Array.from(res.content).join(",") + " " + res.filename)`
+		val, err := v8ctx.RunScript(js, "test_read_binary.js")
+		if err != nil {
+			t.Fatalf("Script failed: %v", err)
+		}
+		if got, want := settled(t, v8ctx, val).String(), "true 0,128,255,10 test.txt"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	// Test openArtifact: write() accepts strings and binary data.
+	t.Run("openArtifact binary", func(t *testing.T) {
+		res := &Result{}
+		if err := InjectOpenArtifact(iso, v8ctx, NewArtifactStoreWithClient(cli), res); err != nil {
+			t.Fatalf("Failed to inject openArtifact: %v", err)
+		}
 		js := `
-			(() => {
-				const items = artifact.list();
-				return JSON.stringify(items);
-			})()
+			const fh = wollmilchsau.openArtifact("image.png", "image/png");
+			fh.write(new Uint8Array([0x89, 0x50]));
+			fh.write(new Uint8Array([0x4e, 0x47]).buffer);
+			fh.write("!");
+			fh.close().then((meta) => meta.fileSize);
 		`
+		val, err := v8ctx.RunScript(js, "test_open_binary.js")
+		if err != nil {
+			t.Fatalf("Script failed: %v", err)
+		}
+		val = settled(t, v8ctx, val)
+		if val.Integer() != 5 || string(mockSvc.lastWrite.Content) != "\x89PNG!" {
+			t.Errorf("got size %d content %q", val.Integer(), mockSvc.lastWrite.Content)
+		}
+		if len(res.CreatedArtifacts) != 1 || res.CreatedArtifacts[0].FileSize != 5 {
+			t.Errorf("unexpected created artifacts: %+v", res.CreatedArtifacts)
+		}
+	})
+
+	// Test errors: failures reject with ArtifactError subclasses whose stack
+	// starts at the caller.
+	t.Run("errors", func(t *testing.T) {
+		mockSvc.readErr = connect.NewError(connect.CodeNotFound, errors.New("artifact missing-id not found"))
+		defer func() { mockSvc.readErr = nil }()
+
+		js := "function load() {\n  return artifact.read('missing-id');\n}\n" +
+			"load().catch((e) => [e instanceof ArtifactNotFoundError, e instanceof ArtifactError, e instanceof Error, e.name, e.code, e.message, e.stack.split('\\n')[1].trim()].join('|'))"
+		val, err := v8ctx.RunScript(js, "test_errors.js")
+		if err != nil {
+			t.Fatalf("Script failed: %v", err)
+		}
+		want := "true|true|true|ArtifactNotFoundError|not_found|artifact.read failed: artifact missing-id not found|at load (test_errors.js:2:19)"
+		if got := settled(t, v8ctx, val).String(); got != want {
+			t.Errorf("got  %q\nwant %q", got, want)
+		}
+
+		val, err = v8ctx.RunScript(`artifact.write("only-name").catch((e) => e.name + "|" + e.code)`, "test_errors.js")
+		if err != nil {
+			t.Fatalf("Script failed: %v", err)
+		}
+		if got, want := settled(t, v8ctx, val).String(), "ArtifactError|invalid_argument"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	// Test List
+	t.Run("list", func(t *testing.T) {
+		js := `artifact.list().then(JSON.stringify)`
 		val, err := v8ctx.RunScript(js, "test_list.js")
 		if err != nil {
 			t.Fatalf("Script failed: %v", err)
 		}
 
-		resStr := val.String()
+		resStr := settled(t, v8ctx, val).String()
 		if !strings.Contains(resStr, "f1.txt") {
 			t.Errorf("Expected list to contain f1.txt, got %s", resStr)
 		}
 	})
 }
+
+// settled runs pending promise jobs and returns the value val resolved to.
+func settled(t *testing.T, v8ctx *v8.Context, val *v8.Value) *v8.Value {
+	t.Helper()
+	v8ctx.PerformMicrotaskCheckpoint()
+	prom := asPromise(val)
+	if prom == nil {
+		t.Fatalf("expected a Promise, got %s", val.String())
+	}
+	switch prom.State() {
+	case v8.Fulfilled:
+		return prom.Result()
+	case v8.Rejected:
+		t.Fatalf("promise rejected: %s", prom.Result().String())
+	default:
+		t.Fatal("promise still pending")
+	}
+	return nil
+}
+
+func TestExecute_ArtifactErrors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Nothing listens on port 1, so every call fails with code unavailable.
+	store, err := NewServiceArtifactStore("127.0.0.1:1")
+	if err != nil {
+		t.Fatalf("NewServiceArtifactStore failed: %v", err)
+	}
+	defer store.Close()
+	opts := Options{Artifacts: store}
+
+	t.Run("caught", func(t *testing.T) {
+		code := `(async () => {
+			const results = await Promise.allSettled([artifact.read("a"), artifact.list()]);
+			for (const r of results) console.log(r.status, r.reason.name, r.reason.code);
+		})()`
+		res := Execute(ctx, code, "test.js", nil, opts)
+		if !res.Success {
+			t.Fatalf("Execution failed: %s", res.Summary)
+		}
+		want := "rejected ArtifactUnavailableError unavailable\nrejected ArtifactUnavailableError unavailable\n"
+		if res.Stdout != want {
+			t.Errorf("stdout = %q, want %q", res.Stdout, want)
+		}
+	})
+
+	t.Run("unclosed handle is aborted", func(t *testing.T) {
+		code := `wollmilchsau.openArtifact("out.txt", "text/plain", { stream: true }).write("pending");`
+		res := Execute(ctx, code, "test.js", nil, opts)
+		if !res.Success {
+			t.Fatalf("Execution failed: %s", res.Summary)
+		}
+		if len(res.CreatedArtifacts) != 1 || res.CreatedArtifacts[0].Status != ArtifactAborted || res.CreatedArtifacts[0].FileSize != 7 {
+			t.Errorf("unexpected created artifacts: %+v", res.CreatedArtifacts)
+		}
+	})
+
+	t.Run("uncaught maps to the caller", func(t *testing.T) {
+		plan := &parser.ExecutionPlan{
+			Files:      []parser.VirtualFile{{Name: "main.ts", Content: "const id: string = 'report';\n\nconst data = await artifact.read(id);\nconsole.log(data);\n"}},
+			EntryPoint: "main.ts",
+		}
+		b, err := bundler.Bundle(plan)
+		if err != nil {
+			t.Fatalf("bundle failed: %v", err)
+		}
+		res := Execute(ctx, b.JS, "main.ts", b.SourceMap, opts)
+		if res.Success {
+			t.Fatal("expected the execution to fail")
+		}
+		if !strings.HasPrefix(res.Summary, "Runtime Error: ArtifactUnavailableError: artifact.read failed:") || !strings.HasSuffix(res.Summary, "in main.ts:3") {
+			t.Errorf("unexpected summary: %s", res.Summary)
+		}
+	})
+}
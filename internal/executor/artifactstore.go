@@ -0,0 +1,133 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+)
+
+// ArtifactStore is the backend behind artifact.*, wollmilchsau.openArtifact,
+// the spilled output and the execute_artifact tool. Implementations must be
+// safe for concurrent use.
+//
+// Errors should be *connect.Error values; their code selects the
+// ArtifactError subclass seen by scripts (e.g. CodeNotFound →
+// ArtifactNotFoundError).
+type ArtifactStore interface {
+	// Write stores a new artifact and returns its metadata.
+	Write(ctx context.Context, req ArtifactWrite) (*ArtifactInfo, error)
+	// Read returns the artifact with the given ID or filename.
+	Read(ctx context.Context, idOrFilename, userID string) (*Artifact, error)
+	// List returns the artifacts of userID, or the shared ones if it is empty.
+	List(ctx context.Context, userID string) ([]*ArtifactInfo, error)
+	// Delete removes an artifact and reports whether it existed.
+	Delete(ctx context.Context, idOrFilename, userID string) (bool, error)
+	// Close releases the resources of the store.
+	Close() error
+}
+
+// ArtifactWrite describes an artifact to be stored.
+type ArtifactWrite struct {
+	Filename     string
+	Content      []byte
+	MimeType     string
+	Description  string
+	Source       string
+	UserID       string            // owner; empty stores a shared artifact
+	ExpiresHours int               // 0 uses the default of the store
+	Metadata     map[string]string // free-form tags
+}
+
+// ArtifactInfo is the metadata of a stored artifact. The JSON field names
+// match the mlcartifact service, because scripts see this structure.
+type ArtifactInfo struct {
+	ID          string `json:"id"`
+	Filename    string `json:"filename"`
+	URI         string `json:"uri,omitempty"`
+	MimeType    string `json:"mime_type,omitempty"`
+	Source      string `json:"source,omitempty"`
+	CreatedAt   string `json:"created_at,omitempty"` // RFC 3339 (ISO 8601)
+	ExpiresAt   string `json:"expires_at,omitempty"` // RFC 3339 (ISO 8601), empty if the artifact does not expire
+	SizeBytes   int64  `json:"size_bytes,omitempty"`
+	UserID      string `json:"user_id,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Artifact is a stored artifact including its content.
+type Artifact struct {
+	ArtifactInfo
+	Content []byte `json:"-"`
+}
+
+// errArtifactNotFound is returned by the local stores for unknown artifacts.
+func errArtifactNotFound(idOrFilename string) error {
+	return connect.NewError(connect.CodeNotFound, fmt.Errorf("artifact %s not found", idOrFilename))
+}
+
+// isNotFound reports whether err has the code CodeNotFound.
+func isNotFound(err error) bool {
+	var connectErr *connect.Error
+	return errors.As(err, &connectErr) && connectErr.Code() == connect.CodeNotFound
+}
+
+// newArtifactInfo returns the metadata of a new artifact written by req.
+// It is shared by the local stores, which generate their own IDs.
+func newArtifactInfo(req ArtifactWrite) (*ArtifactInfo, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	info := &ArtifactInfo{
+		ID:          hex.EncodeToString(id),
+		Filename:    sanitizeFilename(req.Filename),
+		MimeType:    req.MimeType,
+		Source:      req.Source,
+		CreatedAt:   now.Format(time.RFC3339Nano),
+		SizeBytes:   int64(len(req.Content)),
+		UserID:      req.UserID,
+		Description: req.Description,
+		Metadata:    req.Metadata,
+	}
+	if info.MimeType == "" {
+		info.MimeType = "application/octet-stream"
+	}
+	if req.ExpiresHours > 0 {
+		info.ExpiresAt = now.Add(time.Duration(req.ExpiresHours) * time.Hour).Format(time.RFC3339)
+	}
+	return info, nil
+}
+
+// expired reports whether the artifact has passed its expiry time.
+func (info *ArtifactInfo) expired(now time.Time) bool {
+	if info.ExpiresAt == "" {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339Nano, info.ExpiresAt)
+	return err == nil && now.After(t)
+}
+
+// matches reports whether info is visible to userID under idOrFilename.
+func (info *ArtifactInfo) matches(idOrFilename, userID string) bool {
+	return info.UserID == userID && (info.ID == idOrFilename || info.Filename == idOrFilename)
+}
+
+// sanitizeFilename strips directories from name, so it can be used as a
+// path element.
+func sanitizeFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == "/" || name == ".." {
+		return "artifact"
+	}
+	return name
+}
@@ -0,0 +1,283 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DirArtifactStore keeps artifacts in a local directory:
+//
+//	<dir>/<id>.json        metadata
+//	<dir>/<id>/<filename>  content
+//
+// It also implements http.Handler, serving the content under
+// /<id>/<filename> so that the SSE server can hand out HTTP links.
+type DirArtifactStore struct {
+	dir     string
+	baseURL string // prefix of the URIs; empty for file:// URIs
+
+	mu sync.RWMutex
+}
+
+// NewDirArtifactStore returns a store that keeps artifacts in dir, creating
+// it if necessary. With a baseURL the URIs of the artifacts are
+// <baseURL>/<id>/<filename>, otherwise they are file:// URIs.
+//
+// Expired artifacts are hidden immediately and deleted when the store is
+// opened the next time.
+func NewDirArtifactStore(dir, baseURL string) (*DirArtifactStore, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(abs, 0o755); err != nil {
+		return nil, fmt.Errorf("creating artifact directory: %w", err)
+	}
+	d := &DirArtifactStore{dir: abs, baseURL: strings.TrimSuffix(baseURL, "/")}
+	d.prune()
+	return d, nil
+}
+
+func (d *DirArtifactStore) Write(ctx context.Context, req ArtifactWrite) (*ArtifactInfo, error) {
+	info, err := newArtifactInfo(req)
+	if err != nil {
+		return nil, err
+	}
+	info.URI = d.uri(info)
+	meta, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := os.Mkdir(filepath.Join(d.dir, info.ID), 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(d.contentPath(info), req.Content, 0o644); err != nil {
+		return nil, err
+	}
+	// The metadata is written last: an artifact exists once it is complete.
+	if err := os.WriteFile(d.metaPath(info.ID), meta, 0o644); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (d *DirArtifactStore) Read(ctx context.Context, idOrFilename, userID string) (*Artifact, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	info, err := d.find(idOrFilename, userID)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(d.contentPath(info))
+	if err != nil {
+		return nil, err
+	}
+	return &Artifact{ArtifactInfo: *info, Content: content}, nil
+}
+
+func (d *DirArtifactStore) List(ctx context.Context, userID string) ([]*ArtifactInfo, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	all, err := d.all()
+	if err != nil {
+		return nil, err
+	}
+	items := []*ArtifactInfo{}
+	for _, info := range all {
+		if info.UserID == userID {
+			items = append(items, info)
+		}
+	}
+	return items, nil
+}
+
+func (d *DirArtifactStore) Delete(ctx context.Context, idOrFilename, userID string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	info, err := d.find(idOrFilename, userID)
+	if isNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, d.remove(info.ID)
+}
+
+func (d *DirArtifactStore) Close() error {
+	return nil
+}
+
+// inlineMimeTypes are the types that browsers cannot execute, so artifacts
+// of these types are shown inline. Everything else, in particular HTML and
+// SVG written by a script, is served as a download to keep it from running
+// on the origin of the server.
+var inlineMimeTypes = map[string]bool{
+	"text/plain":       true,
+	"text/csv":         true,
+	"application/json": true,
+	"image/png":        true,
+	"image/jpeg":       true,
+	"image/gif":        true,
+	"image/webp":       true,
+}
+
+// ServeHTTP serves the content of the artifact at /<id>/<filename>.
+// Directory listings are not available, so the IDs cannot be enumerated.
+func (d *DirArtifactStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, name, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	d.mu.RLock()
+	info, err := d.info(id)
+	d.mu.RUnlock()
+	if err != nil || info.Filename != name || info.expired(time.Now()) {
+		http.NotFound(w, r)
+		return
+	}
+	f, err := os.Open(d.contentPath(info))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", info.MimeType)
+	h.Set("X-Content-Type-Options", "nosniff")
+	if mediaType, _, err := mime.ParseMediaType(info.MimeType); err != nil || !inlineMimeTypes[mediaType] {
+		h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": info.Filename}))
+	}
+	// ServeContent, unlike ServeFile, does not redirect paths ending in /index.html.
+	http.ServeContent(w, r, info.Filename, stat.ModTime(), f)
+}
+
+func (d *DirArtifactStore) uri(info *ArtifactInfo) string {
+	if d.baseURL != "" {
+		return d.baseURL + "/" + info.ID + "/" + url.PathEscape(info.Filename)
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(d.contentPath(info))}).String()
+}
+
+func (d *DirArtifactStore) metaPath(id string) string {
+	return filepath.Join(d.dir, id+".json")
+}
+
+func (d *DirArtifactStore) contentPath(info *ArtifactInfo) string {
+	return filepath.Join(d.dir, info.ID, info.Filename)
+}
+
+// info reads the metadata of the artifact with the given ID.
+func (d *DirArtifactStore) info(id string) (*ArtifactInfo, error) {
+	// IDs are hex, which also keeps them from escaping the directory.
+	if _, err := hex.DecodeString(id); err != nil || id == "" {
+		return nil, errArtifactNotFound(id)
+	}
+	data, err := os.ReadFile(d.metaPath(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, errArtifactNotFound(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var info ArtifactInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("artifact %s: %w", id, err)
+	}
+	return &info, nil
+}
+
+// all returns the metadata of all artifacts that have not expired, oldest first.
+func (d *DirArtifactStore) all() ([]*ArtifactInfo, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var infos []*ArtifactInfo
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() {
+			continue
+		}
+		info, err := d.info(id)
+		if err != nil || info.expired(now) {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	sort.SliceStable(infos, func(i, j int) bool {
+		ti, _ := time.Parse(time.RFC3339Nano, infos[i].CreatedAt)
+		tj, _ := time.Parse(time.RFC3339Nano, infos[j].CreatedAt)
+		return ti.Before(tj)
+	})
+	return infos, nil
+}
+
+// find returns the newest artifact matching idOrFilename.
+func (d *DirArtifactStore) find(idOrFilename, userID string) (*ArtifactInfo, error) {
+	if info, err := d.info(idOrFilename); err == nil {
+		if info.UserID != userID || info.expired(time.Now()) {
+			return nil, errArtifactNotFound(idOrFilename)
+		}
+		return info, nil
+	}
+	all, err := d.all()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(all) - 1; i >= 0; i-- {
+		if all[i].matches(idOrFilename, userID) {
+			return all[i], nil
+		}
+	}
+	return nil, errArtifactNotFound(idOrFilename)
+}
+
+// prune deletes the expired artifacts.
+func (d *DirArtifactStore) prune() {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() {
+			continue
+		}
+		if info, err := d.info(id); err == nil && info.expired(now) {
+			if err := d.remove(id); err != nil {
+				slog.Warn("failed to delete expired artifact", "id", id, "err", err)
+			}
+		}
+	}
+}
+
+func (d *DirArtifactStore) remove(id string) error {
+	if err := os.Remove(d.metaPath(id)); err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(d.dir, id))
+}
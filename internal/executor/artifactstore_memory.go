@@ -0,0 +1,93 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryArtifactStore keeps artifacts in memory for the lifetime of the process.
+type memoryArtifactStore struct {
+	mu        sync.Mutex
+	artifacts []*Artifact // in creation order
+}
+
+// NewMemoryArtifactStore returns a store that keeps all artifacts in memory.
+// Its URIs have the form memory://artifacts/<id>/<filename>.
+func NewMemoryArtifactStore() ArtifactStore {
+	return &memoryArtifactStore{}
+}
+
+func (m *memoryArtifactStore) Write(ctx context.Context, req ArtifactWrite) (*ArtifactInfo, error) {
+	info, err := newArtifactInfo(req)
+	if err != nil {
+		return nil, err
+	}
+	info.URI = "memory://artifacts/" + info.ID + "/" + info.Filename
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.artifacts = append(m.artifacts, &Artifact{ArtifactInfo: *info, Content: bytes.Clone(req.Content)})
+	return info, nil
+}
+
+func (m *memoryArtifactStore) Read(ctx context.Context, idOrFilename, userID string) (*Artifact, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.find(idOrFilename, userID)
+	if i < 0 {
+		return nil, errArtifactNotFound(idOrFilename)
+	}
+	a := *m.artifacts[i]
+	a.Content = bytes.Clone(a.Content)
+	return &a, nil
+}
+
+func (m *memoryArtifactStore) List(ctx context.Context, userID string) ([]*ArtifactInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	items := []*ArtifactInfo{}
+	for _, a := range m.artifacts {
+		if a.UserID == userID && !a.expired(now) {
+			info := a.ArtifactInfo
+			items = append(items, &info)
+		}
+	}
+	return items, nil
+}
+
+func (m *memoryArtifactStore) Delete(ctx context.Context, idOrFilename, userID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.find(idOrFilename, userID)
+	if i < 0 {
+		return false, nil
+	}
+	m.artifacts = append(m.artifacts[:i], m.artifacts[i+1:]...)
+	return true, nil
+}
+
+func (m *memoryArtifactStore) Close() error {
+	return nil
+}
+
+// find returns the index of the newest matching artifact or -1. Expired
+// artifacts are dropped on the way. m.mu must be held.
+func (m *memoryArtifactStore) find(idOrFilename, userID string) int {
+	now := time.Now()
+	for i := len(m.artifacts) - 1; i >= 0; i-- {
+		a := m.artifacts[i]
+		if !a.matches(idOrFilename, userID) {
+			continue
+		}
+		if a.expired(now) {
+			m.artifacts = append(m.artifacts[:i], m.artifacts[i+1:]...)
+			continue
+		}
+		return i
+	}
+	return -1
+}
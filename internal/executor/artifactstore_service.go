@@ -0,0 +1,316 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	mlcartifact "github.com/hmsoft0815/mlcartifact/client"
+	pb "github.com/hmsoft0815/mlcartifact/proto"
+)
+
+// Reconnect schedule of the ServiceArtifactStore.
+const (
+	serviceRetryMin     = 500 * time.Millisecond
+	serviceRetryMax     = 30 * time.Second
+	serviceProbeTimeout = 2 * time.Second
+)
+
+// ServiceArtifactStore stores artifacts in the mlcartifact service. It holds
+// one HTTP/2 connection that is shared by all executions and only dialed
+// once artifacts are actually used.
+//
+// When the service becomes unreachable, the store drops the connection and
+// probes the service in the background with exponential backoff. Until a
+// probe succeeds, calls fail immediately with CodeUnavailable instead of
+// waiting for another dial to fail.
+type ServiceArtifactStore struct {
+	addr string
+	http *http.Client // nil for a client given to NewArtifactStoreWithClient
+	cli  *mlcartifact.Client
+
+	mu      sync.Mutex
+	err     error         // why the service is unavailable; nil while it is reachable
+	backoff time.Duration // current delay between reconnect attempts
+	retryAt time.Time     // calls fail fast until then
+	probing bool          // the background probe is running
+	closed  bool
+	stop    chan struct{}
+}
+
+// NewServiceArtifactStore returns a store for the mlcartifact service at
+// addr. An empty addr uses ARTIFACT_GRPC_ADDR or the default local address.
+// No connection is made until the store is used.
+func NewServiceArtifactStore(addr string) (*ServiceArtifactStore, error) {
+	if addr == "" {
+		addr = os.Getenv("ARTIFACT_GRPC_ADDR")
+	}
+	if addr == "" {
+		addr = ":9590"
+	}
+
+	// Cleartext HTTP/2 (h2c) like the default client of mlcartifact, but
+	// with a transport we can reset when reconnecting.
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	httpClient := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+
+	cli, err := mlcartifact.NewClientWithAddr(addr, mlcartifact.WithHTTPClient(httpClient))
+	if err != nil {
+		return nil, err
+	}
+	s := newServiceArtifactStore(cli)
+	s.addr = addr
+	s.http = httpClient
+	return s, nil
+}
+
+// NewArtifactStoreWithClient returns a store backed by the given client.
+// Useful for testing.
+func NewArtifactStoreWithClient(cli *mlcartifact.Client) ArtifactStore {
+	return newServiceArtifactStore(cli)
+}
+
+func newServiceArtifactStore(cli *mlcartifact.Client) *ServiceArtifactStore {
+	return &ServiceArtifactStore{addr: "(custom client)", cli: cli, stop: make(chan struct{})}
+}
+
+// Addr returns the address of the service.
+func (s *ServiceArtifactStore) Addr() string {
+	return s.addr
+}
+
+// Ping checks whether the service is reachable. Unlike the other methods
+// it always contacts the service.
+func (s *ServiceArtifactStore) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, serviceProbeTimeout)
+	defer cancel()
+	_, err := s.cli.List(ctx, "", mlcartifact.WithLimit(1))
+	s.observe(err)
+	return err
+}
+
+// call runs fn unless the service is known to be unavailable and records
+// whether it could reach the service.
+func (s *ServiceArtifactStore) call(fn func() error) error {
+	if err := s.available(); err != nil {
+		return err
+	}
+	err := fn()
+	s.observe(err)
+	return err
+}
+
+// available returns an error with code CodeUnavailable while the store is
+// closed or waiting for the next reconnect attempt.
+func (s *ServiceArtifactStore) available() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return connect.NewError(connect.CodeUnavailable, errors.New("artifact store is closed"))
+	}
+	if s.err == nil || !time.Now().Before(s.retryAt) {
+		return nil
+	}
+	cause := s.err.Error()
+	var connectErr *connect.Error
+	if errors.As(s.err, &connectErr) {
+		cause = connectErr.Message()
+	}
+	return connect.NewError(connect.CodeUnavailable, fmt.Errorf("artifact service at %s is unavailable (next retry in %s): %s",
+		s.addr, time.Until(s.retryAt).Round(time.Millisecond), cause))
+}
+
+// observe updates the health of the service after a call. Any answer of
+// the service, including errors such as not_found, shows that it is up.
+func (s *ServiceArtifactStore) observe(err error) {
+	var connectErr *connect.Error
+	down := errors.As(err, &connectErr) && connectErr.Code() == connect.CodeUnavailable
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	if !down {
+		if s.err != nil {
+			slog.Info("artifact service is reachable again", "addr", s.addr)
+			s.err, s.backoff = nil, 0
+		}
+		return
+	}
+
+	if s.err == nil {
+		slog.Warn("artifact service unavailable, reconnecting in the background", "addr", s.addr, "err", err)
+		s.backoff = serviceRetryMin
+	} else {
+		s.backoff = min(2*s.backoff, serviceRetryMax)
+	}
+	s.err = err
+	s.retryAt = time.Now().Add(s.backoff)
+	if s.http != nil {
+		// The next attempt dials a new connection.
+		s.http.CloseIdleConnections()
+	}
+	if !s.probing {
+		s.probing = true
+		go s.probe()
+	}
+}
+
+// probe pings the service on the backoff schedule until it is reachable
+// again or the store is closed.
+func (s *ServiceArtifactStore) probe() {
+	for {
+		s.mu.Lock()
+		if s.err == nil || s.closed {
+			s.probing = false
+			s.mu.Unlock()
+			return
+		}
+		wait := time.Until(s.retryAt)
+		s.mu.Unlock()
+
+		select {
+		case <-s.stop:
+			return
+		case <-time.After(wait):
+		}
+		_ = s.Ping(context.Background())
+	}
+}
+
+func (s *ServiceArtifactStore) Write(ctx context.Context, req ArtifactWrite) (*ArtifactInfo, error) {
+	opts := []mlcartifact.WriteOption{}
+	if req.MimeType != "" {
+		opts = append(opts, mlcartifact.WithMimeType(req.MimeType))
+	}
+	if req.Description != "" {
+		opts = append(opts, mlcartifact.WithDescription(req.Description))
+	}
+	if req.Source != "" {
+		opts = append(opts, mlcartifact.WithSource(req.Source))
+	}
+	if req.UserID != "" {
+		opts = append(opts, mlcartifact.WithUserID(req.UserID))
+	}
+	if req.ExpiresHours > 0 {
+		opts = append(opts, mlcartifact.WithExpiresHours(int32(req.ExpiresHours)))
+	}
+	if len(req.Metadata) > 0 {
+		opts = append(opts, mlcartifact.WithMetadata(req.Metadata))
+	}
+
+	var resp *pb.WriteResponse
+	err := s.call(func() (err error) {
+		resp, err = s.cli.Write(ctx, req.Filename, req.Content, opts...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ArtifactInfo{
+		ID:          resp.Id,
+		Filename:    resp.Filename,
+		URI:         resp.Uri,
+		MimeType:    req.MimeType,
+		Source:      req.Source,
+		ExpiresAt:   resp.ExpiresAt,
+		SizeBytes:   int64(len(req.Content)),
+		UserID:      req.UserID,
+		Description: req.Description,
+		Metadata:    req.Metadata,
+	}, nil
+}
+
+func (s *ServiceArtifactStore) Read(ctx context.Context, idOrFilename, userID string) (*Artifact, error) {
+	opts := []mlcartifact.ReadOption{}
+	if userID != "" {
+		opts = append(opts, mlcartifact.WithReadUserID(userID))
+	}
+	var resp *pb.ReadResponse
+	err := s.call(func() (err error) {
+		resp, err = s.cli.Read(ctx, idOrFilename, opts...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Artifact{
+		// The service does not return the ID, which is unknown if the
+		// artifact was read by filename.
+		ArtifactInfo: ArtifactInfo{
+			Filename:  resp.Filename,
+			MimeType:  resp.MimeType,
+			SizeBytes: int64(len(resp.Content)),
+			UserID:    userID,
+		},
+		Content: resp.Content,
+	}, nil
+}
+
+func (s *ServiceArtifactStore) List(ctx context.Context, userID string) ([]*ArtifactInfo, error) {
+	var resp *pb.ListResponse
+	err := s.call(func() (err error) {
+		resp, err = s.cli.List(ctx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*ArtifactInfo, 0, len(resp.Items))
+	for _, it := range resp.Items {
+		items = append(items, &ArtifactInfo{
+			ID:          it.Id,
+			Filename:    it.Filename,
+			MimeType:    it.MimeType,
+			Source:      it.Source,
+			CreatedAt:   it.CreatedAt,
+			ExpiresAt:   it.ExpiresAt,
+			SizeBytes:   it.SizeBytes,
+			UserID:      it.UserId,
+			Description: it.Description,
+		})
+	}
+	return items, nil
+}
+
+func (s *ServiceArtifactStore) Delete(ctx context.Context, idOrFilename, userID string) (bool, error) {
+	opts := []mlcartifact.DeleteOption{}
+	if userID != "" {
+		opts = append(opts, mlcartifact.WithDeleteUserID(userID))
+	}
+	var resp *pb.DeleteResponse
+	err := s.call(func() (err error) {
+		resp, err = s.cli.Delete(ctx, idOrFilename, opts...)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return resp.Deleted, nil
+}
+
+// Close stops reconnecting and closes the connection to the service.
+func (s *ServiceArtifactStore) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.stop)
+	s.mu.Unlock()
+
+	if s.http != nil {
+		s.http.CloseIdleConnections()
+	}
+	return s.cli.Close()
+}
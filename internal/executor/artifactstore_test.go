@@ -0,0 +1,237 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/hmsoft0815/mlcartifact/proto/protoconnect"
+)
+
+func TestArtifactStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) ArtifactStore{
+		"memory": func(t *testing.T) ArtifactStore { return NewMemoryArtifactStore() },
+		"dir": func(t *testing.T) ArtifactStore {
+			d, err := NewDirArtifactStore(t.TempDir(), "")
+			if err != nil {
+				t.Fatalf("NewDirArtifactStore failed: %v", err)
+			}
+			return d
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newStore(t)
+			defer store.Close()
+
+			info, err := store.Write(ctx, ArtifactWrite{Filename: "../report.csv", Content: []byte("a,b"), MimeType: "text/csv"})
+			if err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			if info.ID == "" || info.Filename != "report.csv" || info.URI == "" || info.SizeBytes != 3 {
+				t.Errorf("unexpected write result: %+v", info)
+			}
+			if _, err := store.Write(ctx, ArtifactWrite{Filename: "private.txt", Content: []byte("x"), UserID: "alice"}); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+
+			for _, key := range []string{info.ID, "report.csv"} {
+				a, err := store.Read(ctx, key, "")
+				if err != nil {
+					t.Fatalf("Read(%q) failed: %v", key, err)
+				}
+				if string(a.Content) != "a,b" || a.MimeType != "text/csv" {
+					t.Errorf("Read(%q) = %+v %q", key, a.ArtifactInfo, a.Content)
+				}
+			}
+
+			// Artifacts of a user are invisible to everyone else.
+			if _, err := store.Read(ctx, "private.txt", ""); !isNotFound(err) {
+				t.Errorf("expected not_found for another user's artifact, got %v", err)
+			}
+			if items, _ := store.List(ctx, "alice"); len(items) != 1 || items[0].Filename != "private.txt" {
+				t.Errorf("unexpected list for alice: %+v", items)
+			}
+
+			if deleted, err := store.Delete(ctx, info.ID, ""); err != nil || !deleted {
+				t.Errorf("Delete = %v, %v", deleted, err)
+			}
+			if deleted, _ := store.Delete(ctx, info.ID, ""); deleted {
+				t.Error("second Delete reported success")
+			}
+			if items, _ := store.List(ctx, ""); len(items) != 0 {
+				t.Errorf("expected no shared artifacts, got %+v", items)
+			}
+		})
+	}
+}
+
+func TestDirArtifactStore_URIs(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	d, err := NewDirArtifactStore(dir, "")
+	if err != nil {
+		t.Fatalf("NewDirArtifactStore failed: %v", err)
+	}
+	info, _ := d.Write(ctx, ArtifactWrite{Filename: "chart.svg", Content: []byte("<svg/>")})
+	if !strings.HasPrefix(info.URI, "file://") || !strings.HasSuffix(info.URI, "/"+info.ID+"/chart.svg") {
+		t.Errorf("unexpected file URI: %s", info.URI)
+	}
+
+	served, err := NewDirArtifactStore(dir, "http://localhost:8080/artifacts/")
+	if err != nil {
+		t.Fatalf("NewDirArtifactStore failed: %v", err)
+	}
+	info, _ = served.Write(ctx, ArtifactWrite{Filename: "my chart.svg", Content: []byte("<svg/>"), MimeType: "image/svg+xml"})
+	if want := "http://localhost:8080/artifacts/" + info.ID + "/my%20chart.svg"; info.URI != want {
+		t.Errorf("URI = %s, want %s", info.URI, want)
+	}
+
+	// SVG and HTML can run scripts, so they are only served as downloads.
+	rec := httptest.NewRecorder()
+	served.ServeHTTP(rec, httptest.NewRequest("GET", "/"+info.ID+"/my%20chart.svg", nil))
+	if rec.Code != 200 || rec.Body.String() != "<svg/>" || rec.Header().Get("Content-Type") != "image/svg+xml" ||
+		rec.Header().Get("X-Content-Type-Options") != "nosniff" || rec.Header().Get("Content-Disposition") != `attachment; filename="my chart.svg"` {
+		t.Errorf("unexpected response: %d %v %q", rec.Code, rec.Header(), rec.Body.String())
+	}
+	html, _ := served.Write(ctx, ArtifactWrite{Filename: "index.html", Content: []byte("<script>x</script>"), MimeType: "text/html"})
+	rec = httptest.NewRecorder()
+	served.ServeHTTP(rec, httptest.NewRequest("GET", "/"+html.ID+"/index.html", nil))
+	if rec.Code != 200 || rec.Body.String() != "<script>x</script>" || !strings.HasPrefix(rec.Header().Get("Content-Disposition"), "attachment") {
+		t.Errorf("unexpected response for index.html: %d %v %q", rec.Code, rec.Header(), rec.Body.String())
+	}
+	csv, _ := served.Write(ctx, ArtifactWrite{Filename: "data.csv", Content: []byte("a,b"), MimeType: "text/csv; charset=utf-8"})
+	rec = httptest.NewRecorder()
+	served.ServeHTTP(rec, httptest.NewRequest("GET", "/"+csv.ID+"/data.csv", nil))
+	if rec.Code != 200 || rec.Header().Get("Content-Disposition") != "" || rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("unexpected response for an inert type: %d %v", rec.Code, rec.Header())
+	}
+	for _, path := range []string{"/", "/" + info.ID + "/", "/" + info.ID + "/other.svg", "/../" + info.ID + ".json"} {
+		rec := httptest.NewRecorder()
+		served.ServeHTTP(rec, httptest.NewRequest("GET", "http://localhost"+path, nil))
+		if rec.Code != 404 {
+			t.Errorf("GET %s: status %d, want 404", path, rec.Code)
+		}
+	}
+}
+
+func TestExecute_MemoryArtifactStore(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store := NewMemoryArtifactStore()
+	code := `(async () => {
+		const { id } = await artifact.write("notes.txt", "hello");
+		const { content } = await artifact.read(id);
+		const fh = wollmilchsau.openArtifact("copy.txt", "text/plain");
+		fh.write(content);
+		await fh.close();
+		console.log((await artifact.list()).map((a) => a.filename).join(","));
+	})()`
+	res := Execute(ctx, code, "test.js", nil, Options{Artifacts: store})
+	if !res.Success {
+		t.Fatalf("Execution failed: %s", res.Summary)
+	}
+	if res.Stdout != "notes.txt,copy.txt\n" {
+		t.Errorf("stdout = %q", res.Stdout)
+	}
+	if len(res.CreatedArtifacts) != 1 || !strings.HasPrefix(res.CreatedArtifacts[0].URI, "memory://artifacts/") {
+		t.Errorf("unexpected created artifacts: %+v", res.CreatedArtifacts)
+	}
+	if a, err := store.Read(ctx, "copy.txt", ""); err != nil || string(a.Content) != "hello" {
+		t.Errorf("copy.txt = %v, %v", a, err)
+	}
+}
+
+// hangingStore is a store whose writes only end when their context is done.
+type hangingStore struct{ ArtifactStore }
+
+func (h hangingStore) Write(ctx context.Context, req ArtifactWrite) (*ArtifactInfo, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestExecute_ArtifactCallsEndWithExecution(t *testing.T) {
+	code := `(async () => {
+		const fh = wollmilchsau.openArtifact("big.txt", "text/plain", { stream: true, chunkBytes: 4 });
+		fh.write("abcdefgh");
+		await artifact.write("other.txt", "x");
+	})()`
+	start := time.Now()
+	res := Execute(context.Background(), code, "test.js", nil, Options{
+		Artifacts: hangingStore{NewMemoryArtifactStore()},
+		Limits:    Limits{MaxWallTimeMs: 200},
+	})
+	if res.ExitCode != ExitCodeTimeout {
+		t.Errorf("expected a timeout, got %d (%s)", res.ExitCode, res.Summary)
+	}
+	// The uploads are cancelled with the execution instead of running into
+	// artifactUploadTimeout.
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("execution took %s", elapsed)
+	}
+	if len(res.CreatedArtifacts) != 1 || res.CreatedArtifacts[0].Status != ArtifactAborted {
+		t.Errorf("unexpected created artifacts: %+v", res.CreatedArtifacts)
+	}
+}
+
+func TestServiceArtifactStore_Reconnect(t *testing.T) {
+	ctx := context.Background()
+
+	// Reserve a free port; the service starts listening on it later.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	store, err := NewServiceArtifactStore(addr)
+	if err != nil {
+		t.Fatalf("NewServiceArtifactStore failed: %v", err)
+	}
+	if _, err := store.List(ctx, ""); connect.CodeOf(err) != connect.CodeUnavailable {
+		t.Fatalf("expected unavailable, got %v", err)
+	}
+	// Until the next reconnect attempt, calls fail without dialing.
+	if _, err := store.List(ctx, ""); connect.CodeOf(err) != connect.CodeUnavailable || !strings.Contains(err.Error(), "next retry") {
+		t.Errorf("expected a fast failure, got %v", err)
+	}
+
+	l, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("port %s was taken in the meantime: %v", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(protoconnect.NewArtifactServiceHandler(&mockArtifactService{}))
+	srv := &http.Server{Handler: mux, Protocols: new(http.Protocols)}
+	srv.Protocols.SetUnencryptedHTTP2(true)
+	go srv.Serve(l)
+	defer srv.Close()
+
+	// The background probe notices that the service is back.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err = store.List(ctx, ""); err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("store did not reconnect: %v", err)
+	}
+
+	store.Close()
+	if _, err := store.List(ctx, ""); connect.CodeOf(err) != connect.CodeUnavailable || !strings.Contains(err.Error(), "closed") {
+		t.Errorf("expected an error after Close, got %v", err)
+	}
+}
@@ -0,0 +1,393 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"connectrpc.com/connect"
+	v8 "rogchap.com/v8go"
+)
+
+// defaultChunkBytes is the part size of streamed handles.
+const defaultChunkBytes = 1024 * 1024
+
+// artifactHandle is the state of one wollmilchsau.openArtifact handle.
+//
+// A buffered handle keeps its content until close(). A streamed handle
+// uploads every chunkBytes as a separate part artifact, so only the current
+// chunk is held in memory.
+type artifactHandle struct {
+	name       string
+	mimeType   string
+	chunkBytes int // part size; 0 buffers everything until close
+
+	// Only used on the JS thread.
+	buf        bytes.Buffer // content not handed to an upload yet
+	size       int          // total bytes written
+	nextPart   int          // last part number handed to an upload
+	closing    bool         // close() was called
+	registered bool         // reported in Result.CreatedArtifacts
+
+	uploads sync.WaitGroup // part uploads started by write()
+	final   sync.WaitGroup // upload started by close()
+
+	// Shared with the upload goroutines.
+	mu     sync.Mutex
+	parts  []ArtifactRef // uploaded artifacts
+	closed bool          // close() uploaded the last part
+	err    error         // first failed upload
+}
+
+// failed returns the error of the first failed upload.
+func (h *artifactHandle) failed() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.err
+}
+
+// takeRest returns the content not uploaded yet and its part number.
+// A handle that never uploaded a part is stored as a single artifact under
+// its own name (part 0); a streamed handle with nothing left returns nil.
+func (h *artifactHandle) takeRest() ([]byte, int) {
+	rest := bytes.Clone(h.buf.Bytes())
+	h.buf.Reset()
+	if h.nextPart == 0 {
+		if rest == nil {
+			rest = []byte{}
+		}
+		return rest, 0
+	}
+	if len(rest) == 0 {
+		return nil, 0
+	}
+	h.nextPart++
+	return rest, h.nextPart
+}
+
+// partName inserts the part number before the extension:
+// report.csv → report.part-001.csv.
+func partName(name string, part int) string {
+	ext := path.Ext(name)
+	return fmt.Sprintf("%s.part-%03d%s", strings.TrimSuffix(name, ext), part, ext)
+}
+
+// injectOpenArtifact adds wollmilchsau.openArtifact, see InjectOpenArtifact.
+// Every handle may receive at most maxBytes.
+func (a *artifactBridge) injectOpenArtifact(res *Result, maxBytes int) error {
+	a.res = res
+	a.maxBytes = maxBytes
+
+	// Retrieve or create the `wollmilchsau` namespace object.
+	wmInst, err := namespaceObject(a.iso, a.ctx)
+	if err != nil {
+		return err
+	}
+	openFn, err := a.function(a.open)
+	if err != nil {
+		return err
+	}
+	return wmInst.Set("openArtifact", openFn)
+}
+
+// open implements openArtifact(name, mimeType?, {stream?, chunkBytes?}).
+func (a *artifactBridge) open(info *v8.FunctionCallbackInfo) *v8.Value {
+	const usage = "wollmilchsau.openArtifact requires (name, optional mimeType, optional {stream, chunkBytes})"
+	args := info.Args()
+	if len(args) < 1 {
+		return throwTypeError(a.iso, a.ctx, usage)
+	}
+	h := &artifactHandle{name: args[0].String(), mimeType: "application/octet-stream"}
+	if len(args) >= 2 && !args[1].IsUndefined() {
+		h.mimeType = args[1].String()
+	}
+	if len(args) >= 3 && args[2].IsObject() {
+		opts := args[2].Object()
+		if stream, err := opts.Get("stream"); err == nil && stream.Boolean() {
+			h.chunkBytes = defaultChunkBytes
+			if chunk, err := opts.Get("chunkBytes"); err == nil && !chunk.IsUndefined() {
+				if chunk.Integer() <= 0 {
+					return throwTypeError(a.iso, a.ctx, "wollmilchsau.openArtifact: chunkBytes must be positive")
+				}
+				h.chunkBytes = int(chunk.Integer())
+			}
+		}
+	}
+
+	handle, err := v8.NewObjectTemplate(a.iso).NewInstance(a.ctx)
+	if err != nil {
+		return throwTypeError(a.iso, a.ctx, "wollmilchsau.openArtifact failed: "+err.Error())
+	}
+	for name, cb := range map[string]v8.FunctionCallback{
+		"write": func(info *v8.FunctionCallbackInfo) *v8.Value { return a.writeHandle(h, info) },
+		"close": func(info *v8.FunctionCallbackInfo) *v8.Value { return a.closeHandle(h) },
+	} {
+		fn, err := a.function(cb)
+		if err == nil {
+			err = handle.Set(name, fn)
+		}
+		if err != nil {
+			return throwTypeError(a.iso, a.ctx, "wollmilchsau.openArtifact failed: "+err.Error())
+		}
+	}
+	a.handles = append(a.handles, h)
+	return handle.Value
+}
+
+// writeHandle implements fh.write(data). Buffered handles return undefined;
+// streamed handles return a Promise that resolves once the chunks completed
+// by this write are uploaded.
+func (a *artifactBridge) writeHandle(h *artifactHandle, info *v8.FunctionCallbackInfo) *v8.Value {
+	const op = "wollmilchsau.openArtifact write()"
+	if h.closing {
+		return a.throw(op, connect.NewError(connect.CodeFailedPrecondition, errors.New("handle is closed")))
+	}
+	var data []byte
+	if len(info.Args()) > 0 {
+		var err error
+		if data, err = a.bin.bytes(info.Args()[0]); err != nil {
+			return a.throw(op, invalidArgument(err.Error()))
+		}
+	}
+	if h.size+len(data) > a.maxBytes {
+		return a.throw(op, connect.NewError(connect.CodeResourceExhausted,
+			fmt.Errorf("%s exceeds the limit of %d bytes", h.name, a.maxBytes)))
+	}
+	h.buf.Write(data)
+	h.size += len(data)
+
+	if h.chunkBytes == 0 {
+		return v8.Undefined(a.iso)
+	}
+	if err := h.failed(); err != nil {
+		return a.reject(op, err)
+	}
+	var chunks [][]byte
+	for h.buf.Len() >= h.chunkBytes {
+		chunks = append(chunks, bytes.Clone(h.buf.Next(h.chunkBytes)))
+	}
+	if len(chunks) == 0 {
+		return a.resolved(v8.Undefined(a.iso))
+	}
+
+	first := h.nextPart + 1
+	h.nextPart += len(chunks)
+	h.uploads.Add(1)
+	return a.call(op, artifactUploadTimeout, func(ctx context.Context) (any, error) {
+		defer h.uploads.Done()
+		for i, chunk := range chunks {
+			if err := a.upload(ctx, h, first+i, chunk); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}, func(any) (*v8.Value, error) {
+		return v8.Undefined(a.iso), nil
+	})
+}
+
+// closeHandle implements fh.close(). It uploads the rest of the content,
+// waits for the parts still in flight and registers the handle in
+// Result.CreatedArtifacts.
+func (a *artifactBridge) closeHandle(h *artifactHandle) *v8.Value {
+	const op = "wollmilchsau.openArtifact close()"
+	if h.closing {
+		return a.reject(op, connect.NewError(connect.CodeFailedPrecondition, errors.New("handle is already closed")))
+	}
+	h.closing = true
+	if err := h.failed(); err != nil {
+		return a.reject(op, err)
+	}
+
+	rest, part := h.takeRest()
+	h.final.Add(1)
+	return a.call(op, artifactUploadTimeout, func(ctx context.Context) (any, error) {
+		defer h.final.Done()
+		h.uploads.Wait()
+		if err := h.failed(); err != nil {
+			return nil, err
+		}
+		if rest != nil {
+			if err := a.upload(ctx, h, part, rest); err != nil {
+				return nil, err
+			}
+		}
+		h.mu.Lock()
+		h.closed = true
+		h.mu.Unlock()
+		return nil, nil
+	}, func(any) (*v8.Value, error) {
+		refs := a.register(h, ArtifactClosed)
+		if len(refs) == 1 && refs[0].Part == 0 {
+			return wrapResult(a.iso, a.ctx, refs[0]), nil
+		}
+		return wrapResult(a.iso, a.ctx, map[string]any{
+			"name":     h.name,
+			"mimeType": h.mimeType,
+			"fileSize": h.size,
+			"status":   ArtifactClosed,
+			"parts":    refs,
+		}), nil
+	})
+}
+
+// upload stores one part of h. Part 0 is the whole content under the
+// handle's name. It runs without access to V8.
+func (a *artifactBridge) upload(ctx context.Context, h *artifactHandle, part int, content []byte) error {
+	req := ArtifactWrite{Filename: h.name, Content: content, MimeType: h.mimeType, Source: "wollmilchsau"}
+	if part > 0 {
+		req.Filename = partName(h.name, part)
+		req.Metadata = map[string]string{
+			"stream": h.name,
+			"part":   strconv.Itoa(part),
+		}
+	}
+	info, err := a.store.Write(ctx, req)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err != nil {
+		slog.Error("wollmilchsau.openArtifact upload failed", "error", err, "filename", req.Filename)
+		if h.err == nil {
+			h.err = err
+		}
+		return err
+	}
+	h.parts = append(h.parts, ArtifactRef{
+		ID:       info.ID,
+		URI:      info.URI,
+		Name:     info.Filename,
+		MimeType: h.mimeType,
+		FileSize: int64(len(content)),
+		Part:     part,
+	})
+	return nil
+}
+
+// register appends the uploaded parts of h to Result.CreatedArtifacts so
+// the MCP handler can add resource_link items.
+func (a *artifactBridge) register(h *artifactHandle, status string) []ArtifactRef {
+	h.mu.Lock()
+	refs := append([]ArtifactRef(nil), h.parts...)
+	h.mu.Unlock()
+
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Part < refs[j].Part })
+	for i := range refs {
+		refs[i].Status = status
+	}
+	h.registered = true
+	a.res.CreatedArtifacts = append(a.res.CreatedArtifacts, refs...)
+	return refs
+}
+
+// finish settles the handles the script did not close once the execution
+// has ended. After a successful execution their rest is uploaded, otherwise
+// (or if an upload failed) the parts uploaded so far are deleted. Either way
+// the outcome is reported in Result.CreatedArtifacts.
+func (a *artifactBridge) finish(success bool) {
+	for _, h := range a.handles {
+		h.uploads.Wait()
+		h.final.Wait()
+		if h.registered {
+			continue
+		}
+		h.mu.Lock()
+		closed, err := h.closed, h.err
+		h.mu.Unlock()
+
+		switch {
+		case closed:
+			// close() finished, but the execution ended before its Promise settled.
+			a.register(h, ArtifactClosed)
+		case err == nil && success && a.finalize(h) == nil:
+			a.register(h, ArtifactFinalized)
+		default:
+			a.abort(h)
+		}
+	}
+}
+
+// graceContext returns the context of the work finish does after the
+// execution has ended. It keeps the values of the execution but not its
+// cancellation, which has usually happened by then, and is bounded by
+// artifactGraceTimeout so the sandbox is not held back for long.
+func (a *artifactBridge) graceContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(a.runCtx), artifactGraceTimeout)
+}
+
+// finalize uploads the rest of an unclosed handle.
+func (a *artifactBridge) finalize(h *artifactHandle) error {
+	rest, part := h.takeRest()
+	if rest == nil {
+		return nil
+	}
+	ctx, cancel := a.graceContext()
+	defer cancel()
+	return a.upload(ctx, h, part, rest)
+}
+
+// abort deletes the uploaded parts of h and reports the handle as aborted.
+func (a *artifactBridge) abort(h *artifactHandle) {
+	ctx, cancel := a.graceContext()
+	defer cancel()
+	h.mu.Lock()
+	parts := h.parts
+	h.mu.Unlock()
+	for _, p := range parts {
+		if _, err := a.store.Delete(ctx, p.ID, ""); err != nil {
+			slog.Warn("failed to delete part of aborted artifact", "error", err, "filename", p.Name)
+		}
+	}
+	slog.Info("aborted unfinished artifact", "filename", h.name, "parts", len(parts))
+
+	h.registered = true
+	a.res.CreatedArtifacts = append(a.res.CreatedArtifacts, ArtifactRef{
+		Name:     h.name,
+		MimeType: h.mimeType,
+		FileSize: int64(h.size),
+		Status:   ArtifactAborted,
+	})
+}
+
+// openArtifactDeclarations declares the member of InjectOpenArtifact and the
+// fields of ArtifactRef.
+const openArtifactDeclarations = `/** An artifact written via wollmilchsau.openArtifact(). */
+interface ArtifactRef {
+  id: string;
+  uri: string;
+  name: string;
+  mimeType: string;
+  fileSize: number;
+  /** 1-based part number of a streamed handle */
+  part?: number;
+  status?: "closed" | "finalized" | "aborted";
+}
+interface ArtifactHandle {
+  write(data: string | Uint8Array | ArrayBuffer | ArrayBufferView): void;
+  close(): Promise<ArtifactRef>;
+}
+/** A streamed handle uploads every chunkBytes as a part (name.part-001.ext, ...). */
+interface StreamedArtifactHandle {
+  /** Resolves when the parts completed by this write are uploaded. */
+  write(data: string | Uint8Array | ArrayBuffer | ArrayBufferView): Promise<void>;
+  close(): Promise<ArtifactRef | {
+    name: string;
+    mimeType: string;
+    fileSize: number;
+    status: "closed";
+    parts: ArtifactRef[];
+  }>;
+}
+interface Wollmilchsau {
+  openArtifact(name: string, mimeType?: string, options?: { stream?: false }): ArtifactHandle;
+  openArtifact(name: string, mimeType: string | undefined, options: { stream: true; chunkBytes?: number }): StreamedArtifactHandle;
+}
+`
@@ -0,0 +1,191 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	mlcartifact "github.com/hmsoft0815/mlcartifact/client"
+	v8 "rogchap.com/v8go"
+)
+
+// newTestHandles returns a context with wollmilchsau.openArtifact backed by
+// the mock service. Every handle may receive at most maxBytes.
+func newTestHandles(t *testing.T, mockSvc *mockArtifactService, maxBytes int) (*v8.Context, *artifactBridge, *Result) {
+	t.Helper()
+	iso := v8.NewIsolate()
+	t.Cleanup(iso.Dispose)
+	v8ctx := v8.NewContext(iso)
+	t.Cleanup(v8ctx.Close)
+
+	res := &Result{}
+	a, err := newArtifactBridge(context.Background(), iso, v8ctx, NewArtifactStoreWithClient(mlcartifact.NewClientWithService(mockSvc)), nil)
+	if err != nil {
+		t.Fatalf("newArtifactBridge failed: %v", err)
+	}
+	if err := a.injectOpenArtifact(res, maxBytes); err != nil {
+		t.Fatalf("Failed to inject openArtifact: %v", err)
+	}
+	return v8ctx, a, res
+}
+
+func TestOpenArtifact_Stream(t *testing.T) {
+	mockSvc := &mockArtifactService{}
+	v8ctx, _, res := newTestHandles(t, mockSvc, 1024)
+
+	js := `
+		const fh = wollmilchsau.openArtifact("report.csv", "text/csv", { stream: true, chunkBytes: 4 });
+		fh.write("abc");
+		fh.write("defghij").then(() => fh.close()).then(JSON.stringify);
+	`
+	val, err := v8ctx.RunScript(js, "test_stream.js")
+	if err != nil {
+		t.Fatalf("Script failed: %v", err)
+	}
+	var meta struct {
+		FileSize int           `json:"fileSize"`
+		Status   string        `json:"status"`
+		Parts    []ArtifactRef `json:"parts"`
+	}
+	if err := json.Unmarshal([]byte(settled(t, v8ctx, val).String()), &meta); err != nil {
+		t.Fatalf("invalid close() result: %v", err)
+	}
+	if meta.FileSize != 10 || meta.Status != ArtifactClosed || len(meta.Parts) != 3 {
+		t.Errorf("unexpected close() result: %+v", meta)
+	}
+
+	wantNames := []string{"report.part-001.csv", "report.part-002.csv", "report.part-003.csv"}
+	wantContent := []string{"abcd", "efgh", "ij"}
+	if len(mockSvc.writes) != len(wantNames) {
+		t.Fatalf("expected %d uploads, got %d", len(wantNames), len(mockSvc.writes))
+	}
+	for i, w := range mockSvc.writes {
+		if w.Filename != wantNames[i] || string(w.Content) != wantContent[i] || w.Metadata["stream"] != "report.csv" {
+			t.Errorf("upload %d: got %s %q %v", i, w.Filename, w.Content, w.Metadata)
+		}
+	}
+	if len(res.CreatedArtifacts) != 3 || res.CreatedArtifacts[2].Part != 3 || res.CreatedArtifacts[2].Status != ArtifactClosed {
+		t.Errorf("unexpected created artifacts: %+v", res.CreatedArtifacts)
+	}
+}
+
+func TestOpenArtifact_SizeCap(t *testing.T) {
+	mockSvc := &mockArtifactService{}
+	v8ctx, _, _ := newTestHandles(t, mockSvc, 8)
+
+	js := "const fh = wollmilchsau.openArtifact('big.bin');\nfh.write('12345');\n" +
+		"try {\n  fh.write('6789');\n} catch (e) {\n  [e.name, e.code, e.message, e.stack.split('\\n')[1].trim()].join('|');\n}"
+	val, err := v8ctx.RunScript(js, "test_cap.js")
+	if err != nil {
+		t.Fatalf("Script failed: %v", err)
+	}
+	want := "ArtifactError|resource_exhausted|wollmilchsau.openArtifact write() failed: big.bin exceeds the limit of 8 bytes|at test_cap.js:4:6"
+	if got := val.String(); got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
+
+func TestOpenArtifact_Finish(t *testing.T) {
+	t.Run("finalize after success", func(t *testing.T) {
+		mockSvc := &mockArtifactService{}
+		v8ctx, a, res := newTestHandles(t, mockSvc, 1024)
+
+		js := `
+			wollmilchsau.openArtifact("small.txt").write("left open");
+			const fh = wollmilchsau.openArtifact("log.txt", "text/plain", { stream: true, chunkBytes: 4 });
+			fh.write("0123456");
+		`
+		if _, err := v8ctx.RunScript(js, "test_finish.js"); err != nil {
+			t.Fatalf("Script failed: %v", err)
+		}
+		a.finish(true)
+
+		if len(res.CreatedArtifacts) != 3 {
+			t.Fatalf("unexpected created artifacts: %+v", res.CreatedArtifacts)
+		}
+		for _, ref := range res.CreatedArtifacts {
+			if ref.Status != ArtifactFinalized {
+				t.Errorf("%s: status %q, want %q", ref.Name, ref.Status, ArtifactFinalized)
+			}
+		}
+		if small := res.CreatedArtifacts[0]; small.Name != "small.txt" || small.FileSize != 9 {
+			t.Errorf("unexpected buffered artifact: %+v", small)
+		}
+		if last := mockSvc.lastWrite; last.Filename != "log.part-002.txt" || string(last.Content) != "456" {
+			t.Errorf("unexpected last upload: %s %q", last.Filename, last.Content)
+		}
+	})
+
+	t.Run("finalize after the execution context ended", func(t *testing.T) {
+		mockSvc := &mockArtifactService{}
+		v8ctx, a, res := newTestHandles(t, mockSvc, 1024)
+		runCtx, cancel := context.WithCancel(context.Background())
+		a.runCtx = runCtx
+
+		if _, err := v8ctx.RunScript(`wollmilchsau.openArtifact("late.txt").write("done");`, "test_late.js"); err != nil {
+			t.Fatalf("Script failed: %v", err)
+		}
+		cancel()
+
+		// The final flush gets its own short deadline instead of the cancelled one.
+		ctx, cancelGrace := a.graceContext()
+		defer cancelGrace()
+		if deadline, ok := ctx.Deadline(); ctx.Err() != nil || !ok || time.Until(deadline) > artifactGraceTimeout {
+			t.Errorf("unexpected grace context: err %v, deadline %v", ctx.Err(), deadline)
+		}
+		a.finish(true)
+		if len(res.CreatedArtifacts) != 1 || res.CreatedArtifacts[0].Status != ArtifactFinalized {
+			t.Errorf("unexpected created artifacts: %+v", res.CreatedArtifacts)
+		}
+	})
+
+	t.Run("abort after failure", func(t *testing.T) {
+		mockSvc := &mockArtifactService{}
+		v8ctx, a, res := newTestHandles(t, mockSvc, 1024)
+
+		js := `
+			const fh = wollmilchsau.openArtifact("data.csv", "text/csv", { stream: true, chunkBytes: 2 });
+			fh.write("abcde");
+		`
+		if _, err := v8ctx.RunScript(js, "test_abort.js"); err != nil {
+			t.Fatalf("Script failed: %v", err)
+		}
+		a.finish(false)
+
+		if len(mockSvc.deleted) != 2 {
+			t.Errorf("expected the 2 uploaded parts to be deleted, got %v", mockSvc.deleted)
+		}
+		want := ArtifactRef{Name: "data.csv", MimeType: "text/csv", FileSize: 5, Status: ArtifactAborted}
+		if len(res.CreatedArtifacts) != 1 || res.CreatedArtifacts[0] != want {
+			t.Errorf("unexpected created artifacts: %+v", res.CreatedArtifacts)
+		}
+	})
+
+	t.Run("abort after failed upload", func(t *testing.T) {
+		mockSvc := &mockArtifactService{writeErr: connect.NewError(connect.CodeUnavailable, errors.New("service down"))}
+		v8ctx, a, res := newTestHandles(t, mockSvc, 1024)
+
+		js := `
+			const fh = wollmilchsau.openArtifact("data.csv", "text/csv", { stream: true, chunkBytes: 2 });
+			fh.write("abc").catch((e) => e.name + "|" + e.code);
+		`
+		val, err := v8ctx.RunScript(js, "test_failed.js")
+		if err != nil {
+			t.Fatalf("Script failed: %v", err)
+		}
+		if got, want := settled(t, v8ctx, val).String(), "ArtifactUnavailableError|unavailable"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+
+		// The run succeeded, but the handle is incomplete and must not be finalized.
+		mockSvc.writeErr = nil
+		a.finish(true)
+		if len(res.CreatedArtifacts) != 1 || res.CreatedArtifacts[0].Status != ArtifactAborted || len(mockSvc.writes) != 0 {
+			t.Errorf("unexpected created artifacts: %+v", res.CreatedArtifacts)
+		}
+	})
+}
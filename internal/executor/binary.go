@@ -0,0 +1,129 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	v8 "rogchap.com/v8go"
+)
+
+// v8go cannot access the memory of an ArrayBuffer, so binary data crosses the
+// Go/JS boundary as a binary string: one char with the code 0-255 per byte.
+// The conversion between binary strings and typed arrays happens in JS.
+
+// binaryBridge converts between JS binary data and Go byte slices.
+type binaryBridge struct {
+	iso        *v8.Isolate
+	ctx        *v8.Context
+	toBinary   *v8.Function // ArrayBuffer or view → binary string, anything else → undefined
+	fromBinary *v8.Function // binary string → Uint8Array
+}
+
+// newBinaryBridge returns the binary helpers of ctx, loading the binary
+// prelude if it has not been run in this context yet. The helpers are
+// captured, so scripts replacing the globals do not affect the bridge.
+func newBinaryBridge(iso *v8.Isolate, ctx *v8.Context) (*binaryBridge, error) {
+	global := ctx.Global()
+	if !global.Has("__binary") {
+		if err := runPrelude(ctx, "binary.js", binaryJS); err != nil {
+			return nil, err
+		}
+	}
+	helpers, err := global.Get("__binary")
+	if err != nil {
+		return nil, err
+	}
+	if !helpers.IsObject() {
+		return nil, fmt.Errorf("binary prelude not loaded")
+	}
+	b := &binaryBridge{iso: iso, ctx: ctx}
+	for name, target := range map[string]**v8.Function{"toBinary": &b.toBinary, "fromBinary": &b.fromBinary} {
+		fn, err := helpers.Object().Get(name)
+		if err != nil {
+			return nil, err
+		}
+		if *target, err = fn.AsFunction(); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// bytes returns the content of a JS value: the raw bytes of an ArrayBuffer or
+// typed array, and the UTF-8 encoding of its string form for anything else.
+func (b *binaryBridge) bytes(v *v8.Value) ([]byte, error) {
+	if v.IsArrayBuffer() || v.IsArrayBufferView() {
+		s, err := b.toBinary.Call(v8.Undefined(b.iso), v)
+		if err != nil {
+			return nil, err
+		}
+		return binaryStringBytes(s.String())
+	}
+	return []byte(v.String()), nil
+}
+
+// uint8Array creates a Uint8Array holding a copy of data.
+func (b *binaryBridge) uint8Array(data []byte) (*v8.Value, error) {
+	s, err := v8.NewValue(b.iso, binaryString(data))
+	if err != nil {
+		return nil, err
+	}
+	return b.fromBinary.Call(v8.Undefined(b.iso), s)
+}
+
+// binaryString maps every byte to the char with the same code, so binary
+// data survives the conversion to a JS string.
+func binaryString(b []byte) string {
+	var sb strings.Builder
+	sb.Grow(len(b))
+	for _, c := range b {
+		sb.WriteRune(rune(c))
+	}
+	return sb.String()
+}
+
+// binaryStringBytes is the inverse of binaryString.
+func binaryStringBytes(s string) ([]byte, error) {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xff {
+			return nil, fmt.Errorf("invalid binary string: char %U", r)
+		}
+		out = append(out, byte(r))
+	}
+	return out, nil
+}
+
+// binaryJS defines the non-enumerable global __binary used by binaryBridge.
+const binaryJS = `(function() {
+	// Large arrays are converted in chunks to stay below the argument limit.
+	const CHUNK = 0x8000;
+
+	function toBinary(value) {
+		let bytes;
+		if (value instanceof ArrayBuffer) {
+			bytes = new Uint8Array(value);
+		} else if (ArrayBuffer.isView(value)) {
+			bytes = new Uint8Array(value.buffer, value.byteOffset, value.byteLength);
+		} else {
+			return undefined;
+		}
+		let s = '';
+		for (let i = 0; i < bytes.length; i += CHUNK) {
+			s += String.fromCharCode.apply(null, bytes.subarray(i, i + CHUNK));
+		}
+		return s;
+	}
+
+	function fromBinary(s) {
+		const bytes = new Uint8Array(s.length);
+		for (let i = 0; i < s.length; i++) bytes[i] = s.charCodeAt(i);
+		return bytes;
+	}
+
+	Object.defineProperty(globalThis, '__binary', {
+		value: Object.freeze({ toBinary, fromBinary }),
+	});
+})();
+`
@@ -0,0 +1,810 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	v8 "rogchap.com/v8go"
+)
+
+// InjectConsole adds the global console object to the V8 context.
+// Formatting (util.inspect-style object inspection, printf-style substitution,
+// console.table, groups, ...) is done in JavaScript, see consoleJS. Each
+// finished line is handed to stdout or stderr.
+func InjectConsole(iso *v8.Isolate, ctx *v8.Context, stdout, stderr func(line string)) error {
+	writeFn := v8.NewFunctionTemplate(iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
+		args := info.Args()
+		if len(args) < 2 {
+			return v8.Undefined(iso)
+		}
+		if args[0].Int32() == 2 {
+			stderr(args[1].String())
+		} else {
+			stdout(args[1].String())
+		}
+		return v8.Undefined(iso)
+	})
+	if err := ctx.Global().Set("__console_write", writeFn.GetFunction(ctx)); err != nil {
+		return err
+	}
+	return runPrelude(ctx, "console.js", consoleJS)
+}
+
+// consoleJS implements the console object on top of __console_write(fd, line),
+// which it removes from the global object so scripts cannot bypass console.
+// The output format follows Node's util.inspect and console as closely as
+// practical, so that scripts print what their authors expect.
+const consoleJS = `(function() {
+	const write = __console_write;
+	delete globalThis.__console_write;
+	const hasOwn = Object.prototype.hasOwnProperty;
+	const isEnumerable = Object.prototype.propertyIsEnumerable;
+	const fnToString = Function.prototype.toString;
+	const keyStrRegExp = /^[a-zA-Z_][a-zA-Z_0-9]*$/;
+	const customInspect = Symbol.for('nodejs.util.inspect.custom');
+
+	const defaultOptions = {
+		depth: 2,
+		breakLength: 80,
+		compact: 3,
+		maxArrayLength: 100,
+		maxStringLength: 10000,
+		customInspect: true,
+	};
+
+	// ----- util.inspect -----
+
+	function inspect(value, opts) {
+		const ctx = Object.assign({}, defaultOptions);
+		if (opts !== null && typeof opts === 'object') {
+			for (const k of Object.keys(defaultOptions)) {
+				if (opts[k] !== undefined) ctx[k] = opts[k];
+			}
+		}
+		if (ctx.depth === null) ctx.depth = Infinity;
+		if (ctx.compact === true) ctx.compact = Infinity;
+		if (ctx.compact === false) ctx.compact = 0;
+		ctx.seen = [];
+		ctx.circular = undefined;
+		ctx.indentationLvl = 0;
+		ctx.currentDepth = 0;
+		return formatValue(ctx, value, 0);
+	}
+
+	function formatNumber(n) {
+		return Object.is(n, -0) ? '-0' : String(n);
+	}
+
+	function strEscape(s) {
+		let q = "'";
+		if (s.indexOf("'") !== -1 && s.indexOf('"') === -1) q = '"';
+		let out = '';
+		for (let i = 0; i < s.length; i++) {
+			const c = s[i];
+			const code = s.charCodeAt(i);
+			if (c === q || c === '\\') out += '\\' + c;
+			else if (c === '\n') out += '\\n';
+			else if (c === '\t') out += '\\t';
+			else if (c === '\r') out += '\\r';
+			else if (code < 0x20 || code === 0x7f) out += '\\x' + code.toString(16).padStart(2, '0');
+			else out += c;
+		}
+		return q + out + q;
+	}
+
+	function formatString(ctx, s) {
+		let trailer = '';
+		if (s.length > ctx.maxStringLength) {
+			const remaining = s.length - ctx.maxStringLength;
+			s = s.slice(0, ctx.maxStringLength);
+			trailer = '... ' + remaining + ' more character' + (remaining > 1 ? 's' : '');
+		}
+		// Long multi-line strings are split at line breaks, like Node does.
+		if (ctx.compact !== Infinity && s.length > 16 && s.length > ctx.breakLength - ctx.indentationLvl - 4) {
+			const lines = s.split(/(?<=\n)/);
+			if (lines.length > 1) {
+				const indent = ' '.repeat(ctx.indentationLvl);
+				return lines.map(strEscape).join(' +\n' + indent + '  ') + trailer;
+			}
+		}
+		return strEscape(s) + trailer;
+	}
+
+	function formatPrimitive(ctx, value) {
+		switch (typeof value) {
+		case 'string': return formatString(ctx, value);
+		case 'number': return formatNumber(value);
+		case 'bigint': return value + 'n';
+		case 'symbol': return value.toString();
+		default: return String(value);
+		}
+	}
+
+	function formatValue(ctx, value, recurseTimes) {
+		if (typeof value !== 'object' && typeof value !== 'function') {
+			return formatPrimitive(ctx, value);
+		}
+		if (value === null) return 'null';
+
+		if (ctx.customInspect) {
+			const fn = value[customInspect];
+			if (typeof fn === 'function' && fn !== inspect) {
+				const depth = ctx.depth === Infinity ? null : ctx.depth - recurseTimes;
+				const ret = fn.call(value, depth, Object.assign({}, ctx), inspect);
+				if (ret !== value) {
+					if (typeof ret !== 'string') return formatValue(ctx, ret, recurseTimes);
+					return ret.split('\n').join('\n' + ' '.repeat(ctx.indentationLvl));
+				}
+			}
+		}
+
+		if (ctx.seen.includes(value)) {
+			if (ctx.circular === undefined) ctx.circular = new Map();
+			let index = ctx.circular.get(value);
+			if (index === undefined) {
+				index = ctx.circular.size + 1;
+				ctx.circular.set(value, index);
+			}
+			return '[Circular *' + index + ']';
+		}
+		return formatRaw(ctx, value, recurseTimes);
+	}
+
+	function getCtorName(obj) {
+		let o = obj;
+		while (o !== null && o !== undefined) {
+			const d = Object.getOwnPropertyDescriptor(o, 'constructor');
+			if (d !== undefined && typeof d.value === 'function' && d.value.name !== '') {
+				try {
+					if (obj instanceof d.value) return d.value.name;
+				} catch (e) {}
+			}
+			o = Object.getPrototypeOf(o);
+		}
+		return null;
+	}
+
+	function getPrefix(constructor, tag, fallback, size) {
+		size = size || '';
+		if (constructor === null) {
+			if (tag !== '' && fallback !== tag) return '[' + fallback + size + ': null prototype] [' + tag + '] ';
+			return '[' + fallback + size + ': null prototype] ';
+		}
+		if (tag !== '' && constructor !== tag) return constructor + size + ' [' + tag + '] ';
+		return constructor + size + ' ';
+	}
+
+	function getKeys(value) {
+		const keys = Object.keys(value);
+		for (const sym of Object.getOwnPropertySymbols(value)) {
+			if (isEnumerable.call(value, sym)) keys.push(sym);
+		}
+		return keys;
+	}
+
+	function isIndex(key) {
+		return typeof key === 'string' && /^(0|[1-9][0-9]*)$/.test(key);
+	}
+
+	function isError(value) {
+		return value instanceof Error || Object.prototype.toString.call(value) === '[object Error]';
+	}
+
+	function getFunctionBase(value, constructor, tag) {
+		const source = fnToString.call(value);
+		if (source.startsWith('class') && source.endsWith('}')) {
+			let base = '[class ' + (value.name || '(anonymous)');
+			if (constructor !== 'Function' && constructor !== null) base += ' [' + constructor + ']';
+			const superClass = Object.getPrototypeOf(value);
+			if (superClass && superClass.name) base += ' extends ' + superClass.name;
+			return base + ']';
+		}
+		let type = 'Function';
+		if (constructor === 'AsyncFunction' || constructor === 'GeneratorFunction' || constructor === 'AsyncGeneratorFunction') {
+			type = constructor;
+		}
+		let base = '[' + type;
+		if (constructor === null) base += ' (null prototype)';
+		base += value.name === '' ? ' (anonymous)' : ': ' + value.name;
+		base += ']';
+		if (constructor !== type && constructor !== 'Function' && constructor !== null) base += ' ' + constructor;
+		if (tag !== '' && constructor !== tag) base += ' [' + tag + ']';
+		return base;
+	}
+
+	function formatError(ctx, err, keys) {
+		let stack = typeof err.stack === 'string' && err.stack !== '' ? err.stack : Error.prototype.toString.call(err);
+		if (stack.indexOf('\n    at ') === -1) stack = '[' + stack + ']';
+		// Show cause and errors even though they are not enumerable.
+		for (const name of ['cause', 'errors']) {
+			if (hasOwn.call(err, name) && !keys.includes(name)) keys.push(name);
+		}
+		if (ctx.indentationLvl !== 0) {
+			stack = stack.split('\n').join('\n' + ' '.repeat(ctx.indentationLvl));
+		}
+		return stack;
+	}
+
+	function formatRaw(ctx, value, recurseTimes) {
+		let keys = getKeys(value);
+		const constructor = getCtorName(value);
+		let tag = '';
+		try {
+			const t = value[Symbol.toStringTag];
+			if (typeof t === 'string' && t !== '' && !isEnumerable.call(value, Symbol.toStringTag)) tag = t;
+		} catch (e) {}
+
+		let base = '';
+		let braces;
+		let formatter = formatNothing;
+		let isArrayType = false;
+
+		if (Array.isArray(value)) {
+			keys = keys.filter((k) => !isIndex(k));
+			const prefix = constructor !== 'Array' || tag !== '' ? getPrefix(constructor, tag, 'Array', '(' + value.length + ')') : '';
+			braces = [prefix + '[', ']'];
+			if (value.length === 0 && keys.length === 0) return braces[0] + ']';
+			formatter = formatArray;
+			isArrayType = true;
+		} else if (value instanceof Set) {
+			const prefix = getPrefix(constructor, tag, 'Set', '(' + value.size + ')');
+			if (value.size === 0 && keys.length === 0) return prefix + '{}';
+			braces = [prefix + '{', '}'];
+			formatter = formatSet;
+		} else if (value instanceof Map) {
+			const prefix = getPrefix(constructor, tag, 'Map', '(' + value.size + ')');
+			if (value.size === 0 && keys.length === 0) return prefix + '{}';
+			braces = [prefix + '{', '}'];
+			formatter = formatMap;
+		} else if (ArrayBuffer.isView(value) && !(value instanceof DataView)) {
+			keys = keys.filter((k) => !isIndex(k));
+			const fallback = tag || 'TypedArray';
+			braces = [getPrefix(constructor, tag, fallback, '(' + value.length + ')') + '[', ']'];
+			if (value.length === 0 && keys.length === 0) return braces[0] + ']';
+			formatter = formatTypedArray;
+			isArrayType = true;
+		} else if (typeof value === 'function') {
+			base = getFunctionBase(value, constructor, tag);
+			if (keys.length === 0) return base;
+			braces = ['{', '}'];
+		} else if (value instanceof RegExp) {
+			base = RegExp.prototype.toString.call(value);
+			if (keys.length === 0) return base;
+			braces = ['{', '}'];
+		} else if (value instanceof Date) {
+			base = isNaN(value.getTime()) ? 'Invalid Date' : value.toISOString();
+			if (keys.length === 0) return base;
+			braces = ['{', '}'];
+		} else if (isError(value)) {
+			base = formatError(ctx, value, keys);
+			if (keys.length === 0) return base;
+			braces = ['{', '}'];
+		} else if (value instanceof Number || value instanceof String || value instanceof Boolean || value instanceof BigInt) {
+			const type = value instanceof Number ? 'Number' : value instanceof String ? 'String' : value instanceof Boolean ? 'Boolean' : 'BigInt';
+			if (value instanceof String) keys = keys.filter((k) => !isIndex(k));
+			base = '[' + type + ': ' + formatPrimitive(ctx, value.valueOf()) + ']';
+			if (keys.length === 0) return base;
+			braces = ['{', '}'];
+		} else if (value instanceof ArrayBuffer) {
+			braces = [getPrefix(constructor, tag, 'ArrayBuffer') + '{', '}'];
+			formatter = formatArrayBuffer;
+		} else if (value instanceof WeakMap || value instanceof WeakSet) {
+			return getPrefix(constructor, tag, value instanceof WeakMap ? 'WeakMap' : 'WeakSet') + '{ <items unknown> }';
+		} else {
+			if (constructor === 'Object') {
+				braces = [tag !== '' ? getPrefix(constructor, tag, 'Object') + '{' : '{', '}'];
+			} else {
+				braces = [getPrefix(constructor, tag, 'Object') + '{', '}'];
+			}
+			if (keys.length === 0) return braces[0] + '}';
+		}
+
+		if (recurseTimes > ctx.depth) {
+			return '[' + (constructor || tag || 'Object') + ']';
+		}
+
+		recurseTimes += 1;
+		ctx.seen.push(value);
+		ctx.currentDepth = recurseTimes;
+		let output;
+		try {
+			output = formatter(ctx, value, recurseTimes);
+			for (const key of keys) {
+				output.push(formatProperty(ctx, value, recurseTimes, key, false));
+			}
+		} finally {
+			ctx.seen.pop();
+		}
+
+		if (ctx.circular !== undefined) {
+			const index = ctx.circular.get(value);
+			if (index !== undefined) {
+				const reference = '<ref *' + index + '>';
+				base = base === '' ? reference : reference + ' ' + base;
+			}
+		}
+
+		return reduceToSingleString(ctx, output, base, braces, isArrayType, recurseTimes, value);
+	}
+
+	function formatNothing() {
+		return [];
+	}
+
+	function moreItems(n) {
+		return '... ' + n + ' more item' + (n > 1 ? 's' : '');
+	}
+
+	function formatArray(ctx, value, recurseTimes) {
+		const len = value.length;
+		const output = [];
+		let i = 0;
+		for (; i < len && output.length < ctx.maxArrayLength; i++) {
+			if (!hasOwn.call(value, i)) {
+				let j = i;
+				while (j < len && !hasOwn.call(value, j)) j++;
+				const n = j - i;
+				output.push('<' + n + ' empty item' + (n > 1 ? 's' : '') + '>');
+				i = j - 1;
+				continue;
+			}
+			output.push(formatProperty(ctx, value, recurseTimes, i, true));
+		}
+		if (i < len) output.push(moreItems(len - i));
+		return output;
+	}
+
+	function formatTypedArray(ctx, value) {
+		const max = Math.min(ctx.maxArrayLength, value.length);
+		const output = new Array(max);
+		for (let i = 0; i < max; i++) output[i] = formatPrimitive(ctx, value[i]);
+		if (value.length > max) output.push(moreItems(value.length - max));
+		return output;
+	}
+
+	function formatArrayBuffer(ctx, value) {
+		const bytes = new Uint8Array(value);
+		const max = Math.min(50, bytes.length);
+		let hex = '';
+		for (let i = 0; i < max; i++) hex += (i > 0 ? ' ' : '') + bytes[i].toString(16).padStart(2, '0');
+		if (bytes.length > max) hex += ' ... ' + (bytes.length - max) + ' more byte' + (bytes.length - max > 1 ? 's' : '');
+		return ['[Uint8Contents]: <' + hex + '>', 'byteLength: ' + bytes.length];
+	}
+
+	function formatSet(ctx, value, recurseTimes) {
+		const output = [];
+		ctx.indentationLvl += 2;
+		for (const v of value) {
+			if (output.length >= ctx.maxArrayLength) break;
+			output.push(formatValue(ctx, v, recurseTimes));
+		}
+		ctx.indentationLvl -= 2;
+		if (value.size > output.length) output.push(moreItems(value.size - output.length));
+		return output;
+	}
+
+	function formatMap(ctx, value, recurseTimes) {
+		const output = [];
+		ctx.indentationLvl += 2;
+		for (const [k, v] of value) {
+			if (output.length >= ctx.maxArrayLength) break;
+			output.push(formatValue(ctx, k, recurseTimes) + ' => ' + formatValue(ctx, v, recurseTimes));
+		}
+		ctx.indentationLvl -= 2;
+		if (value.size > output.length) output.push(moreItems(value.size - output.length));
+		return output;
+	}
+
+	function formatProperty(ctx, value, recurseTimes, key, arrayEntry) {
+		const desc = Object.getOwnPropertyDescriptor(value, key) || { value: value[key], enumerable: true };
+		let str;
+		if (desc.value !== undefined) {
+			ctx.indentationLvl += 2;
+			str = formatValue(ctx, desc.value, recurseTimes);
+			ctx.indentationLvl -= 2;
+		} else if (desc.get !== undefined) {
+			str = desc.set !== undefined ? '[Getter/Setter]' : '[Getter]';
+		} else if (desc.set !== undefined) {
+			str = '[Setter]';
+		} else {
+			str = 'undefined';
+		}
+		if (arrayEntry) return str;
+
+		let name;
+		if (typeof key === 'symbol') name = '[' + key.toString() + ']';
+		else if (keyStrRegExp.test(key)) name = key;
+		else name = strEscape(key);
+		if (desc.enumerable === false) name = '[' + name + ']';
+		return name + ': ' + str;
+	}
+
+	function isBelowBreakLength(ctx, output, start, base) {
+		let totalLength = output.length + start;
+		if (totalLength + output.length > ctx.breakLength) return false;
+		for (let i = 0; i < output.length; i++) {
+			totalLength += output[i].length;
+			if (totalLength > ctx.breakLength) return false;
+		}
+		return base === '' || base.indexOf('\n') === -1;
+	}
+
+	function reduceToSingleString(ctx, output, base, braces, isArrayType, recurseTimes, value) {
+		const entries = output.length;
+		if (isArrayType && entries > 6) {
+			output = groupArrayElements(ctx, output, value);
+		}
+		// Combine the innermost ctx.compact levels on a single line if they fit.
+		if (ctx.currentDepth - recurseTimes < ctx.compact && entries === output.length) {
+			const start = output.length + ctx.indentationLvl + braces[0].length + base.length + 10;
+			if (isBelowBreakLength(ctx, output, start, base)) {
+				const joined = output.join(', ');
+				if (joined.indexOf('\n') === -1) {
+					return (base ? base + ' ' : '') + braces[0] + ' ' + joined + ' ' + braces[1];
+				}
+			}
+		}
+		const indentation = '\n' + ' '.repeat(ctx.indentationLvl);
+		return (base ? base + ' ' : '') + braces[0] + indentation + '  ' +
+			output.join(',' + indentation + '  ') + indentation + braces[1];
+	}
+
+	// groupArrayElements lays out long arrays of short entries in columns.
+	function groupArrayElements(ctx, output, value) {
+		let totalLength = 0;
+		let maxLength = 0;
+		let outputLength = output.length;
+		if (ctx.maxArrayLength < output.length) {
+			// Keep the "... n more items" entry out of the layout.
+			outputLength--;
+		}
+		const separatorSpace = 2;
+		const dataLen = new Array(outputLength);
+		for (let i = 0; i < outputLength; i++) {
+			const len = output[i].length;
+			dataLen[i] = len;
+			totalLength += len + separatorSpace;
+			if (maxLength < len) maxLength = len;
+		}
+		const actualMax = maxLength + separatorSpace;
+		if (actualMax * 3 + ctx.indentationLvl < ctx.breakLength &&
+			(totalLength / actualMax > 5 || maxLength <= 6)) {
+			const averageBias = Math.sqrt(actualMax - totalLength / output.length);
+			const biasedMax = Math.max(actualMax - 3 - averageBias, 1);
+			const columns = Math.min(
+				Math.round(Math.sqrt(2.5 * biasedMax * outputLength) / biasedMax),
+				Math.floor((ctx.breakLength - ctx.indentationLvl) / actualMax),
+				ctx.compact * 4,
+				15
+			);
+			if (columns <= 1) return output;
+
+			const maxLineLength = [];
+			for (let i = 0; i < columns; i++) {
+				let lineLength = 0;
+				for (let j = i; j < outputLength; j += columns) {
+					if (dataLen[j] > lineLength) lineLength = dataLen[j];
+				}
+				maxLineLength.push(lineLength + separatorSpace);
+			}
+			let padStart = true;
+			for (let i = 0; i < outputLength; i++) {
+				if (typeof value[i] !== 'number' && typeof value[i] !== 'bigint') {
+					padStart = false;
+					break;
+				}
+			}
+			const tmp = [];
+			for (let i = 0; i < outputLength; i += columns) {
+				const max = Math.min(i + columns, outputLength);
+				let str = '';
+				let j = i;
+				for (; j < max - 1; j++) {
+					const cell = output[j] + ', ';
+					str += padStart ? cell.padStart(maxLineLength[j - i]) : cell.padEnd(maxLineLength[j - i]);
+				}
+				str += padStart ? output[j].padStart(maxLineLength[j - i] - separatorSpace) : output[j];
+				tmp.push(str);
+			}
+			if (outputLength < output.length) tmp.push(output[outputLength]);
+			output = tmp;
+		}
+		return output;
+	}
+
+	// ----- printf-style formatting -----
+
+	function hasUserToString(value) {
+		const fn = value.toString;
+		return typeof fn === 'function' && !/\[native code\]\s*\}$/.test(fnToString.call(fn));
+	}
+
+	function tryStringify(value) {
+		try {
+			return String(JSON.stringify(value));
+		} catch (e) {
+			return '[Circular]';
+		}
+	}
+
+	function format(args) {
+		const first = args[0];
+		let a = 0;
+		let str = '';
+		let join = '';
+		if (typeof first === 'string') {
+			if (args.length === 1) return first;
+			let lastPos = 0;
+			for (let i = 0; i < first.length - 1; i++) {
+				if (first.charCodeAt(i) !== 37) continue; // '%'
+				const next = first[++i];
+				if (a + 1 === args.length) {
+					if (next === '%') {
+						str += first.slice(lastPos, i);
+						lastPos = i + 1;
+					}
+					continue;
+				}
+				let tempStr;
+				switch (next) {
+				case 's': {
+					const arg = args[++a];
+					if (typeof arg === 'number') tempStr = formatNumber(arg);
+					else if (typeof arg === 'bigint') tempStr = arg + 'n';
+					else if (typeof arg !== 'object' || arg === null || hasUserToString(arg)) tempStr = String(arg);
+					else tempStr = inspect(arg, { depth: 0 });
+					break;
+				}
+				case 'j':
+					tempStr = tryStringify(args[++a]);
+					break;
+				case 'd': {
+					const arg = args[++a];
+					if (typeof arg === 'bigint') tempStr = arg + 'n';
+					else if (typeof arg === 'symbol' || (typeof arg === 'object' && arg !== null)) tempStr = 'NaN';
+					else tempStr = formatNumber(Number(arg));
+					break;
+				}
+				case 'i': {
+					const arg = args[++a];
+					if (typeof arg === 'bigint') tempStr = arg + 'n';
+					else if (typeof arg === 'symbol') tempStr = 'NaN';
+					else tempStr = formatNumber(parseInt(arg));
+					break;
+				}
+				case 'f': {
+					const arg = args[++a];
+					tempStr = typeof arg === 'symbol' ? 'NaN' : formatNumber(parseFloat(arg));
+					break;
+				}
+				case 'o':
+					tempStr = inspect(args[++a], { depth: 4 });
+					break;
+				case 'O':
+					tempStr = inspect(args[++a]);
+					break;
+				case 'c':
+					a++;
+					tempStr = '';
+					break;
+				case '%':
+					str += first.slice(lastPos, i);
+					lastPos = i + 1;
+					continue;
+				default:
+					continue;
+				}
+				if (lastPos !== i - 1) str += first.slice(lastPos, i - 1);
+				str += tempStr;
+				lastPos = i + 1;
+			}
+			if (lastPos !== 0) {
+				a++;
+				join = ' ';
+				if (lastPos < first.length) str += first.slice(lastPos);
+			}
+		}
+		while (a < args.length) {
+			const value = args[a];
+			str += join;
+			str += typeof value !== 'string' ? inspect(value) : value;
+			join = ' ';
+			a++;
+		}
+		return str;
+	}
+
+	// ----- console.table -----
+
+	function renderTable(head, columns) {
+		const width = (s) => [...s].length;
+		const rows = [];
+		const columnWidths = head.map(width);
+		const longestColumn = Math.max(...columns.map((c) => c.length));
+		for (let i = 0; i < head.length; i++) {
+			const column = columns[i];
+			for (let j = 0; j < longestColumn; j++) {
+				if (rows[j] === undefined) rows[j] = [];
+				const cell = rows[j][i] = hasOwn.call(column, j) ? column[j] : '';
+				columnWidths[i] = Math.max(columnWidths[i], width(cell));
+			}
+		}
+		const renderRow = (row) => '│ ' + row.map((cell, i) => cell + ' '.repeat(columnWidths[i] - width(cell))).join(' │ ') + ' │';
+		const divider = columnWidths.map((w) => '─'.repeat(w + 2));
+		let result = '┌' + divider.join('┬') + '┐\n' +
+			renderRow(head) + '\n' +
+			'├' + divider.join('┼') + '┤\n';
+		for (const row of rows) result += renderRow(row) + '\n';
+		result += '└' + divider.join('┴') + '┘';
+		return result;
+	}
+
+	function table(data, properties) {
+		if (data === null || typeof data !== 'object') return console.log(data);
+
+		const cell = (v) => {
+			const depth = v !== null && typeof v === 'object' && !Array.isArray(v) && Object.keys(v).length > 2 ? -1 : 0;
+			return inspect(v, { depth: depth, maxArrayLength: 3, breakLength: Infinity });
+		};
+		const indexes = (n) => Array.from({ length: n }, (_, i) => cell(i));
+
+		if (data instanceof Map) {
+			const keys = [];
+			const values = [];
+			for (const [k, v] of data) {
+				keys.push(cell(k));
+				values.push(cell(v));
+			}
+			return print(1, renderTable(['(iteration index)', 'Key', 'Values'], [indexes(keys.length), keys, values]));
+		}
+		if (data instanceof Set) {
+			const values = [];
+			for (const v of data) values.push(cell(v));
+			return print(1, renderTable(['(iteration index)', 'Values'], [indexes(values.length), values]));
+		}
+
+		const map = Object.create(null);
+		let hasPrimitives = false;
+		const primitiveValues = [];
+		const indexKeys = Object.keys(data);
+		for (let i = 0; i < indexKeys.length; i++) {
+			const item = data[indexKeys[i]];
+			const primitive = item === null || (typeof item !== 'function' && typeof item !== 'object');
+			if (properties === undefined && primitive) {
+				hasPrimitives = true;
+				primitiveValues[i] = cell(item);
+			} else {
+				for (const key of properties || Object.keys(item)) {
+					if (map[key] === undefined) map[key] = [];
+					map[key][i] = (primitive && properties) || !hasOwn.call(item, key) ? '' : cell(item[key]);
+				}
+			}
+		}
+		const head = Object.keys(map);
+		const columns = Object.values(map);
+		if (hasPrimitives) {
+			head.push('Values');
+			columns.push(primitiveValues);
+		}
+		head.unshift('(index)');
+		columns.unshift(indexKeys);
+		print(1, renderTable(head, columns));
+	}
+
+	// ----- console -----
+
+	let groupIndent = '';
+	const counts = new Map();
+	const timers = new Map();
+
+	function print(fd, s) {
+		if (groupIndent !== '') s = groupIndent + s.split('\n').join('\n' + groupIndent);
+		write(fd, s);
+	}
+
+	function warning(msg) {
+		print(2, 'Warning: ' + msg);
+	}
+
+	function formatTime(ms) {
+		if (ms >= 60000) {
+			const minutes = Math.floor(ms / 60000);
+			const seconds = ((ms % 60000) / 1000).toFixed(3).padStart(6, '0');
+			return minutes + ':' + seconds + ' (m:ss.mmm)';
+		}
+		if (ms >= 1000) return (ms / 1000).toFixed(3) + 's';
+		return Number(ms.toFixed(3)) + 'ms';
+	}
+
+	function log(...args) {
+		print(1, format(args));
+	}
+
+	function error(...args) {
+		print(2, format(args));
+	}
+
+	function group(...labels) {
+		if (labels.length > 0) log(...labels);
+		groupIndent += '  ';
+	}
+
+	globalThis.console = {
+		log: log,
+		info: log,
+		debug: log,
+		dirxml: log,
+		warn: error,
+		error: error,
+		dir(obj, options) {
+			print(1, inspect(obj, Object.assign({ customInspect: false }, options)));
+		},
+		trace(...args) {
+			const err = { name: 'Trace', message: format(args) };
+			Error.captureStackTrace(err, globalThis.console.trace);
+			print(2, err.stack);
+		},
+		assert(condition, ...args) {
+			if (condition) return;
+			if (typeof args[0] === 'string') args[0] = 'Assertion failed: ' + args[0];
+			else args.unshift('Assertion failed');
+			error(...args);
+		},
+		count(label = 'default') {
+			label = String(label);
+			const n = (counts.get(label) || 0) + 1;
+			counts.set(label, n);
+			print(1, label + ': ' + n);
+		},
+		countReset(label = 'default') {
+			label = String(label);
+			if (!counts.has(label)) return warning("Count for '" + label + "' does not exist");
+			counts.delete(label);
+		},
+		group: group,
+		groupCollapsed: group,
+		groupEnd() {
+			groupIndent = groupIndent.slice(0, -2);
+		},
+		time(label = 'default') {
+			label = String(label);
+			if (timers.has(label)) return warning("Label '" + label + "' already exists for console.time()");
+			timers.set(label, performance.now());
+		},
+		timeLog(label = 'default', ...data) {
+			label = String(label);
+			if (!timers.has(label)) return warning("No such label '" + label + "' for console.timeLog()");
+			const elapsed = formatTime(performance.now() - timers.get(label));
+			print(1, label + ': ' + elapsed + (data.length > 0 ? ' ' + format(data) : ''));
+		},
+		timeEnd(label = 'default') {
+			label = String(label);
+			if (!timers.has(label)) return warning("No such label '" + label + "' for console.timeEnd()");
+			print(1, label + ': ' + formatTime(performance.now() - timers.get(label)));
+			timers.delete(label);
+		},
+		table: table,
+	};
+})();
+`
+
+// consoleDeclarations declares the console of InjectConsole.
+const consoleDeclarations = `interface Console {
+  log(...data: any[]): void;
+  info(...data: any[]): void;
+  debug(...data: any[]): void;
+  warn(...data: any[]): void;
+  error(...data: any[]): void;
+  trace(...data: any[]): void;
+  dir(item?: any, options?: any): void;
+  table(tabularData?: any, properties?: string[]): void;
+  assert(condition?: boolean, ...data: any[]): void;
+  count(label?: string): void;
+  countReset(label?: string): void;
+  group(...label: any[]): void;
+  groupCollapsed(...label: any[]): void;
+  groupEnd(): void;
+  time(label?: string): void;
+  timeLog(label?: string, ...data: any[]): void;
+  timeEnd(label?: string): void;
+}
+declare var console: Console;
+`
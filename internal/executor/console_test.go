@@ -0,0 +1,86 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestExecute_Console(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name   string
+		code   string
+		stdout string
+		stderr string
+	}{
+		{
+			name:   "nested objects with depth limit",
+			code:   `console.log({ a: 1, b: 'x', c: [1, { d: 3 }], e: { f: { g: { h: 1 } } } });`,
+			stdout: "{ a: 1, b: 'x', c: [ 1, { d: 3 } ], e: { f: { g: [Object] } } }\n",
+		},
+		{
+			name:   "cycles",
+			code:   `const o = { name: 'o' }; o.self = o; console.log(o);`,
+			stdout: "<ref *1> { name: 'o', self: [Circular *1] }\n",
+		},
+		{
+			name:   "maps, sets and primitives",
+			code:   `console.log(new Map([['a', 1]]), new Set([1, 'two']), [, 1], -0, 10n, null, undefined);`,
+			stdout: "Map(1) { 'a' => 1 } Set(2) { 1, 'two' } [ <1 empty item>, 1 ] -0 10n null undefined\n",
+		},
+		{
+			name:   "classes and functions",
+			code:   `class Foo { x = 1; } console.log(new Foo(), Foo, function bar() {}, Object.create(null));`,
+			stdout: "Foo { x: 1 } [class Foo] [Function: bar] [Object: null prototype] {}\n",
+		},
+		{
+			name:   "long arrays are grouped",
+			code:   `console.log(Array.from({ length: 12 }, (_, i) => i * 10));`,
+			stdout: "[\n    0,  10, 20, 30, 40,\n   50,  60, 70, 80, 90,\n  100, 110\n]\n",
+		},
+		{
+			name:   "printf substitution",
+			code:   `console.log('%s is %d, %i, %f, %j %o %%', 'Bob', 42.5, 42.5, '1.5', { a: [1] }, { b: 2 }, 'rest');`,
+			stdout: "Bob is 42.5, 42, 1.5, {\"a\":[1]} { b: 2 } % rest\n",
+		},
+		{
+			name:   "table",
+			code:   `console.table([{ a: 1, b: 'x' }, { a: 2 }]);`,
+			stdout: "┌─────────┬───┬─────┐\n│ (index) │ a │ b   │\n├─────────┼───┼─────┤\n│ 0       │ 1 │ 'x' │\n│ 1       │ 2 │     │\n└─────────┴───┴─────┘\n",
+		},
+		{
+			name:   "group, count and dir",
+			code:   `console.group('G'); console.count(); console.count(); console.groupEnd(); console.dir({ a: { b: 1 } }, { depth: 0 });`,
+			stdout: "G\n  default: 1\n  default: 2\n{ a: [Object] }\n",
+		},
+		{
+			name:   "assert and missing timer label",
+			code:   `console.assert(true, 'no'); console.assert(false, 'failed %s', 'here'); console.timeEnd('t');`,
+			stderr: "Assertion failed: failed here\nWarning: No such label 't' for console.timeEnd()\n",
+		},
+		{
+			name:   "internal writer is not global",
+			code:   `console.log(typeof __console_write, 'write' in console);`,
+			stdout: "undefined false\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := Execute(ctx, tt.code, "test.js", nil, Options{})
+			if !res.Success {
+				t.Fatalf("Execution failed: %s\nStderr: %s", res.Summary, res.Stderr)
+			}
+			if res.Stdout != tt.stdout {
+				t.Errorf("stdout:\n%s\nwant:\n%s", res.Stdout, tt.stdout)
+			}
+			if res.Stderr != tt.stderr {
+				t.Errorf("stderr:\n%s\nwant:\n%s", res.Stderr, tt.stderr)
+			}
+		})
+	}
+}
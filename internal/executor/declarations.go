@@ -0,0 +1,50 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import "strings"
+
+// DeclarationsFile is the conventional name of the file returned by
+// Declarations.
+const DeclarationsFile = "wollmilchsau.d.ts"
+
+// declarationsHeader starts the file returned by Declarations.
+const declarationsHeader = `// wollmilchsau.d.ts: type declarations of the globals of the wollmilchsau
+// sandbox, in addition to the ECMAScript built-ins (lib.es2020 or later).
+// Generated by wollmilchsau; do not edit.
+`
+
+// declarations lists the declarations of the globals each injection adds.
+// Every fragment is defined next to the code that injects the globals, so
+// that both change together; TestDeclarations checks that they cover all
+// globals of a sandbox. The fragments add their members to the Wollmilchsau
+// interface of namespaceDeclarations by declaration merging.
+var declarations = []struct {
+	source    string
+	artifacts bool // only injected with an artifact store
+}{
+	{source: consoleDeclarations},
+	{source: eventLoopDeclarations},
+	{source: polyfillsDeclarations},
+	{source: namespaceDeclarations},
+	{source: inputDeclarations},
+	{source: filesDeclarations},
+	{source: returnValueDeclarations},
+	{source: artifactErrorsDeclarations},
+	{source: artifactsDeclarations, artifacts: true},
+	{source: openArtifactDeclarations, artifacts: true},
+}
+
+// Declarations returns the TypeScript declarations of the globals a sandbox
+// provides on top of the ECMAScript built-ins, as an ambient declaration
+// file. The artifact APIs are only declared when they are enabled.
+func Declarations(enableArtifacts bool) string {
+	var b strings.Builder
+	b.WriteString(declarationsHeader)
+	for _, d := range declarations {
+		if d.artifacts && !enableArtifacts {
+			continue
+		}
+		b.WriteString("\n" + d.source)
+	}
+	return b.String()
+}
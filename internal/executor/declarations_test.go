@@ -0,0 +1,113 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	v8 "rogchap.com/v8go"
+)
+
+// globalsJS returns the names of the globals, the members of wollmilchsau
+// and of artifact, if any.
+const globalsJS = `wollmilchsau.return({
+	globals: Object.getOwnPropertyNames(globalThis),
+	wollmilchsau: Object.getOwnPropertyNames(wollmilchsau),
+	artifact: typeof artifact === 'undefined' ? [] : Object.getOwnPropertyNames(artifact),
+});`
+
+// TestDeclarations checks that the declarations cover exactly the globals of
+// a sandbox that are not ECMAScript built-ins.
+func TestDeclarations(t *testing.T) {
+	iso := v8.NewIsolate()
+	defer iso.Dispose()
+	bare := v8.NewContext(iso)
+	defer bare.Close()
+	val, err := bare.RunScript("JSON.stringify(Object.getOwnPropertyNames(globalThis))", "builtins.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var builtins []string
+	if err := json.Unmarshal([]byte(val.String()), &builtins); err != nil {
+		t.Fatal(err)
+	}
+	builtin := make(map[string]bool)
+	for _, name := range builtins {
+		builtin[name] = true
+	}
+
+	for _, artifacts := range []bool{false, true} {
+		opts := Options{}
+		if artifacts {
+			opts.Artifacts = NewMemoryArtifactStore()
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		res := Execute(ctx, globalsJS, "globals.js", nil, opts)
+		cancel()
+		if !res.Success {
+			t.Fatalf("execution failed: %s %s", res.Summary, res.Stderr)
+		}
+		var got struct{ Globals, Wollmilchsau, Artifact []string }
+		if err := json.Unmarshal(res.ReturnValue, &got); err != nil {
+			t.Fatal(err)
+		}
+
+		decl := Declarations(artifacts)
+		for _, name := range got.Globals {
+			// Internal helpers start with "__" and are not part of the API.
+			if !builtin[name] && !strings.HasPrefix(name, "__") {
+				if !regexp.MustCompile(`declare (var|function|class) ` + name + `\b`).MatchString(decl) {
+					t.Errorf("artifacts=%v: global %s is not declared", artifacts, name)
+				}
+			}
+		}
+		for _, m := range regexp.MustCompile(`declare (?:var|function|class) (\w+)`).FindAllStringSubmatch(decl, -1) {
+			// Injections may replace built-ins such as console.
+			found := false
+			for _, name := range got.Globals {
+				found = found || name == m[1]
+			}
+			if !found {
+				t.Errorf("artifacts=%v: %s is declared but does not exist", artifacts, m[1])
+			}
+		}
+		members := append(got.Wollmilchsau, got.Artifact...)
+		for _, name := range members {
+			if !regexp.MustCompile(`\n  (readonly )?` + name + `[(:]`).MatchString(decl) {
+				t.Errorf("artifacts=%v: member %s is not declared", artifacts, name)
+			}
+		}
+	}
+}
+
+// TestDeclarations_Layout checks the layout of the rendered file, which is
+// published as a resource: comments end their line, the fragments are
+// separated by blank lines and indentation uses two spaces.
+func TestDeclarations_Layout(t *testing.T) {
+	decl := Declarations(true)
+	if !strings.HasSuffix(decl, "}\n") || strings.Contains(decl, "\n\n\n") {
+		t.Errorf("fragments are not separated by single blank lines")
+	}
+	for _, d := range declarations {
+		if !strings.HasSuffix(d.source, "\n") || strings.HasPrefix(d.source, "\n") {
+			t.Errorf("fragment does not end with exactly one newline: %.40q", d.source)
+		}
+	}
+	for i, line := range strings.Split(decl, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		switch {
+		case strings.Contains(line, "*/") && !strings.HasSuffix(line, "*/"):
+			t.Errorf("line %d: code after a comment: %q", i+1, line)
+		case strings.Contains(line, "/**") && !strings.HasPrefix(trimmed, "/**"):
+			t.Errorf("line %d: comment after code: %q", i+1, line)
+		case strings.ContainsAny(line, "\t\r") || strings.TrimRight(line, " ") != line:
+			t.Errorf("line %d: tab or trailing whitespace: %q", i+1, line)
+		case (len(line)-len(trimmed))%2 != 0:
+			t.Errorf("line %d: odd indentation: %q", i+1, line)
+		}
+	}
+}
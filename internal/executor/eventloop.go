@@ -0,0 +1,323 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	v8 "rogchap.com/v8go"
+)
+
+// eventLoop is a Go-driven event loop for a single V8 context.
+// It provides setTimeout/setInterval/clearTimeout/clearInterval and
+// queueMicrotask. Timers fire in order of their due time; timers that are due
+// at the same time fire in the order they were created. Due times follow the
+// wall clock, so timing-dependent scripts are not reproducible.
+type eventLoop struct {
+	iso   *v8.Isolate
+	v8ctx *v8.Context
+	wd    *watchdog // set for the duration of Run
+
+	timers map[int32]*timer
+	nextID int32
+	seq    uint64
+
+	// uncaught holds the first exception thrown by a queueMicrotask callback.
+	uncaught *v8.Value
+
+	// rejections records promises rejected without a handler.
+	rejections *rejectionTracker
+
+	// Host operations (e.g. artifact service calls) run on their own
+	// goroutines. Their completions are queued and run by the loop, which is
+	// the only goroutine using the isolate.
+	mu        sync.Mutex
+	completed []func()
+	wake      chan struct{} // signals new completions
+	pending   int           // host operations that have not completed yet
+}
+
+// timer is a single pending setTimeout/setInterval registration.
+type timer struct {
+	id       int32
+	seq      uint64 // creation order, used to break ties between equal due times
+	fn       *v8.Function
+	args     []v8.Valuer
+	due      time.Time
+	interval time.Duration // > 0 for setInterval
+}
+
+func newEventLoop(iso *v8.Isolate, v8ctx *v8.Context) *eventLoop {
+	return &eventLoop{
+		iso:    iso,
+		v8ctx:  v8ctx,
+		timers: make(map[int32]*timer),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// async runs work on a new goroutine and calls done with its result from the
+// event loop. work must not use V8; done may. Run keeps going while host
+// operations are pending.
+func (l *eventLoop) async(work func() (any, error), done func(any, error)) {
+	l.pending++
+	go func() {
+		v, err := work()
+		l.mu.Lock()
+		l.completed = append(l.completed, func() { done(v, err) })
+		l.mu.Unlock()
+		select {
+		case l.wake <- struct{}{}:
+		default:
+		}
+	}()
+}
+
+// runCompleted calls the done callbacks of finished host operations and
+// reports whether there were any.
+func (l *eventLoop) runCompleted() bool {
+	l.mu.Lock()
+	completed := l.completed
+	l.completed = nil
+	l.mu.Unlock()
+
+	for _, done := range completed {
+		l.pending--
+		l.wd.enter()
+		done()
+		l.wd.leave()
+	}
+	return len(completed) > 0
+}
+
+// wait blocks until t is due or a host operation completes and reports
+// whether t is due. t may be nil if only host operations are pending.
+func (l *eventLoop) wait(ctx context.Context, t *timer) (bool, error) {
+	var due <-chan time.Time
+	if t != nil {
+		wait := time.Until(t.due)
+		if wait <= 0 {
+			return true, nil
+		}
+		timerC := time.NewTimer(wait)
+		defer timerC.Stop()
+		due = timerC.C
+	}
+	select {
+	case <-ctx.Done():
+		return false, errTerminated
+	case <-due:
+		return true, nil
+	case <-l.wake:
+		return false, nil
+	}
+}
+
+// Inject adds the timer functions and queueMicrotask to the global object.
+func (l *eventLoop) Inject() error {
+	global := l.v8ctx.Global()
+
+	setTimeoutFn := v8.NewFunctionTemplate(l.iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
+		return l.schedule(info, false)
+	})
+	setIntervalFn := v8.NewFunctionTemplate(l.iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
+		return l.schedule(info, true)
+	})
+	clearFn := v8.NewFunctionTemplate(l.iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
+		if len(info.Args()) > 0 && info.Args()[0].IsNumber() {
+			delete(l.timers, info.Args()[0].Int32())
+		}
+		return v8.Undefined(l.iso)
+	})
+	reportFn := v8.NewFunctionTemplate(l.iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
+		if l.uncaught == nil && len(info.Args()) > 0 {
+			l.uncaught = info.Args()[0]
+		}
+		return v8.Undefined(l.iso)
+	})
+
+	_ = global.Set("setTimeout", setTimeoutFn.GetFunction(l.v8ctx))
+	_ = global.Set("setInterval", setIntervalFn.GetFunction(l.v8ctx))
+	_ = global.Set("clearTimeout", clearFn.GetFunction(l.v8ctx))
+	_ = global.Set("clearInterval", clearFn.GetFunction(l.v8ctx))
+	_ = global.Set("__report_uncaught", reportFn.GetFunction(l.v8ctx))
+
+	rejections, err := trackRejections(l.iso, l.v8ctx)
+	if err != nil {
+		return err
+	}
+	l.rejections = rejections
+
+	return runPrelude(l.v8ctx, "eventloop.js", eventLoopJS)
+}
+
+// schedule registers a new timer from a setTimeout/setInterval call and
+// returns its ID to JS.
+func (l *eventLoop) schedule(info *v8.FunctionCallbackInfo, repeat bool) *v8.Value {
+	args := info.Args()
+	if len(args) < 1 || !args[0].IsFunction() {
+		return throwTypeError(l.iso, l.v8ctx, "timer callback must be a function")
+	}
+	fn, _ := args[0].AsFunction()
+
+	// Like Node.js, delays below 1ms (or not a number) are treated as 1ms.
+	delay := time.Millisecond
+	if len(args) >= 2 {
+		if ms := args[1].Number(); !math.IsNaN(ms) && ms > 1 && ms <= math.MaxInt32 {
+			delay = time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+
+	var extra []v8.Valuer
+	for _, a := range args[min(2, len(args)):] {
+		extra = append(extra, a)
+	}
+
+	l.nextID++
+	l.seq++
+	t := &timer{
+		id:   l.nextID,
+		seq:  l.seq,
+		fn:   fn,
+		args: extra,
+		due:  time.Now().Add(delay),
+	}
+	if repeat {
+		t.interval = delay
+	}
+	l.timers[t.id] = t
+
+	val, _ := v8.NewValue(l.iso, t.id)
+	return val
+}
+
+// next returns the timer that is due first, or nil if none are pending.
+func (l *eventLoop) next() *timer {
+	var best *timer
+	for _, t := range l.timers {
+		if best == nil || t.due.Before(best.due) || (t.due.Equal(best.due) && t.seq < best.seq) {
+			best = t
+		}
+	}
+	return best
+}
+
+// Run drains the microtask queue and processes timers and completed host
+// operations until no work is left, a promise is rejected without a handler,
+// or ctx expires. If entry is a Promise, its settled value is returned; a
+// rejection of the entry promise or of any other promise that has no handler
+// once the microtask queue is drained is returned as a *v8.JSError, like an
+// unhandled rejection ends a Node.js process.
+// Time spent in promise jobs and timer callbacks is accounted to wd.
+func (l *eventLoop) Run(ctx context.Context, wd *watchdog, entry *v8.Value) (*v8.Value, error) {
+	l.wd = wd
+	prom := asPromise(entry)
+
+	for {
+		wd.enter()
+		l.v8ctx.PerformMicrotaskCheckpoint()
+		wd.leave()
+		if err := l.check(ctx); err != nil {
+			return nil, err
+		}
+		if prom != nil && prom.State() == v8.Rejected {
+			return nil, rejectionError(prom.Result())
+		}
+		if jsErr := l.rejections.take(); jsErr != nil {
+			return nil, jsErr
+		}
+
+		if l.runCompleted() {
+			continue
+		}
+
+		t := l.next()
+		if t == nil && l.pending == 0 {
+			break
+		}
+
+		due, err := l.wait(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+		if !due {
+			continue
+		}
+
+		if t.interval > 0 {
+			l.seq++
+			t.seq = l.seq
+			t.due = time.Now().Add(t.interval)
+		} else {
+			delete(l.timers, t.id)
+		}
+
+		wd.enter()
+		_, err = t.fn.Call(v8.Undefined(l.iso), t.args...)
+		wd.leave()
+		if err != nil {
+			if cerr := l.check(ctx); cerr != nil {
+				return nil, cerr
+			}
+			return nil, err
+		}
+	}
+
+	if prom == nil {
+		return entry, nil
+	}
+	switch prom.State() {
+	case v8.Fulfilled:
+		return prom.Result(), nil
+	case v8.Rejected:
+		return nil, rejectionError(prom.Result())
+	default:
+		return nil, errUnsettled
+	}
+}
+
+// close stops tracking rejections. It must be called before the isolate is
+// disposed.
+func (l *eventLoop) close() {
+	l.rejections.close()
+}
+
+// check reports termination (any exceeded limit) and exceptions thrown
+// inside queueMicrotask callbacks.
+func (l *eventLoop) check(ctx context.Context) error {
+	if ctx.Err() != nil || l.iso.IsExecutionTerminating() || l.wd.check() != reasonNone {
+		return errTerminated
+	}
+	if l.uncaught != nil {
+		return rejectionError(l.uncaught)
+	}
+	return nil
+}
+
+// eventLoopJS implements queueMicrotask on top of the native promise job queue.
+// Exceptions are routed back to Go so they are not silently swallowed; the
+// reporting function is removed from the global object once captured.
+const eventLoopJS = `(function() {
+	const report = __report_uncaught;
+	delete globalThis.__report_uncaught;
+	globalThis.queueMicrotask = function(callback) {
+		if (typeof callback !== 'function') {
+			throw new TypeError('queueMicrotask: callback must be a function');
+		}
+		Promise.resolve().then(() => {
+			try { callback(); } catch (e) { report(e); }
+		});
+	};
+})();
+`
+
+// eventLoopDeclarations declares the timer functions and queueMicrotask of
+// eventLoop.Inject.
+const eventLoopDeclarations = `declare function setTimeout(callback: (...args: any[]) => void, ms?: number, ...args: any[]): number;
+declare function setInterval(callback: (...args: any[]) => void, ms?: number, ...args: any[]): number;
+declare function clearTimeout(id?: number): void;
+declare function clearInterval(id?: number): void;
+declare function queueMicrotask(callback: () => void): void;
+`
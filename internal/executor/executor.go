@@ -4,16 +4,26 @@ package executor
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log/slog"
 	"strings"
-	"time"
 
-	mlcartifact "github.com/hmsoft0815/mlcartifact/client"
 	"github.com/hmsoft0815/wollmilchsau/internal/sourcemap"
 	v8 "rogchap.com/v8go"
 )
 
+// defaultMaxMemoryBytes is the default V8 heap limit enforced by the watchdog.
+const defaultMaxMemoryBytes = 128 * 1024 * 1024
+
+// Options configures a single execution.
+type Options struct {
+	Artifacts   ArtifactStore // artifact backend; nil disables the artifact APIs
+	Limits      Limits        // resource limits; unset fields use DefaultLimits
+	SpillOutput bool          // save output exceeding the budget as an artifact
+	Input       *Input        // optional input data, nil if none
+	Files       []InputFile   // prefetched artifacts exposed as wollmilchsau.files
+}
+
 // Execute runs the provided JavaScript inside a fresh and isolated V8 Isolate.
 //
 // @Summary Executes JavaScript in V8
@@ -23,141 +33,83 @@ import (
 // @Param js body string true "Bundled JavaScript code"
 // @Param filename body string true "Name of the entry file for stack traces"
 // @Param sm body object false "Source map for position resolution"
+// @Param opts body Options false "Artifact store and resource limits"
 // Success 200 {object} Result
-func Execute(ctx context.Context, js string, filename string, sm *sourcemap.SourceMap, artifactAddr string) *Result {
-	start := time.Now()
-
-	res := &Result{Diagnostics: []Diagnostic{}}
-
+func Execute(ctx context.Context, js string, filename string, sm *sourcemap.SourceMap, opts Options) *Result {
 	iso := v8.NewIsolate()
 	defer iso.Dispose()
 
-	var stdout, stderr strings.Builder
-	global := v8.NewObjectTemplate(iso)
-	consoleTmpl := v8.NewObjectTemplate(iso)
-
-	makeLogger := func(target *strings.Builder) *v8.FunctionTemplate {
-		return v8.NewFunctionTemplate(iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
-			parts := make([]string, len(info.Args()))
-			for i, arg := range info.Args() {
-				parts[i] = arg.String()
-			}
-			target.WriteString(strings.Join(parts, " "))
-			target.WriteByte('\n')
-			return nil
-		})
-	}
-
-	_ = consoleTmpl.Set("log", makeLogger(&stdout))
-	_ = consoleTmpl.Set("info", makeLogger(&stdout))
-	_ = consoleTmpl.Set("warn", makeLogger(&stderr))
-	_ = consoleTmpl.Set("error", makeLogger(&stderr))
-
-	v8ctx := v8.NewContext(iso, global)
-	defer v8ctx.Close()
-
-	if console, err := consoleTmpl.NewInstance(v8ctx); err == nil {
-		_ = v8ctx.Global().Set("console", console)
-	}
-
-	if err := InjectPolyfills(iso, v8ctx); err != nil {
-		slog.Error("failed to inject polyfills", "err", err)
-	}
-
-	// Create one shared artifact client — used by both the low-level `artifact.*`
-	// API and the new `wollmilchsau.openArtifact()` high-level API.
-	var cli *mlcartifact.Client
-	var artErr error
-	if artifactAddr != "" {
-		cli, artErr = mlcartifact.NewClientWithAddr(artifactAddr)
-	} else {
-		cli, artErr = mlcartifact.NewClient()
-	}
-
-	if artErr == nil {
-		if err := InjectArtifactServiceWithClient(iso, v8ctx, cli); err != nil {
-			slog.Error("failed to inject artifact service", "err", err)
-		}
-		if err := InjectOpenArtifact(iso, v8ctx, cli, res); err != nil {
-			slog.Error("failed to inject wollmilchsau.openArtifact", "err", err)
-		}
-	} else {
-		slog.Warn("artifact client unavailable, skipping artifact polyfills", "err", artErr)
-	}
-
-	watchdogDone := startWatchdog(ctx, iso, 128*1024*1024)
-	val, runErr := v8ctx.RunScript(js, filename)
-	watchdogDone <- struct{}{}
-
-	res.Stdout = stdout.String()
-	res.Stderr = stderr.String()
-	res.DurationMs = time.Since(start).Milliseconds()
-
-	if runErr != nil {
-		handleExecuteError(ctx, iso, runErr, sm, res)
-	} else {
-		_ = val
-		res.ExitCode = 0
-		res.Success = true
-		res.Summary = "Execution finished successfully"
-	}
-
-	return res
-}
+	sb := newSandbox(iso)
+	defer sb.close()
 
-func startWatchdog(ctx context.Context, iso *v8.Isolate, maxMemoryBytes uint64) chan struct{} {
-	done := make(chan struct{}, 1)
-	go func() {
-		ticker := time.NewTicker(100 * time.Millisecond)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ctx.Done():
-				iso.TerminateExecution()
-				return
-			case <-ticker.C:
-				if stats := iso.GetHeapStatistics(); stats.UsedHeapSize > maxMemoryBytes {
-					iso.TerminateExecution()
-					return
-				}
-			case <-done:
-				return
-			}
-		}
-	}()
-	return done
+	return sb.run(ctx, js, filename, sm, opts)
 }
 
-func handleExecuteError(ctx context.Context, iso *v8.Isolate, err error, sm *sourcemap.SourceMap, res *Result) {
+func handleExecuteError(ctx context.Context, heapUsed uint64, reason terminationReason, limits Limits, err error, filename string, sm *sourcemap.SourceMap, res *Result) {
 	res.Success = false
-	if ctx.Err() != nil {
+	if reason == reasonNone && ctx.Err() != nil {
+		reason = reasonWallTime
+	}
+
+	switch reason {
+	case reasonWallTime:
 		res.Stderr += "execution terminated: timeout exceeded\n"
-		res.ExitCode = 124
+		res.ExitCode = ExitCodeTimeout
 		res.Summary = "Execution timed out"
 		return
+	case reasonMemory:
+		res.Stderr += fmt.Sprintf("execution terminated: memory limit exceeded (%d MB used, limit %d MB)\n",
+			heapUsed/1024/1024, limits.MaxHeapBytes/1024/1024)
+		res.ExitCode = ExitCodeMemoryLimit
+		res.Summary = "Execution terminated: Memory limit exceeded"
+		return
+	case reasonCPUTime:
+		res.Stderr += fmt.Sprintf("execution terminated: CPU time limit exceeded (%d ms)\n", limits.MaxCPUTimeMs)
+		res.ExitCode = ExitCodeCPULimit
+		res.Summary = "Execution terminated: CPU time limit exceeded"
+		return
+	case reasonOutput:
+		res.Stderr += fmt.Sprintf("execution terminated: output limit exceeded (stdout %d bytes, stderr %d bytes)\n",
+			limits.MaxStdoutBytes, limits.MaxStderrBytes)
+		res.ExitCode = ExitCodeOutputLimit
+		res.Summary = "Execution terminated: Output limit exceeded"
+		return
 	}
 
-	diag := extractDiagnostic(err, sm)
+	diag := extractDiagnostic(err, filename, sm)
 	res.Diagnostics = append(res.Diagnostics, diag)
-	res.ExitCode = 1
-
-	if strings.Contains(err.Error(), "Execution terminated") {
-		stats := iso.GetHeapStatistics()
-		const maxMemoryBytes = 128 * 1024 * 1024
-		if stats.UsedHeapSize > maxMemoryBytes {
-			res.Stderr += fmt.Sprintf("execution terminated: memory limit exceeded (%d MB)\n", stats.UsedHeapSize/1024/1024)
-			res.Summary = "Execution terminated: Memory limit exceeded"
-		} else {
-			res.Summary = "Execution terminated (internal error or forced stop)"
+	res.ExitCode = ExitCodeRuntimeError
+	if len(diag.Frames) > 0 {
+		if res.Stderr != "" && !strings.HasSuffix(res.Stderr, "\n") {
+			res.Stderr += "\n"
 		}
-	} else {
+		res.Stderr += formatTrace(diag.Message, diag.Frames)
+	}
+
+	switch {
+	case isStackOverflow(err):
+		res.ExitCode = ExitCodeStackLimit
+		res.Summary = fmt.Sprintf("Execution terminated: Stack limit exceeded in %s:%d", diag.Source, diag.Line)
+	case errors.Is(err, errTerminated) || strings.Contains(err.Error(), "Execution terminated"):
+		res.Summary = "Execution terminated (internal error or forced stop)"
+	case diag.Source == "":
+		res.Summary = "Runtime Error: " + diag.Message
+	default:
 		res.Summary = fmt.Sprintf("Runtime Error: %s in %s:%d", diag.Message, diag.Source, diag.Line)
 	}
 }
 
+// isStackOverflow reports whether err is V8's RangeError for exceeding the stack size.
+func isStackOverflow(err error) bool {
+	return strings.Contains(err.Error(), "Maximum call stack size exceeded")
+}
+
 // extractDiagnostic converts a V8 error into a Diagnostic, resolving positions
-// using the provided source map if available.
-func extractDiagnostic(err error, sm *sourcemap.SourceMap) Diagnostic {
+// using the provided source map if available. The frames of its stack trace
+// that lie in the bundle, which runs as filename, become d.Frames; the
+// position of the diagnostic is that of the first frame, or the location of
+// the exception if there is none.
+func extractDiagnostic(err error, filename string, sm *sourcemap.SourceMap) Diagnostic {
 	d := Diagnostic{Severity: SeverityError}
 	var genLine, genCol int
 
@@ -167,6 +119,12 @@ func extractDiagnostic(err error, sm *sourcemap.SourceMap) Diagnostic {
 		return d
 	}
 	d.Message = jsErr.Message
+	d.Frames, genLine, genCol = resolveFrames(jsErr.StackTrace, filename, sm)
+	if len(d.Frames) > 0 {
+		d.GeneratedLine, d.GeneratedColumn = genLine, genCol
+		d.Source, d.Line, d.Column = d.Frames[0].Source, d.Frames[0].Line, d.Frames[0].Column
+		return d
+	}
 
 	if jsErr.Location == "" {
 		return d
@@ -6,6 +6,9 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/hmsoft0815/wollmilchsau/internal/bundler"
+	"github.com/hmsoft0815/wollmilchsau/internal/parser"
 )
 
 func TestExecute_Polyfills(t *testing.T) {
@@ -36,6 +39,15 @@ func TestExecute_Polyfills(t *testing.T) {
 			`,
 			contains: "b64_ok: true",
 		},
+		{
+			name: "atob and btoa with binary data",
+			code: `
+				const bytes = Buffer.from("AH//gA==", "base64");
+				const roundTrip = btoa(String.fromCharCode(...bytes));
+				console.log('bin_ok:', bytes.join(',') === "0,127,255,128" && roundTrip === "AH//gA==");
+			`,
+			contains: "bin_ok: true",
+		},
 		{
 			name: "crypto.getRandomValues",
 			code: `
@@ -73,7 +85,7 @@ func TestExecute_Polyfills(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			res := Execute(ctx, tt.code, "test.js", nil, "")
+			res := Execute(ctx, tt.code, "test.js", nil, Options{})
 			if !res.Success {
 				t.Errorf("Execution failed: %s\nStderr: %s", res.Summary, res.Stderr)
 				return
@@ -84,3 +96,190 @@ func TestExecute_Polyfills(t *testing.T) {
 		})
 	}
 }
+
+func TestExecute_Async(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("awaits async main", func(t *testing.T) {
+		code := `
+			(async () => {
+				async function main() {
+					await null;
+					console.log('async_ok');
+				}
+				await main();
+			})()
+		`
+		res := Execute(ctx, code, "test.js", nil, Options{})
+		if !res.Success {
+			t.Fatalf("Execution failed: %s\nStderr: %s", res.Summary, res.Stderr)
+		}
+		if !strings.Contains(res.Stdout, "async_ok") {
+			t.Errorf("Expected output to contain async_ok, got %q", res.Stdout)
+		}
+	})
+
+	t.Run("then chain", func(t *testing.T) {
+		code := `Promise.resolve(21).then(x => x * 2).then(x => console.log('then:', x));`
+		res := Execute(ctx, code, "test.js", nil, Options{})
+		if !strings.Contains(res.Stdout, "then: 42") {
+			t.Errorf("Expected output to contain 'then: 42', got %q", res.Stdout)
+		}
+	})
+
+	t.Run("rejected promise", func(t *testing.T) {
+		code := "(async () => {\n  await null;\n  throw new Error('boom');\n})()"
+		res := Execute(ctx, code, "test.js", nil, Options{})
+		if res.Success {
+			t.Fatal("Expected execution to fail")
+		}
+		if len(res.Diagnostics) != 1 {
+			t.Fatalf("Expected 1 diagnostic, got %d", len(res.Diagnostics))
+		}
+		d := res.Diagnostics[0]
+		if !strings.Contains(d.Message, "boom") || d.GeneratedLine != 3 {
+			t.Errorf("Unexpected diagnostic: %+v", d)
+		}
+	})
+
+	t.Run("unhandled rejections", func(t *testing.T) {
+		tests := []struct {
+			name string
+			code string
+			msg  string
+			line int
+		}{
+			{"async main without await", "async function main(): Promise<void> {\n  await null;\n  throw new Error('boom');\n}\nmain();", "Error: boom", 3},
+			{"Promise.reject", "console.log('start');\nPromise.reject(new Error('nope'));", "Error: nope", 2},
+			{"rejected in a timer", "setTimeout(() => {\n  Promise.reject(new Error('late'));\n}, 1);", "Error: late", 2},
+		}
+		for _, tt := range tests {
+			plan := &parser.ExecutionPlan{Files: []parser.VirtualFile{{Name: "main.ts", Content: tt.code}}, EntryPoint: "main.ts"}
+			b, err := bundler.Bundle(plan)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := Execute(ctx, b.JS, "main.ts", b.SourceMap, Options{})
+			if res.Success || res.ExitCode != ExitCodeRuntimeError || len(res.Diagnostics) != 1 {
+				t.Fatalf("%s: expected a runtime error, got %+v", tt.name, res)
+			}
+			if d := res.Diagnostics[0]; d.Message != tt.msg || d.Source != "main.ts" || d.Line != tt.line {
+				t.Errorf("%s: unexpected diagnostic: %+v", tt.name, d)
+			}
+		}
+
+		// A handler attached after the rejection, but in the same turn, counts.
+		res := Execute(ctx, "const p = Promise.reject(new Error('x'));\np.catch((e) => console.log('caught', e.message));", "test.js", nil, Options{})
+		if !res.Success || res.Stdout != "caught x\n" {
+			t.Errorf("a handled rejection was reported: %+v", res)
+		}
+	})
+
+	t.Run("never settled", func(t *testing.T) {
+		res := Execute(ctx, `new Promise(() => {})`, "test.js", nil, Options{})
+		if res.Success {
+			t.Fatal("Expected execution to fail for a pending top-level promise")
+		}
+	})
+}
+
+func TestExecute_EventLoop(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name     string
+		code     string
+		contains string
+	}{
+		{
+			name: "timer ordering",
+			code: `
+				const out = [];
+				setTimeout(() => out.push('c'), 20);
+				setTimeout(() => out.push('a'), 0);
+				setTimeout(() => out.push('b'), 0);
+				queueMicrotask(() => out.push('micro'));
+				setTimeout(() => console.log('order:', out.join(',')), 30);
+			`,
+			contains: "order: micro,a,b,c",
+		},
+		{
+			name: "setInterval and clearInterval",
+			code: `
+				let n = 0;
+				const id = setInterval((step) => {
+					n += step;
+					if (n >= 3) {
+						clearInterval(id);
+						console.log('interval_done:', n);
+					}
+				}, 1, 1);
+			`,
+			contains: "interval_done: 3",
+		},
+		{
+			name: "await sleep",
+			code: `
+				(async () => {
+					const sleep = (ms) => new Promise(r => setTimeout(r, ms));
+					const t0 = performance.now();
+					await sleep(15);
+					console.log('slept:', performance.now() - t0 >= 10);
+				})()
+			`,
+			contains: "slept: true",
+		},
+		{
+			name: "clearTimeout",
+			code: `
+				const id = setTimeout(() => console.log('should not run'), 5);
+				clearTimeout(id);
+				console.log('cleared');
+			`,
+			contains: "cleared",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := Execute(ctx, tt.code, "test.js", nil, Options{})
+			if !res.Success {
+				t.Fatalf("Execution failed: %s\nStderr: %s", res.Summary, res.Stderr)
+			}
+			if !strings.Contains(res.Stdout, tt.contains) {
+				t.Errorf("Expected output to contain %q, got %q", tt.contains, res.Stdout)
+			}
+			if strings.Contains(res.Stdout, "should not run") {
+				t.Errorf("Cleared timer fired: %q", res.Stdout)
+			}
+		})
+	}
+
+	t.Run("error in queueMicrotask callback", func(t *testing.T) {
+		res := Execute(ctx, "console.log(typeof __report_uncaught);\nqueueMicrotask(() => { throw new Error('micro'); });", "test.js", nil, Options{})
+		if res.Success || len(res.Diagnostics) == 0 || !strings.Contains(res.Diagnostics[0].Message, "micro") {
+			t.Fatalf("Expected runtime error from microtask, got %+v", res)
+		}
+		if res.Stdout != "undefined\n" {
+			t.Errorf("the internal reporter is visible to the script: %q", res.Stdout)
+		}
+	})
+
+	t.Run("error in timer callback", func(t *testing.T) {
+		res := Execute(ctx, "setTimeout(() => {\n  throw new Error('late');\n}, 1);", "test.js", nil, Options{})
+		if res.Success || len(res.Diagnostics) == 0 || !strings.Contains(res.Diagnostics[0].Message, "late") {
+			t.Fatalf("Expected runtime error from timer callback, got %+v", res)
+		}
+	})
+
+	t.Run("endless interval times out", func(t *testing.T) {
+		shortCtx, shortCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer shortCancel()
+		res := Execute(shortCtx, "setInterval(() => {}, 5);", "test.js", nil, Options{})
+		if res.ExitCode != 124 {
+			t.Fatalf("Expected timeout exit code 124, got %d (%s)", res.ExitCode, res.Summary)
+		}
+	})
+}
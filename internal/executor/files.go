@@ -0,0 +1,129 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"errors"
+
+	v8 "rogchap.com/v8go"
+)
+
+// InputFile is an artifact fetched before the execution and exposed to the
+// script as wollmilchsau.files[Alias].
+type InputFile struct {
+	Alias    string // key in wollmilchsau.files
+	ID       string // artifact ID
+	Name     string // original filename
+	MimeType string
+	Content  []byte
+}
+
+// InjectFiles defines the read-only object wollmilchsau.files for a single
+// execution. Each entry has the metadata fields id, name, mimeType and size
+// and the accessors text(), bytes() and json(). Contents are only copied into
+// V8 when an accessor is called.
+func InjectFiles(iso *v8.Isolate, ctx *v8.Context, files []InputFile) error {
+	global := ctx.Global()
+	defineVal, err := global.Get("__define_files")
+	if err != nil {
+		return err
+	}
+	if !defineVal.IsFunction() {
+		return errors.New("files prelude not loaded")
+	}
+	define, _ := defineVal.AsFunction()
+	// The helper is only needed once per context.
+	global.Delete("__define_files")
+
+	ns, err := namespaceObject(iso, ctx)
+	if err != nil {
+		return err
+	}
+
+	type fileMeta struct {
+		Alias    string `json:"alias"`
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		MimeType string `json:"mimeType"`
+		Size     int    `json:"size"`
+	}
+	metas := make([]fileMeta, len(files))
+	for i, f := range files {
+		metas[i] = fileMeta{Alias: f.Alias, ID: f.ID, Name: f.Name, MimeType: f.MimeType, Size: len(f.Content)}
+	}
+
+	bin, err := newBinaryBridge(iso, ctx)
+	if err != nil {
+		return err
+	}
+
+	// read(index, binary) returns the content as text or as a Uint8Array.
+	readFn := v8.NewFunctionTemplate(iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
+		args := info.Args()
+		if len(args) < 2 {
+			return v8.Undefined(iso)
+		}
+		i := int(args[0].Integer())
+		if i < 0 || i >= len(files) {
+			return v8.Undefined(iso)
+		}
+		if args[1].Boolean() {
+			val, _ := bin.uint8Array(files[i].Content)
+			return val
+		}
+		val, _ := v8.NewValue(iso, string(files[i].Content))
+		return val
+	})
+
+	_, err = define.Call(v8.Undefined(iso), ns, wrapResult(iso, ctx, metas), readFn.GetFunction(ctx))
+	return err
+}
+
+// filesJS provides __define_files(ns, metas, read), used by InjectFiles.
+const filesJS = `(function() {
+	class InputFile {
+		#index;
+		#read;
+		constructor(meta, index, read) {
+			this.id = meta.id;
+			this.name = meta.name;
+			this.mimeType = meta.mimeType;
+			this.size = meta.size;
+			this.#index = index;
+			this.#read = read;
+			Object.freeze(this);
+		}
+		text() {
+			return this.#read(this.#index, false);
+		}
+		bytes() {
+			return this.#read(this.#index, true);
+		}
+		json() {
+			return JSON.parse(this.text());
+		}
+	}
+
+	globalThis.__define_files = function(ns, metas, read) {
+		const files = Object.create(null);
+		metas.forEach((meta, i) => { files[meta.alias] = new InputFile(meta, i, read); });
+		Object.defineProperty(ns, 'files', { value: Object.freeze(files), enumerable: true });
+	};
+})();
+`
+
+// filesDeclarations declares the members of InjectFiles.
+const filesDeclarations = `/** An artifact of the inputArtifacts parameter. */
+interface WollmilchsauInputFile {
+  readonly id: string;
+  readonly name: string;
+  readonly mimeType: string;
+  readonly size: number;
+  text(): string;
+  bytes(): Uint8Array;
+  json(): any;
+}
+interface Wollmilchsau {
+  /** The input artifacts by alias. */
+  readonly files: { readonly [alias: string]: WollmilchsauInputFile };
+}
+`
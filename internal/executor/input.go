@@ -0,0 +1,92 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"errors"
+
+	v8 "rogchap.com/v8go"
+)
+
+// Input is data passed to a script next to its code.
+type Input struct {
+	Text string // raw input, available as wollmilchsau.inputText
+	JSON bool   // Text is a JSON document; wollmilchsau.input is the parsed value
+}
+
+// InjectInput defines the read-only properties wollmilchsau.input and
+// wollmilchsau.inputText for a single execution. With a nil input both are
+// undefined.
+//
+// wollmilchsau.input is parsed on first access and deeply frozen, so scripts
+// that only need the text do not pay for parsing.
+func InjectInput(iso *v8.Isolate, ctx *v8.Context, in *Input) error {
+	global := ctx.Global()
+	defineVal, err := global.Get("__define_input")
+	if err != nil {
+		return err
+	}
+	if !defineVal.IsFunction() {
+		return errors.New("input prelude not loaded")
+	}
+	define, _ := defineVal.AsFunction()
+	// The helper is only needed once per context.
+	global.Delete("__define_input")
+
+	ns, err := namespaceObject(iso, ctx)
+	if err != nil {
+		return err
+	}
+
+	var text v8.Valuer = v8.Undefined(iso)
+	isJSON := false
+	if in != nil {
+		if text, err = v8.NewValue(iso, in.Text); err != nil {
+			return err
+		}
+		isJSON = in.JSON
+	}
+	jsonFlag, _ := v8.NewValue(iso, isJSON)
+
+	_, err = define.Call(v8.Undefined(iso), ns, text, jsonFlag)
+	return err
+}
+
+// inputJS provides __define_input(ns, text, isJSON), used by InjectInput.
+const inputJS = `(function() {
+	function deepFreeze(root) {
+		const stack = [root];
+		while (stack.length > 0) {
+			const v = stack.pop();
+			if (v === null || typeof v !== 'object' || Object.isFrozen(v)) continue;
+			Object.freeze(v);
+			for (const k of Object.keys(v)) stack.push(v[k]);
+		}
+		return root;
+	}
+
+	globalThis.__define_input = function(ns, text, isJSON) {
+		let parsed;
+		let done = false;
+		Object.defineProperty(ns, 'inputText', { value: text, enumerable: true });
+		Object.defineProperty(ns, 'input', {
+			enumerable: true,
+			get() {
+				if (!done) {
+					parsed = deepFreeze(isJSON ? JSON.parse(text) : text);
+					done = true;
+				}
+				return parsed;
+			},
+		});
+	};
+})();
+`
+
+// inputDeclarations declares the members of InjectInput.
+const inputDeclarations = `interface Wollmilchsau {
+  /** The input parameter, deeply frozen: the JSON value, or the text of a string input; undefined without input. */
+  readonly input: any;
+  /** The input parameter as raw text; undefined without input. */
+  readonly inputText: string | undefined;
+}
+`
@@ -0,0 +1,119 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecute_Input(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name  string
+		input *Input
+		code  string
+		want  string
+	}{
+		{
+			name:  "json",
+			input: &Input{Text: `{"items":[1,2,3]}`, JSON: true},
+			code:  `console.log(wollmilchsau.input.items.length, wollmilchsau.inputText);`,
+			want:  "3 {\"items\":[1,2,3]}\n",
+		},
+		{
+			name:  "raw text",
+			input: &Input{Text: "a,b\n1,2"},
+			code:  `console.log(wollmilchsau.input === wollmilchsau.inputText, wollmilchsau.input.split('\n').length);`,
+			want:  "true 2\n",
+		},
+		{
+			name: "no input",
+			code: `console.log(wollmilchsau.input, wollmilchsau.inputText);`,
+			want: "undefined undefined\n",
+		},
+		{
+			name:  "read-only",
+			input: &Input{Text: `{"a":{"b":1}}`, JSON: true},
+			code: `
+				'use strict';
+				const errors = [];
+				try { wollmilchsau.input.a.b = 2; } catch (e) { errors.push(e.name); }
+				try { wollmilchsau.input = {}; } catch (e) { errors.push(e.name); }
+				try { wollmilchsau.inputText = ''; } catch (e) { errors.push(e.name); }
+				console.log(errors.join(','), wollmilchsau.input.a.b, typeof __define_input);
+			`,
+			want: "TypeError,TypeError,TypeError 1 undefined\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := Execute(ctx, tt.code, "test.js", nil, Options{Input: tt.input})
+			if !res.Success {
+				t.Fatalf("Execution failed: %s", res.Summary)
+			}
+			if res.Stdout != tt.want {
+				t.Errorf("stdout = %q, want %q", res.Stdout, tt.want)
+			}
+		})
+	}
+
+	t.Run("size limit", func(t *testing.T) {
+		in := &Input{Text: strings.Repeat("x", 2048)}
+		res := Execute(ctx, `console.log('ran');`, "test.js", nil, Options{Input: in, Limits: Limits{MaxInputBytes: 1024}})
+		if res.Success || res.Stdout != "" || !strings.Contains(res.Summary, "Input too large") {
+			t.Errorf("expected input to be rejected, got %+v", res)
+		}
+	})
+}
+
+func TestExecute_InputFiles(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	files := []InputFile{
+		{Alias: "data", ID: "a1", Name: "data.json", MimeType: "application/json", Content: []byte(`{"n":42}`)},
+		{Alias: "blob", ID: "a2", Name: "blob.bin", MimeType: "application/octet-stream", Content: []byte{0, 127, 128, 255}},
+		{Alias: "text", ID: "a3", Name: "grüße.txt", MimeType: "text/plain", Content: []byte("grüße")},
+	}
+
+	code := `
+		'use strict';
+		const { data, blob, text } = wollmilchsau.files;
+		console.log(Object.keys(wollmilchsau.files).join(','));
+		console.log(data.id, data.name, data.mimeType, data.size, data.json().n);
+		console.log(Array.from(blob.bytes()).join(','), blob.bytes() instanceof Uint8Array);
+		console.log(text.text(), text.size);
+		try { wollmilchsau.files.other = data; } catch (e) { console.log(e.name); }
+	`
+	res := Execute(ctx, code, "test.js", nil, Options{Files: files})
+	if !res.Success {
+		t.Fatalf("Execution failed: %s", res.Summary)
+	}
+	want := "data,blob,text\n" +
+		"a1 data.json application/json 8 42\n" +
+		"0,127,128,255 true\n" +
+		"grüße 7\n" +
+		"TypeError\n"
+	if res.Stdout != want {
+		t.Errorf("stdout = %q, want %q", res.Stdout, want)
+	}
+
+	t.Run("no files", func(t *testing.T) {
+		res := Execute(ctx, `console.log(Object.keys(wollmilchsau.files).length);`, "test.js", nil, Options{})
+		if res.Stdout != "0\n" {
+			t.Errorf("stdout = %q, want %q", res.Stdout, "0\n")
+		}
+	})
+
+	t.Run("size limit", func(t *testing.T) {
+		res := Execute(ctx, `console.log('ran');`, "test.js", nil, Options{Files: files, Limits: Limits{MaxInputBytes: 8}})
+		if res.Success || !strings.Contains(res.Summary, "Input too large: 19 bytes") {
+			t.Errorf("expected input files to be rejected, got %+v", res)
+		}
+	})
+}
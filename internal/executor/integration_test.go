@@ -41,11 +41,12 @@ func TestArtifactIntegration(t *testing.T) {
 		content := "Real artifact content " + time.Now().Format(time.RFC3339)
 
 		// 1. Write via JS
-		jsWrite := `JSON.stringify(artifact.write("` + filename + `", "` + content + `", "text/plain"))`
+		jsWrite := `artifact.write("` + filename + `", "` + content + `", "text/plain").then(JSON.stringify)`
 		val, err := v8ctx.RunScript(jsWrite, "test_write.js")
 		if err != nil {
 			t.Fatalf("JS Write failed: %v", err)
 		}
+		val = settled(t, v8ctx, val)
 		t.Logf("JS Write result: %s", val.String())
 
 		// Parse ID from result
@@ -86,26 +87,23 @@ func TestArtifactIntegration(t *testing.T) {
 		userID := "test_user_123"
 
 		// 1. Write via JS with UserID (6th argument)
-		jsWrite := `JSON.stringify(artifact.write("` + filename + `", "` + content + `", "text/plain", 1, "test desc", "` + userID + `"))`
+		jsWrite := `artifact.write("` + filename + `", "` + content + `", "text/plain", 1, "test desc", "` + userID + `").then(JSON.stringify)`
 		val, err := v8ctx.RunScript(jsWrite, "test_user_write.js")
 		if err != nil {
 			t.Fatalf("JS Write failed: %v", err)
 		}
 
+		// A failed write rejects the promise, which settled reports.
 		var writeRes struct {
-			ID    string `json:"id"`
-			Error string `json:"error"`
+			ID string `json:"id"`
 		}
-		if err := wrapResultToStruct(v8ctx, val, &writeRes); err != nil {
+		if err := wrapResultToStruct(v8ctx, settled(t, v8ctx, val), &writeRes); err != nil {
 			t.Fatalf("Failed to parse write result: %v", err)
 		}
 
-		if writeRes.Error != "" {
-			t.Fatalf("Artifact write error: %s", writeRes.Error)
-		}
-
 		// 2. Read via JS with UserID (2nd argument)
-		jsRead := `JSON.stringify(artifact.read("` + writeRes.ID + `", "` + userID + `"))`
+		jsRead := `artifact.read("` + writeRes.ID + `", "` + userID + `").then((res) =>
+			JSON.stringify({ ...res, content: String.fromCharCode(...res.content) }))`
 		readVal, err := v8ctx.RunScript(jsRead, "test_user_read.js")
 		if err != nil {
 			t.Fatalf("JS Read failed: %v", err)
@@ -113,16 +111,13 @@ func TestArtifactIntegration(t *testing.T) {
 
 		var readRes struct {
 			Content string `json:"content"`
-			Error   string `json:"error"`
 		}
-		// Note: content comes as base64 in JSON result if using our wrapResult (which uses json.Marshal on pb.ReadResponse)
-		// Actually pb.ReadResponse.Content is []byte, which Marshals to base64.
-		if err := wrapResultToStruct(v8ctx, readVal, &readRes); err != nil {
+		// The content is a Uint8Array; the script above turns it back into a string.
+		if err := wrapResultToStruct(v8ctx, settled(t, v8ctx, readVal), &readRes); err != nil {
 			t.Fatalf("Failed to parse read result: %v", err)
 		}
-
-		if readRes.Error != "" {
-			t.Fatalf("Artifact read error: %s", readRes.Error)
+		if readRes.Content != content {
+			t.Errorf("Content mismatch. Expected %q, got %q", content, readRes.Content)
 		}
 
 		// 3. Cleanup
@@ -131,8 +126,6 @@ func TestArtifactIntegration(t *testing.T) {
 }
 
 func wrapResultToStruct(ctx *v8.Context, v *v8.Value, target any) error {
-	// Our JS callbacks in artifacts.go currently return JSON.stringify results
-	// but wrapResult actually returns a parsed JSON object.
-	// However, if the JS returns JSON.stringify(res), then v.String() IS the JSON.
+	// The test scripts resolve to JSON.stringify(res), so v.String() IS the JSON.
 	return json.Unmarshal([]byte(v.String()), target)
 }
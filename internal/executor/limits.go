@@ -0,0 +1,155 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"fmt"
+
+	v8 "rogchap.com/v8go"
+)
+
+// Exit codes reported in Result.ExitCode. Each resource limit has its own code
+// so that callers can tell them apart without parsing the summary.
+const (
+	ExitCodeSuccess      = 0
+	ExitCodeRuntimeError = 1
+	ExitCodeTimeout      = 124 // wall time limit exceeded (like timeout(1))
+	ExitCodeStackLimit   = 134 // maximum call stack size exceeded
+	ExitCodeMemoryLimit  = 137 // heap limit exceeded (like the OOM killer)
+	ExitCodeCPULimit     = 152 // CPU time limit exceeded (128 + SIGXCPU)
+	ExitCodeOutputLimit  = 153 // stdout/stderr limit exceeded (128 + SIGXFSZ)
+)
+
+// Limits bounds the resources a single execution may use.
+//
+// The operator configures the upper bounds (CLI flags / config file); a tool
+// call may only tighten them, see Tighten. A zero value means "not set".
+type Limits struct {
+	MaxHeapBytes   uint64 `json:"maxHeapBytes,omitempty"`   // V8 heap allocated by one execution
+	MaxStackKB     int    `json:"maxStackKb,omitempty"`     // V8 stack size; bounds the maximum call depth (process-wide, server only)
+	MaxStdoutBytes int    `json:"maxStdoutBytes,omitempty"` // captured console.log/info output
+	MaxStderrBytes int    `json:"maxStderrBytes,omitempty"` // captured console.warn/error output
+	MaxWallTimeMs  int    `json:"maxWallTimeMs,omitempty"`  // total run time including waits for timers
+	MaxCPUTimeMs   int    `json:"maxCpuTimeMs,omitempty"`   // time spent actively executing JavaScript
+	MaxInputBytes  int    `json:"maxInputBytes,omitempty"`  // total size of the input and the input files
+
+	// MaxArtifactBytes bounds the content written to one openArtifact handle.
+	MaxArtifactBytes int `json:"maxArtifactBytes,omitempty"`
+
+	// Output budget per stream. Output beyond the budget is counted but not
+	// returned; unlike MaxStdoutBytes/MaxStderrBytes it does not stop the script.
+	// OutputBudgetBytes also bounds the serialized return value.
+	OutputBudgetBytes int `json:"outputBudgetBytes,omitempty"`
+	OutputBudgetLines int `json:"outputBudgetLines,omitempty"`
+}
+
+// DefaultLimits returns the limits used when the operator configures nothing.
+func DefaultLimits() Limits {
+	return Limits{
+		MaxHeapBytes:   defaultMaxMemoryBytes,
+		MaxStackKB:     984, // V8's default on 64-bit platforms
+		MaxStdoutBytes: 4 * 1024 * 1024,
+		MaxStderrBytes: 1024 * 1024,
+		MaxWallTimeMs:  30_000,
+		MaxCPUTimeMs:   30_000,
+		MaxInputBytes:  8 * 1024 * 1024,
+
+		MaxArtifactBytes: 64 * 1024 * 1024,
+
+		OutputBudgetBytes: 64 * 1024,
+		OutputBudgetLines: 2000,
+	}
+}
+
+// WithDefaults fills every unset field with the corresponding default.
+func (l Limits) WithDefaults() Limits {
+	d := DefaultLimits()
+	if l.MaxHeapBytes == 0 {
+		l.MaxHeapBytes = d.MaxHeapBytes
+	}
+	if l.MaxStackKB == 0 {
+		l.MaxStackKB = d.MaxStackKB
+	}
+	if l.MaxStdoutBytes == 0 {
+		l.MaxStdoutBytes = d.MaxStdoutBytes
+	}
+	if l.MaxStderrBytes == 0 {
+		l.MaxStderrBytes = d.MaxStderrBytes
+	}
+	if l.MaxWallTimeMs == 0 {
+		l.MaxWallTimeMs = d.MaxWallTimeMs
+	}
+	if l.MaxCPUTimeMs == 0 {
+		l.MaxCPUTimeMs = d.MaxCPUTimeMs
+	}
+	if l.MaxInputBytes == 0 {
+		l.MaxInputBytes = d.MaxInputBytes
+	}
+	if l.MaxArtifactBytes == 0 {
+		l.MaxArtifactBytes = d.MaxArtifactBytes
+	}
+	if l.OutputBudgetBytes == 0 {
+		l.OutputBudgetBytes = d.OutputBudgetBytes
+	}
+	if l.OutputBudgetLines == 0 {
+		l.OutputBudgetLines = d.OutputBudgetLines
+	}
+	return l
+}
+
+// Tighten returns the limits in l lowered by every field set in req.
+// Fields of req that would loosen a limit are ignored. The stack size is
+// process-wide and cannot be changed per execution.
+func (l Limits) Tighten(req Limits) Limits {
+	if req.MaxHeapBytes > 0 && req.MaxHeapBytes < l.MaxHeapBytes {
+		l.MaxHeapBytes = req.MaxHeapBytes
+	}
+	if req.MaxStdoutBytes > 0 && req.MaxStdoutBytes < l.MaxStdoutBytes {
+		l.MaxStdoutBytes = req.MaxStdoutBytes
+	}
+	if req.MaxStderrBytes > 0 && req.MaxStderrBytes < l.MaxStderrBytes {
+		l.MaxStderrBytes = req.MaxStderrBytes
+	}
+	if req.MaxWallTimeMs > 0 && req.MaxWallTimeMs < l.MaxWallTimeMs {
+		l.MaxWallTimeMs = req.MaxWallTimeMs
+	}
+	if req.MaxCPUTimeMs > 0 && req.MaxCPUTimeMs < l.MaxCPUTimeMs {
+		l.MaxCPUTimeMs = req.MaxCPUTimeMs
+	}
+	if req.MaxInputBytes > 0 && req.MaxInputBytes < l.MaxInputBytes {
+		l.MaxInputBytes = req.MaxInputBytes
+	}
+	if req.MaxArtifactBytes > 0 && req.MaxArtifactBytes < l.MaxArtifactBytes {
+		l.MaxArtifactBytes = req.MaxArtifactBytes
+	}
+	if req.OutputBudgetBytes > 0 && req.OutputBudgetBytes < l.OutputBudgetBytes {
+		l.OutputBudgetBytes = req.OutputBudgetBytes
+	}
+	if req.OutputBudgetLines > 0 && req.OutputBudgetLines < l.OutputBudgetLines {
+		l.OutputBudgetLines = req.OutputBudgetLines
+	}
+	return l
+}
+
+// Validate rejects limits that cannot be enforced safely.
+func (l Limits) Validate() error {
+	if l.MaxHeapBytes != 0 && l.MaxHeapBytes < 1024*1024 {
+		return fmt.Errorf("max heap must be at least 1 MB, got %d bytes", l.MaxHeapBytes)
+	}
+	// The V8 stack must fit into the native thread stack (8 MB by default).
+	if l.MaxStackKB != 0 && (l.MaxStackKB < 64 || l.MaxStackKB > 4096) {
+		return fmt.Errorf("max stack size must be between 64 and 4096 KB, got %d", l.MaxStackKB)
+	}
+	if l.MaxStdoutBytes < 0 || l.MaxStderrBytes < 0 || l.MaxWallTimeMs < 0 || l.MaxCPUTimeMs < 0 || l.MaxInputBytes < 0 ||
+		l.MaxArtifactBytes < 0 || l.OutputBudgetBytes < 0 || l.OutputBudgetLines < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	return nil
+}
+
+// ApplyProcessLimits applies the limits that V8 only supports process-wide.
+// It must be called before the first isolate is created.
+func ApplyProcessLimits(l Limits) {
+	if l.MaxStackKB > 0 {
+		v8.SetFlags(fmt.Sprintf("--stack-size=%d", l.MaxStackKB))
+	}
+}
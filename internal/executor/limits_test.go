@@ -0,0 +1,105 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimits_Tighten(t *testing.T) {
+	op := DefaultLimits()
+
+	got := op.Tighten(Limits{
+		MaxHeapBytes:   op.MaxHeapBytes * 2, // loosening is ignored
+		MaxStackKB:     op.MaxStackKB / 2,   // process-wide, ignored
+		MaxStdoutBytes: 100,
+		MaxWallTimeMs:  op.MaxWallTimeMs + 1,
+		MaxCPUTimeMs:   50,
+	})
+
+	want := op
+	want.MaxStdoutBytes = 100
+	want.MaxCPUTimeMs = 50
+	if got != want {
+		t.Errorf("Tighten() = %+v, want %+v", got, want)
+	}
+}
+
+func TestExecute_Limits(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		limits   Limits
+		exitCode int
+	}{
+		{
+			name:     "wall time while waiting for a timer",
+			code:     `setTimeout(() => {}, 5000);`,
+			limits:   Limits{MaxWallTimeMs: 100},
+			exitCode: ExitCodeTimeout,
+		},
+		{
+			name:     "cpu time",
+			code:     `for (;;) {}`,
+			limits:   Limits{MaxCPUTimeMs: 50, MaxWallTimeMs: 5000},
+			exitCode: ExitCodeCPULimit,
+		},
+		{
+			name:     "heap",
+			code:     `const a = []; for (;;) a.push(new Array(10000).fill(1));`,
+			limits:   Limits{MaxHeapBytes: 16 * 1024 * 1024},
+			exitCode: ExitCodeMemoryLimit,
+		},
+		{
+			name:     "stdout",
+			code:     `for (;;) console.log('spam');`,
+			limits:   Limits{MaxStdoutBytes: 100},
+			exitCode: ExitCodeOutputLimit,
+		},
+		{
+			name:     "stack",
+			code:     `function f(n) { return f(n + 1) + 1; } f(0);`,
+			exitCode: ExitCodeStackLimit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			res := Execute(ctx, tt.code, "test.js", nil, Options{Limits: tt.limits})
+			if res.Success || res.ExitCode != tt.exitCode {
+				t.Errorf("expected exit code %d, got %d (%s)", tt.exitCode, res.ExitCode, res.Summary)
+			}
+		})
+	}
+}
+
+func TestExecute_OutputBudget(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	code := `for (let i = 0; i < 1000; i++) console.log('line ' + i); console.error('oops');`
+	res := Execute(ctx, code, "test.js", nil, Options{Limits: Limits{OutputBudgetLines: 10}})
+	if !res.Success {
+		t.Fatalf("expected the script to keep running past the budget, got: %s", res.Summary)
+	}
+	if !res.Truncated {
+		t.Error("expected Truncated to be set")
+	}
+	if !strings.HasPrefix(res.Stdout, "line 0\n") || !strings.Contains(res.Stdout, "line 9\n") || strings.Contains(res.Stdout, "line 10\n") {
+		t.Errorf("expected the first 10 lines, got: %q", res.Stdout)
+	}
+	if !strings.Contains(res.Stdout, "[output truncated: showing 70 of 8890 bytes, 10 of 1000 lines]") {
+		t.Errorf("expected truncation marker, got: %q", res.Stdout)
+	}
+	if res.StdoutBytes != 8890 {
+		t.Errorf("expected original stdout size 8890, got %d", res.StdoutBytes)
+	}
+	if res.Stderr != "oops\n" {
+		t.Errorf("expected stderr within budget to be untouched, got: %q", res.Stderr)
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	v8 "rogchap.com/v8go"
+)
+
+// namespaceObject returns the global `wollmilchsau` object that holds the
+// sandbox-specific APIs, creating it on first use.
+func namespaceObject(iso *v8.Isolate, v8ctx *v8.Context) (*v8.Object, error) {
+	global := v8ctx.Global()
+	val, err := global.Get("wollmilchsau")
+	if err == nil && val.IsObject() {
+		return val.Object(), nil
+	}
+	inst, err := v8.NewObjectTemplate(iso).NewInstance(v8ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := global.Set("wollmilchsau", inst); err != nil {
+		return nil, err
+	}
+	return inst, nil
+}
+
+// namespaceDeclarations declares the namespace object; the injections add
+// their members to the Wollmilchsau interface.
+const namespaceDeclarations = `/** Sandbox-specific APIs. */
+interface Wollmilchsau {}
+declare var wollmilchsau: Wollmilchsau;
+`
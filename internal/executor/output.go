@@ -0,0 +1,127 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// outputBuffer captures one console stream (stdout or stderr).
+//
+// Everything written is counted, but only the part within the budget is
+// returned by visible(). If keepAll is set, output beyond the budget is kept
+// as well (bounded by the hard output limit) so it can be spilled to an
+// artifact; otherwise it is dropped right away.
+type outputBuffer struct {
+	budgetBytes int
+	budgetLines int
+	keepAll     bool
+
+	buf        strings.Builder
+	keptLines  int
+	totalBytes int
+	totalLines int
+}
+
+// reset prepares the buffer for a new execution.
+func (b *outputBuffer) reset(budgetBytes, budgetLines int, keepAll bool) {
+	*b = outputBuffer{budgetBytes: budgetBytes, budgetLines: budgetLines, keepAll: keepAll}
+}
+
+// writeLine appends s followed by a newline.
+func (b *outputBuffer) writeLine(s string) {
+	lines := strings.Count(s, "\n") + 1
+	b.totalBytes += len(s) + 1
+	b.totalLines += lines
+
+	if b.keepAll || (b.buf.Len() < b.budgetBytes && b.keptLines < b.budgetLines) {
+		b.buf.WriteString(s)
+		b.buf.WriteByte('\n')
+		b.keptLines += lines
+	}
+}
+
+// truncated reports whether the output exceeded the budget.
+func (b *outputBuffer) truncated() bool {
+	return b.totalBytes > b.budgetBytes || b.totalLines > b.budgetLines
+}
+
+// full returns everything that was kept.
+func (b *outputBuffer) full() string {
+	return b.buf.String()
+}
+
+// visible returns the output cut to the budget. If it was truncated, a marker
+// with the original size is appended; spillURI is mentioned if set.
+func (b *outputBuffer) visible(spillURI string) string {
+	s := b.buf.String()
+	if !b.truncated() {
+		return s
+	}
+
+	cut := min(len(s), b.budgetBytes)
+	if idx := nthIndex(s, '\n', b.budgetLines); idx >= 0 && idx+1 < cut {
+		cut = idx + 1
+	}
+	for cut > 0 && cut < len(s) && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	shown := s[:cut]
+
+	marker := fmt.Sprintf("showing %d of %d bytes, %d of %d lines",
+		len(shown), b.totalBytes, strings.Count(shown, "\n"), b.totalLines)
+	if spillURI != "" {
+		marker += "; full output saved as " + spillURI
+	}
+	if shown != "" && !strings.HasSuffix(shown, "\n") {
+		shown += "\n"
+	}
+	return shown + "... [output truncated: " + marker + "]\n"
+}
+
+// spillOutput uploads content as an artifact, registers it in
+// res.CreatedArtifacts and returns its URI. It returns "" on failure.
+func spillOutput(store ArtifactStore, name, mimeType string, content []byte, res *Result) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	info, err := store.Write(ctx, ArtifactWrite{
+		Filename: name,
+		Content:  content,
+		MimeType: mimeType,
+		Source:   "wollmilchsau",
+	})
+	if err != nil {
+		slog.Error("failed to spill truncated output", "error", err, "filename", name)
+		return ""
+	}
+
+	res.CreatedArtifacts = append(res.CreatedArtifacts, ArtifactRef{
+		ID:       info.ID,
+		URI:      info.URI,
+		Name:     info.Filename,
+		MimeType: mimeType,
+		FileSize: int64(len(content)),
+	})
+	return info.URI
+}
+
+// nthIndex returns the index of the n-th occurrence of c in s, or -1.
+func nthIndex(s string, c byte, n int) int {
+	if n <= 0 {
+		return -1
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == c {
+			n--
+			if n == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -25,7 +25,7 @@ func InjectPolyfills(iso *v8.Isolate, ctx *v8.Context) error {
 	perfInst, _ := perfObj.NewInstance(ctx)
 	_ = global.Set("performance", perfInst)
 
-	// 2. Base64 (atob / btoa)
+	// 2. Base64 (atob / btoa) on binary strings, one char per byte
 	atobFn := v8.NewFunctionTemplate(iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
 		if len(info.Args()) < 1 {
 			return v8.Undefined(iso)
@@ -34,15 +34,18 @@ func InjectPolyfills(iso *v8.Isolate, ctx *v8.Context) error {
 		if err != nil {
 			return v8.Undefined(iso)
 		}
-		val, _ := v8.NewValue(iso, string(data))
+		val, _ := v8.NewValue(iso, binaryString(data))
 		return val
 	})
 	btoaFn := v8.NewFunctionTemplate(iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
 		if len(info.Args()) < 1 {
 			return v8.Undefined(iso)
 		}
-		str := base64.StdEncoding.EncodeToString([]byte(info.Args()[0].String()))
-		val, _ := v8.NewValue(iso, str)
+		data, err := binaryStringBytes(info.Args()[0].String())
+		if err != nil {
+			return v8.Undefined(iso)
+		}
+		val, _ := v8.NewValue(iso, base64.StdEncoding.EncodeToString(data))
 		return val
 	})
 	_ = global.Set("atob", atobFn.GetFunction(ctx))
@@ -102,53 +105,83 @@ func InjectPolyfills(iso *v8.Isolate, ctx *v8.Context) error {
 	_ = global.Set("__decode_utf8", decodeUtf8.GetFunction(ctx))
 
 	// 5. JS-side Polyfills (Crypto, TextEncoder, Buffer)
-	_, err := ctx.RunScript(`
-		(function() {
-			// Helper to convert b64 to Uint8Array
-			function b64ToUint8(b64) {
-				const s = atob(b64);
-				const b = new Uint8Array(s.length);
-				for (let i = 0; i < s.length; i++) b[i] = s.charCodeAt(i);
-				return b;
+	return runPrelude(ctx, "polyfills.js", polyfillsJS)
+}
+
+// polyfillsJS is the JS side of the polyfills (crypto, TextEncoder/TextDecoder,
+// Buffer). It relies on the Go bridge functions installed by InjectPolyfills.
+const polyfillsJS = `(function() {
+	// Helper to convert b64 to Uint8Array
+	function b64ToUint8(b64) {
+		const s = atob(b64);
+		const b = new Uint8Array(s.length);
+		for (let i = 0; i < s.length; i++) b[i] = s.charCodeAt(i);
+		return b;
+	}
+
+	// Crypto
+	globalThis.crypto = {
+		getRandomValues: function(array) {
+			const bytes = b64ToUint8(__get_random_b64(array.byteLength));
+			const view = new Uint8Array(array.buffer, array.byteOffset, array.byteLength);
+			for (let i = 0; i < bytes.length; i++) view[i] = bytes[i];
+			return array;
+		}
+	};
+
+	// TextEncoder / TextDecoder using the Go bridge
+	globalThis.TextEncoder = class {
+		encode(str) {
+			const arr = __encode_utf8(str);
+			return new Uint8Array(Object.values(arr));
+		}
+	};
+	globalThis.TextDecoder = class {
+		decode(buf) {
+			const arr = Array.from(new Uint8Array(buf));
+			return __decode_utf8(arr);
+		}
+	};
+
+	// Minimal Buffer
+	globalThis.Buffer = {
+		from: function(data, encoding) {
+			if (typeof data === 'string') {
+				if (encoding === 'base64') return b64ToUint8(data);
+				return new TextEncoder().encode(data);
 			}
+			return new Uint8Array(data);
+		},
+		alloc: (size) => new Uint8Array(size)
+	};
+})();
+`
+
+// polyfillsDeclarations declares the globals of InjectPolyfills.
+const polyfillsDeclarations = `declare var performance: {
+  now(): number;
+};
 
-			// Crypto
-			globalThis.crypto = {
-				getRandomValues: function(array) {
-					const bytes = b64ToUint8(__get_random_b64(array.byteLength));
-					const view = new Uint8Array(array.buffer, array.byteOffset, array.byteLength);
-					for (let i = 0; i < bytes.length; i++) view[i] = bytes[i];
-					return array;
-				}
-			};
-
-			// TextEncoder / TextDecoder using the Go bridge
-			globalThis.TextEncoder = class {
-				encode(str) {
-					const arr = __encode_utf8(str);
-					return new Uint8Array(Object.values(arr));
-				}
-			};
-			globalThis.TextDecoder = class {
-				decode(buf) {
-					const arr = Array.from(new Uint8Array(buf));
-					return __decode_utf8(arr);
-				}
-			};
-
-			// Minimal Buffer
-			globalThis.Buffer = {
-				from: function(data, encoding) {
-					if (typeof data === 'string') {
-						if (encoding === 'base64') return b64ToUint8(data);
-						return new TextEncoder().encode(data);
-					}
-					return new Uint8Array(data);
-				},
-				alloc: (size) => new Uint8Array(size)
-			};
-		})();
-	`, "polyfills.js")
-
-	return err
+/** Decodes base64 into a binary string with one character per byte. */
+declare function atob(data: string): string;
+/** Encodes a binary string with one character per byte as base64. */
+declare function btoa(data: string): string;
+
+declare var crypto: {
+  getRandomValues<T extends ArrayBufferView>(array: T): T;
+};
+
+declare class TextEncoder {
+  encode(input?: string): Uint8Array;
 }
+declare class TextDecoder {
+  decode(input?: ArrayBuffer | ArrayBufferView): string;
+}
+
+/** A minimal Buffer; its values are plain Uint8Arrays. */
+declare var Buffer: {
+  from(data: string, encoding?: "utf8" | "utf-8" | "base64"): Uint8Array;
+  from(data: ArrayLike<number> | ArrayBuffer): Uint8Array;
+  alloc(size: number): Uint8Array;
+};
+`
@@ -0,0 +1,164 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hmsoft0815/wollmilchsau/internal/sourcemap"
+	v8 "rogchap.com/v8go"
+)
+
+// maxIsolateUses bounds how often a single isolate is reused. v8go keeps every
+// function template callback registered until the isolate is disposed, so an
+// isolate is recycled after this many executions to keep its footprint flat.
+const maxIsolateUses = 100
+
+// PoolStats is a snapshot of the pool's metrics.
+type PoolStats struct {
+	Size        int     `json:"size"`        // configured number of warm sandboxes
+	Ready       int     `json:"ready"`       // sandboxes currently waiting in the pool
+	Hits        int64   `json:"hits"`        // executions served by a warm sandbox
+	Misses      int64   `json:"misses"`      // executions that had to create a sandbox on demand
+	HitRate     float64 `json:"hitRate"`     // Hits / (Hits + Misses)
+	TimeSavedMs int64   `json:"timeSavedMs"` // accumulated setup time avoided by hits
+}
+
+// Pool keeps a bounded number of isolates with pre-initialized contexts ready
+// for execution. Every execution gets a brand-new context; after a run the
+// context is discarded and a new one is prepared in the background, so no
+// globals leak between executions.
+type Pool struct {
+	size  int
+	ready chan *pooledSandbox
+
+	mu     sync.Mutex
+	closed bool
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	timeSaved atomic.Int64 // nanoseconds
+}
+
+// pooledSandbox tracks how often the underlying isolate has been used.
+type pooledSandbox struct {
+	*sandbox
+	uses int
+}
+
+// NewPool creates a pool with size warm sandboxes. Warm-up happens in the
+// background. A size <= 0 disables pooling: every execution uses a fresh isolate.
+func NewPool(size int) *Pool {
+	if size < 0 {
+		size = 0
+	}
+	p := &Pool{
+		size:  size,
+		ready: make(chan *pooledSandbox, size),
+	}
+	go func() {
+		for i := 0; i < size; i++ {
+			p.put(&pooledSandbox{sandbox: newSandbox(v8.NewIsolate())})
+		}
+	}()
+	return p
+}
+
+// Execute runs js like Execute, but on a warm sandbox from the pool if one is available.
+func (p *Pool) Execute(ctx context.Context, js string, filename string, sm *sourcemap.SourceMap, opts Options) *Result {
+	sb := p.acquire()
+	res := sb.run(ctx, js, filename, sm, opts)
+	go p.release(sb)
+	return res
+}
+
+// Stats returns a snapshot of the pool metrics.
+func (p *Pool) Stats() PoolStats {
+	hits, misses := p.hits.Load(), p.misses.Load()
+	st := PoolStats{
+		Size:        p.size,
+		Ready:       len(p.ready),
+		Hits:        hits,
+		Misses:      misses,
+		TimeSavedMs: time.Duration(p.timeSaved.Load()).Milliseconds(),
+	}
+	if total := hits + misses; total > 0 {
+		st.HitRate = float64(hits) / float64(total)
+	}
+	return st
+}
+
+// Close disposes all idle isolates. Sandboxes still in use are disposed when released.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	for {
+		select {
+		case sb := <-p.ready:
+			sb.dispose()
+		default:
+			return
+		}
+	}
+}
+
+func (p *Pool) acquire() *pooledSandbox {
+	select {
+	case sb := <-p.ready:
+		p.hits.Add(1)
+		p.timeSaved.Add(int64(sb.warmup))
+		return sb
+	default:
+		p.misses.Add(1)
+		return &pooledSandbox{sandbox: newSandbox(v8.NewIsolate())}
+	}
+}
+
+// release discards the used context and prepares a new one. The isolate is
+// reused unless it was terminated or has reached maxIsolateUses.
+func (p *Pool) release(sb *pooledSandbox) {
+	sb.close()
+	sb.uses++
+
+	p.mu.Lock()
+	full := p.closed || len(p.ready) >= p.size
+	p.mu.Unlock()
+	if full {
+		sb.iso.Dispose()
+		return
+	}
+
+	iso := sb.iso
+	if sb.tainted || sb.uses >= maxIsolateUses {
+		iso.Dispose()
+		iso = v8.NewIsolate()
+		sb.uses = 0
+	}
+
+	p.put(&pooledSandbox{sandbox: newSandbox(iso), uses: sb.uses})
+}
+
+// put adds a warm sandbox to the pool, or disposes it if the pool is full or closed.
+func (p *Pool) put(sb *pooledSandbox) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed {
+		select {
+		case p.ready <- sb:
+			return
+		default:
+		}
+	}
+	sb.dispose()
+}
+
+func (sb *pooledSandbox) dispose() {
+	sb.close()
+	sb.iso.Dispose()
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func waitForReady(t *testing.T, p *Pool, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for p.Stats().Ready < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("pool did not warm up: %+v", p.Stats())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPool_NoLeakedGlobals(t *testing.T) {
+	p := NewPool(1)
+	defer p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	waitForReady(t, p, 1)
+	res := p.Execute(ctx, `globalThis.leaked = 42; console.log('first');`, "test.js", nil, Options{})
+	if !res.Success {
+		t.Fatalf("first run failed: %s", res.Summary)
+	}
+
+	waitForReady(t, p, 1)
+	res = p.Execute(ctx, `console.log('leaked:', typeof leaked);`, "test.js", nil, Options{})
+	if !strings.Contains(res.Stdout, "leaked: undefined") {
+		t.Errorf("global leaked between executions: %q", res.Stdout)
+	}
+
+	st := p.Stats()
+	if st.Hits != 2 || st.Misses != 0 || st.HitRate != 1 {
+		t.Errorf("unexpected pool stats: %+v", st)
+	}
+}
+
+func TestPool_RecoversAfterTimeout(t *testing.T) {
+	p := NewPool(1)
+	defer p.Close()
+
+	waitForReady(t, p, 1)
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer shortCancel()
+	res := p.Execute(shortCtx, `for(;;){}`, "test.js", nil, Options{})
+	if res.ExitCode != 124 {
+		t.Fatalf("expected timeout, got %d (%s)", res.ExitCode, res.Summary)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	waitForReady(t, p, 1)
+	res = p.Execute(ctx, `console.log('alive');`, "test.js", nil, Options{})
+	if !res.Success || !strings.Contains(res.Stdout, "alive") {
+		t.Errorf("execution after timeout failed: %s %q", res.Summary, res.Stdout)
+	}
+}
+
+func TestPool_Disabled(t *testing.T) {
+	p := NewPool(0)
+	defer p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	res := p.Execute(ctx, `console.log('ok');`, "test.js", nil, Options{})
+	if !res.Success {
+		t.Fatalf("execution failed: %s", res.Summary)
+	}
+	if st := p.Stats(); st.Misses != 1 || st.Hits != 0 {
+		t.Errorf("unexpected pool stats: %+v", st)
+	}
+}
+
+func TestPool_HeapLimitIgnoresEarlierGarbage(t *testing.T) {
+	p := NewPool(1)
+	defer p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Leave more garbage in the isolate than the later run may allocate.
+	waitForReady(t, p, 1)
+	res := p.Execute(ctx, `globalThis.big = new Array(4e6).fill(0).map((_, i) => ({ i })); console.log(big.length);`, "test.js", nil, Options{})
+	if !res.Success {
+		t.Fatalf("first run failed: %s", res.Summary)
+	}
+
+	// The small run lasts several watchdog intervals, so the heap is checked.
+	waitForReady(t, p, 1)
+	limits := Limits{MaxHeapBytes: 16 * 1024 * 1024}
+	res = p.Execute(ctx, `const end = Date.now() + 50; while (Date.now() < end); console.log('ok');`, "test.js", nil, Options{Limits: limits})
+	if !res.Success {
+		t.Errorf("small run failed with exit code %d: %s", res.ExitCode, res.Summary)
+	}
+
+	// Memory allocated by the run itself is still limited.
+	waitForReady(t, p, 1)
+	res = p.Execute(ctx, `const a = []; for (;;) a.push({ x: a.length });`, "test.js", nil, Options{Limits: limits})
+	if res.ExitCode != ExitCodeMemoryLimit {
+		t.Errorf("expected the memory limit, got %d (%s)", res.ExitCode, res.Summary)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"errors"
+	"fmt"
+
+	v8 "rogchap.com/v8go"
+)
+
+// errTerminated is reported when the isolate was stopped by the watchdog
+// while promise jobs were still being processed.
+var errTerminated = errors.New("execution terminated")
+
+// errUnsettled is reported when the entry promise is still pending after all
+// promise jobs have run, e.g. when awaiting a promise that never resolves.
+var errUnsettled = errors.New("top-level promise never settled (awaiting something that never resolves?)")
+
+// asPromise returns val as a Promise, or nil if it is not one.
+func asPromise(val *v8.Value) *v8.Promise {
+	if val == nil || !val.IsPromise() {
+		return nil
+	}
+	prom, err := val.AsPromise()
+	if err != nil {
+		return nil
+	}
+	return prom
+}
+
+// isThenable reports whether val is a Promise or an object with a then method.
+func isThenable(val *v8.Value) bool {
+	if val.IsPromise() {
+		return true
+	}
+	if !val.IsObject() {
+		return false
+	}
+	then, err := val.Object().Get("then")
+	return err == nil && then.IsFunction()
+}
+
+// rejectionError converts a promise rejection reason into a *v8.JSError.
+// The location is taken from the first frame of the reason's stack, if any.
+func rejectionError(reason *v8.Value) *v8.JSError {
+	if !reason.IsObject() {
+		return newRejectionError(reason.String(), "")
+	}
+	stack, err := reason.Object().Get("stack")
+	if err != nil || stack.IsUndefined() {
+		return newRejectionError(reason.String(), "")
+	}
+	return newRejectionError(reason.String(), stack.String())
+}
+
+// newRejectionError builds the *v8.JSError of a rejection with the given
+// message and stack, which may be empty.
+func newRejectionError(msg, stack string) *v8.JSError {
+	jsErr := &v8.JSError{Message: msg}
+	if stack != "" {
+		jsErr.StackTrace = stack
+		jsErr.Location = firstFrameLocation(stack)
+	}
+	return jsErr
+}
+
+// firstFrameLocation extracts "file:line:col" from the first frame with a
+// position of a V8 stack trace.
+func firstFrameLocation(stack string) string {
+	frames := parseStack(stack)
+	if len(frames) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d:%d", frames[0].file, frames[0].line, frames[0].col)
+}
+
+// throwTypeError throws a JS TypeError from inside a Go callback.
+func throwTypeError(iso *v8.Isolate, ctx *v8.Context, msg string) *v8.Value {
+	ctor, err := ctx.Global().Get("TypeError")
+	if err == nil && ctor.IsFunction() {
+		fn, _ := ctor.AsFunction()
+		m, _ := v8.NewValue(iso, msg)
+		if obj, err := fn.NewInstance(m); err == nil {
+			return iso.ThrowException(obj.Value)
+		}
+	}
+	m, _ := v8.NewValue(iso, msg)
+	return iso.ThrowException(m)
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+#include "rejections.h"
+
+#include <stdlib.h>
+#include <string.h>
+
+#include <mutex>
+#include <unordered_map>
+#include <vector>
+
+#include "v8.h"
+
+namespace {
+
+struct Rejection {
+  v8::Global<v8::Promise> promise;
+  v8::Global<v8::Value> reason;
+};
+
+// Tracked per isolate; every isolate runs one sandbox context at a time.
+struct Tracker {
+  v8::Global<v8::Context> ctx;
+  std::vector<Rejection> pending;
+};
+
+std::mutex mu;
+std::unordered_map<v8::Isolate*, Tracker> trackers;
+
+// OnPromiseReject keeps the promises that are rejected without a handler
+// until a handler is attached.
+void OnPromiseReject(v8::PromiseRejectMessage msg) {
+  v8::Local<v8::Promise> promise = msg.GetPromise();
+  v8::Isolate* iso = promise->GetIsolate();
+
+  std::lock_guard<std::mutex> lock(mu);
+  auto it = trackers.find(iso);
+  if (it == trackers.end()) {
+    return;
+  }
+  std::vector<Rejection>& pending = it->second.pending;
+
+  switch (msg.GetEvent()) {
+    case v8::kPromiseRejectWithNoHandler: {
+      Rejection r;
+      r.promise.Reset(iso, promise);
+      r.reason.Reset(iso, msg.GetValue());
+      pending.push_back(std::move(r));
+      break;
+    }
+    case v8::kPromiseHandlerAddedAfterReject:
+      for (auto r = pending.begin(); r != pending.end(); ++r) {
+        if (r->promise == promise) {
+          pending.erase(r);
+          break;
+        }
+      }
+      break;
+    default:
+      break;
+  }
+}
+
+char* CopyString(v8::Isolate* iso, v8::Local<v8::Context> ctx, v8::Local<v8::Value> val) {
+  v8::Local<v8::String> str;
+  if (!val->ToString(ctx).ToLocal(&str)) {
+    return strdup("");
+  }
+  v8::String::Utf8Value utf8(iso, str);
+  return strdup(*utf8 != nullptr ? *utf8 : "");
+}
+
+}  // namespace
+
+void* TrackRejections(void) {
+  v8::Isolate* iso = v8::Isolate::GetCurrent();
+  {
+    std::lock_guard<std::mutex> lock(mu);
+    Tracker& t = trackers[iso];
+    t.pending.clear();
+    t.ctx.Reset(iso, iso->GetCurrentContext());
+  }
+  iso->SetPromiseRejectCallback(OnPromiseReject);
+  return iso;
+}
+
+int TakeRejection(void* ptr, RejectionInfo* out) {
+  v8::Isolate* iso = static_cast<v8::Isolate*>(ptr);
+  v8::Locker locker(iso);
+  v8::Isolate::Scope isolate_scope(iso);
+  v8::HandleScope handle_scope(iso);
+
+  v8::Local<v8::Context> ctx;
+  v8::Local<v8::Value> reason;
+  {
+    std::lock_guard<std::mutex> lock(mu);
+    auto it = trackers.find(iso);
+    if (it == trackers.end() || it->second.pending.empty()) {
+      return 0;
+    }
+    ctx = it->second.ctx.Get(iso);
+    reason = it->second.pending.front().reason.Get(iso);
+    it->second.pending.clear();
+  }
+
+  // Converting the reason may run script code, which may reject promises
+  // again, so the lock is not held here.
+  v8::Context::Scope context_scope(ctx);
+  v8::TryCatch try_catch(iso);
+  out->message = CopyString(iso, ctx, reason);
+  out->stack = nullptr;
+  if (reason->IsObject()) {
+    v8::Local<v8::Value> stack;
+    v8::Local<v8::String> key = v8::String::NewFromUtf8Literal(iso, "stack");
+    if (reason.As<v8::Object>()->Get(ctx, key).ToLocal(&stack) && !stack->IsUndefined()) {
+      out->stack = CopyString(iso, ctx, stack);
+    }
+  }
+  return 1;
+}
+
+void ForgetRejections(void* ptr) {
+  v8::Isolate* iso = static_cast<v8::Isolate*>(ptr);
+  v8::Locker locker(iso);
+  v8::Isolate::Scope isolate_scope(iso);
+
+  std::lock_guard<std::mutex> lock(mu);
+  trackers.erase(iso);
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+// The V8 headers must match the V8 version linked by v8go, see third_party/v8.
+
+// #cgo CXXFLAGS: -fno-rtti -fPIC -std=c++17 -DV8_COMPRESS_POINTERS -DV8_31BIT_SMIS_ON_64BIT_ARCH -DV8_ENABLE_SANDBOX -I${SRCDIR}/../../third_party/v8/include -Wall
+// #include <stdlib.h>
+// #include "rejections.h"
+import "C"
+
+import (
+	"unsafe"
+
+	v8 "rogchap.com/v8go"
+)
+
+// rejectionTracker records promises that are rejected without a handler.
+// v8go does not expose V8's promise reject callback, so it is installed by
+// rejections.cc. A promise counts as handled as soon as a handler is attached,
+// even after it was rejected.
+type rejectionTracker struct {
+	iso unsafe.Pointer
+}
+
+// trackRejections starts tracking unhandled rejections in ctx. The callback
+// is installed from inside a function call, where the isolate and the context
+// are entered.
+func trackRejections(iso *v8.Isolate, ctx *v8.Context) (*rejectionTracker, error) {
+	t := &rejectionTracker{}
+	tmpl := v8.NewFunctionTemplate(iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
+		t.iso = C.TrackRejections()
+		return v8.Undefined(iso)
+	})
+	if _, err := tmpl.GetFunction(ctx).Call(v8.Undefined(iso)); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// take returns the first rejection that is still unhandled as a *v8.JSError,
+// or nil. All recorded rejections are forgotten.
+func (t *rejectionTracker) take() *v8.JSError {
+	if t == nil || t.iso == nil {
+		return nil
+	}
+	var info C.RejectionInfo
+	if C.TakeRejection(t.iso, &info) == 0 {
+		return nil
+	}
+	defer C.free(unsafe.Pointer(info.message))
+	stack := ""
+	if info.stack != nil {
+		defer C.free(unsafe.Pointer(info.stack))
+		stack = C.GoString(info.stack)
+	}
+	return newRejectionError(C.GoString(info.message), stack)
+}
+
+// close stops tracking. It must be called before the isolate is disposed.
+func (t *rejectionTracker) close() {
+	if t == nil || t.iso == nil {
+		return
+	}
+	C.ForgetRejections(t.iso)
+	t.iso = nil
+}
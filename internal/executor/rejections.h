@@ -0,0 +1,33 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+#ifndef WOLLMILCHSAU_REJECTIONS_H
+#define WOLLMILCHSAU_REJECTIONS_H
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+// RejectionInfo describes an unhandled promise rejection. Both strings are
+// allocated with malloc; stack is NULL if the reason has no stack.
+typedef struct {
+  char* message;
+  char* stack;
+} RejectionInfo;
+
+// TrackRejections starts tracking unhandled rejections in the current context
+// of the current isolate. It must be called from a V8 callback and returns the
+// isolate for the other functions.
+void* TrackRejections(void);
+
+// TakeRejection stores the first rejection that is still unhandled in out and
+// forgets all others. It returns 0 if there is none.
+int TakeRejection(void* iso, RejectionInfo* out);
+
+// ForgetRejections stops tracking; it must be called before the isolate is
+// disposed.
+void ForgetRejections(void* iso);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif
@@ -0,0 +1,73 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"errors"
+	"strings"
+
+	v8 "rogchap.com/v8go"
+)
+
+// InjectReturnValue adds wollmilchsau.return(value) to the V8 context. The
+// value is JSON-serialized when return is called and handed to set; values
+// that cannot be serialized throw a TypeError at the call site.
+//
+// The returned function is the original wollmilchsau.return, so the caller can
+// serialize the entry module's default export with the same rules even if the
+// script replaced the property.
+func InjectReturnValue(iso *v8.Isolate, ctx *v8.Context, set func(json string)) (*v8.Function, error) {
+	jsonObj, err := ctx.Global().Get("JSON")
+	if err != nil {
+		return nil, err
+	}
+	stringifyVal, err := jsonObj.Object().Get("stringify")
+	if err != nil {
+		return nil, err
+	}
+	stringify, err := stringifyVal.AsFunction()
+	if err != nil {
+		return nil, err
+	}
+
+	returnTmpl := v8.NewFunctionTemplate(iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
+		var arg v8.Valuer = v8.Undefined(iso)
+		if len(info.Args()) > 0 {
+			arg = info.Args()[0]
+		}
+		data, err := stringify.Call(v8.Undefined(iso), arg)
+		if err != nil {
+			if iso.IsExecutionTerminating() {
+				return nil
+			}
+			msg := err.Error()
+			var jsErr *v8.JSError
+			if errors.As(err, &jsErr) {
+				msg = jsErr.Message
+			}
+			msg = strings.TrimPrefix(msg, "TypeError: ")
+			return throwTypeError(iso, ctx, "wollmilchsau.return: value is not JSON-serializable: "+msg)
+		}
+		if data.IsUndefined() {
+			return throwTypeError(iso, ctx, "wollmilchsau.return: value is not JSON-serializable")
+		}
+		set(data.String())
+		return v8.Undefined(iso)
+	})
+
+	ns, err := namespaceObject(iso, ctx)
+	if err != nil {
+		return nil, err
+	}
+	fn := returnTmpl.GetFunction(ctx)
+	if err := ns.Set("return", fn); err != nil {
+		return nil, err
+	}
+	return fn, nil
+}
+
+// returnValueDeclarations declares the member of InjectReturnValue.
+const returnValueDeclarations = `interface Wollmilchsau {
+  /** Sets the JSON value returned as returnValue; throws a TypeError if it cannot be serialized. */
+  return(value: unknown): void;
+}
+`
@@ -0,0 +1,133 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hmsoft0815/wollmilchsau/internal/bundler"
+	"github.com/hmsoft0815/wollmilchsau/internal/parser"
+)
+
+func TestExecute_ReturnValue(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{
+			name: "default export",
+			code: "export const other = 1;\nexport default { answer: 42, list: [1, 2] };",
+			want: `{"answer":42,"list":[1,2]}`,
+		},
+		{
+			name: "default export after await",
+			code: "const n = await new Promise<number>((r) => setTimeout(() => r(7), 1));\nexport default n;",
+			want: `7`,
+		},
+		{
+			name: "explicit return wins over default export",
+			code: "wollmilchsau.return(['a', null]);\nexport default 5;",
+			want: `["a",null]`,
+		},
+		{
+			name: "promise default export",
+			code: "export default Promise.resolve(5);",
+			want: `5`,
+		},
+		{
+			name: "default export of an async call",
+			code: "async function fetchData() {\n  await new Promise((r) => setTimeout(r, 1));\n  return { rows: [1] };\n}\nexport default fetchData();",
+			want: `{"rows":[1]}`,
+		},
+		{
+			name: "thenable default export",
+			code: "export default { then(resolve: (v: string) => void) { resolve('done'); } };",
+			want: `"done"`,
+		},
+		{
+			name: "no return value",
+			code: "console.log('only output');",
+			want: ``,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &parser.ExecutionPlan{
+				Files:      []parser.VirtualFile{{Name: "main.ts", Content: tt.code}},
+				EntryPoint: "main.ts",
+			}
+			b, err := bundler.Bundle(plan)
+			if err != nil {
+				t.Fatalf("bundle failed: %v", err)
+			}
+			res := Execute(ctx, b.JS, "main.ts", b.SourceMap, Options{})
+			if !res.Success {
+				t.Fatalf("Execution failed: %s", res.Summary)
+			}
+			if string(res.ReturnValue) != tt.want {
+				t.Errorf("ReturnValue = %s, want %s", res.ReturnValue, tt.want)
+			}
+		})
+	}
+
+	t.Run("over the output budget", func(t *testing.T) {
+		code := "wollmilchsau.return('x'.repeat(100));"
+		res := Execute(ctx, code, "test.js", nil, Options{Limits: Limits{OutputBudgetBytes: 50}})
+		if !res.Success || res.ReturnValue != nil || !res.Truncated || res.ReturnValueBytes != 102 {
+			t.Fatalf("unexpected result: %+v", res)
+		}
+
+		store, err := NewDirArtifactStore(t.TempDir(), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res = Execute(ctx, code, "test.js", nil, Options{Limits: Limits{OutputBudgetBytes: 50}, Artifacts: store, SpillOutput: true})
+		if !res.Success || res.ReturnValue != nil || len(res.CreatedArtifacts) != 1 {
+			t.Fatalf("unexpected result with spilling: %+v", res)
+		}
+		if ref := res.CreatedArtifacts[0]; ref.Name != "returnValue.json" || ref.FileSize != 102 {
+			t.Errorf("unexpected artifact: %+v", ref)
+		}
+
+		res = Execute(ctx, "wollmilchsau.return('x'.repeat(48));", "test.js", nil, Options{Limits: Limits{OutputBudgetBytes: 50}})
+		if res.Truncated || len(res.ReturnValue) != 50 {
+			t.Errorf("a value within the budget was cut: %+v", res)
+		}
+	})
+
+	t.Run("rejected default export", func(t *testing.T) {
+		plan := &parser.ExecutionPlan{
+			Files:      []parser.VirtualFile{{Name: "main.ts", Content: "async function fetchData(): Promise<number> {\n  await null;\n  throw new Error('no data');\n}\nexport default fetchData();"}},
+			EntryPoint: "main.ts",
+		}
+		b, err := bundler.Bundle(plan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := Execute(ctx, b.JS, "main.ts", b.SourceMap, Options{})
+		if res.Success || res.ExitCode != ExitCodeRuntimeError || res.ReturnValue != nil || len(res.Diagnostics) != 1 {
+			t.Fatalf("expected a runtime error, got %+v", res)
+		}
+		if d := res.Diagnostics[0]; d.Message != "Error: no data" || d.Source != "main.ts" || d.Line != 3 {
+			t.Errorf("unexpected diagnostic: %+v", d)
+		}
+	})
+
+	t.Run("not serializable", func(t *testing.T) {
+		res := Execute(ctx, "const o = {};\no.self = o;\nwollmilchsau.return(o);", "test.js", nil, Options{})
+		if res.Success || len(res.Diagnostics) == 0 {
+			t.Fatalf("expected a runtime error, got %+v", res)
+		}
+		d := res.Diagnostics[0]
+		if !strings.Contains(d.Message, "not JSON-serializable") || d.Line != 3 {
+			t.Errorf("expected error at the call site, got %+v", d)
+		}
+	})
+}
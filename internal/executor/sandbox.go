@@ -0,0 +1,290 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/hmsoft0815/wollmilchsau/internal/sourcemap"
+	v8 "rogchap.com/v8go"
+)
+
+// sandbox is a V8 context prepared for exactly one execution. Console,
+// polyfills and the event loop are installed up front so that a sandbox can
+// be created ahead of time (see Pool) and the script can start immediately.
+type sandbox struct {
+	iso    *v8.Isolate
+	v8ctx  *v8.Context
+	loop   *eventLoop
+	stdout outputBuffer
+	stderr outputBuffer
+
+	// returnFn is the original wollmilchsau.return, used for the default export.
+	returnFn *v8.Function
+
+	// Per-run state, set by run before the script starts.
+	limits      Limits
+	wd          *watchdog
+	spill       bool            // output beyond the budget is saved as an artifact
+	returnValue json.RawMessage // set by wollmilchsau.return(); nil if dropped
+	returnBytes int             // size of the serialized return value, 0 if none
+
+	warmup  time.Duration // time it took to prepare the sandbox
+	tainted bool          // isolate was terminated and must not be reused
+}
+
+// newSandbox creates a fresh context on iso and installs all sandbox globals.
+func newSandbox(iso *v8.Isolate) *sandbox {
+	start := time.Now()
+	sb := &sandbox{iso: iso}
+
+	// The hard limits count all output, including what the budget hides.
+	makeWriter := func(target *outputBuffer, limit func() int) func(string) {
+		return func(line string) {
+			if target.totalBytes+len(line)+1 > limit() {
+				sb.wd.terminate(reasonOutput)
+				return
+			}
+			target.writeLine(line)
+		}
+	}
+	stdout := makeWriter(&sb.stdout, func() int { return sb.limits.MaxStdoutBytes })
+	stderr := makeWriter(&sb.stderr, func() int { return sb.limits.MaxStderrBytes })
+
+	sb.v8ctx = v8.NewContext(iso)
+
+	if err := InjectConsole(iso, sb.v8ctx, stdout, stderr); err != nil {
+		slog.Error("failed to inject console", "err", err)
+	}
+
+	// A return value beyond the output budget is only kept if it can be
+	// spilled to an artifact.
+	returnFn, err := InjectReturnValue(iso, sb.v8ctx, func(data string) {
+		sb.returnBytes = len(data)
+		sb.returnValue = nil
+		if sb.spill || len(data) <= sb.limits.OutputBudgetBytes {
+			sb.returnValue = json.RawMessage(data)
+		}
+	})
+	if err != nil {
+		slog.Error("failed to inject wollmilchsau.return", "err", err)
+	}
+	sb.returnFn = returnFn
+
+	if err := runPrelude(sb.v8ctx, "binary.js", binaryJS); err != nil {
+		slog.Error("failed to load binary prelude", "err", err)
+	}
+	if err := runPrelude(sb.v8ctx, "artifacts.js", artifactsJS); err != nil {
+		slog.Error("failed to load artifacts prelude", "err", err)
+	}
+	if err := runPrelude(sb.v8ctx, "input.js", inputJS); err != nil {
+		slog.Error("failed to load input prelude", "err", err)
+	}
+	if err := runPrelude(sb.v8ctx, "files.js", filesJS); err != nil {
+		slog.Error("failed to load files prelude", "err", err)
+	}
+
+	if err := InjectPolyfills(iso, sb.v8ctx); err != nil {
+		slog.Error("failed to inject polyfills", "err", err)
+	}
+
+	sb.loop = newEventLoop(iso, sb.v8ctx)
+	if err := sb.loop.Inject(); err != nil {
+		slog.Error("failed to inject event loop", "err", err)
+	}
+
+	sb.warmup = time.Since(start)
+	return sb
+}
+
+// runPrelude runs one of the sandbox's own scripts in ctx. The name shows up
+// in stack traces, where frames of these scripts are left out.
+func runPrelude(ctx *v8.Context, name, source string) error {
+	_, err := ctx.RunScript(source, name)
+	return err
+}
+
+// close releases the context. The isolate is owned by the caller.
+func (sb *sandbox) close() {
+	sb.loop.close()
+	sb.v8ctx.Close()
+}
+
+// run executes the bundled JavaScript in the sandbox and collects the result.
+func (sb *sandbox) run(ctx context.Context, js string, filename string, sm *sourcemap.SourceMap, opts Options) *Result {
+	start := time.Now()
+
+	res := &Result{Diagnostics: []Diagnostic{}}
+	sb.limits = opts.Limits.WithDefaults()
+	sb.returnValue = nil
+	sb.returnBytes = 0
+
+	// The input limit covers the input parameter and all input files.
+	inputBytes := 0
+	if opts.Input != nil {
+		inputBytes += len(opts.Input.Text)
+	}
+	for _, f := range opts.Files {
+		inputBytes += len(f.Content)
+	}
+	if inputBytes > sb.limits.MaxInputBytes {
+		res.ExitCode = ExitCodeRuntimeError
+		res.Summary = fmt.Sprintf("Input too large: %d bytes (limit %d bytes)", inputBytes, sb.limits.MaxInputBytes)
+		return res
+	}
+	if err := InjectInput(sb.iso, sb.v8ctx, opts.Input); err != nil {
+		slog.Error("failed to inject input", "err", err)
+	}
+	if err := InjectFiles(sb.iso, sb.v8ctx, opts.Files); err != nil {
+		slog.Error("failed to inject input files", "err", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(sb.limits.MaxWallTimeMs)*time.Millisecond)
+	defer cancel()
+
+	// One store is shared by the low-level `artifact.*` API and the
+	// high-level `wollmilchsau.openArtifact()` API. It is owned by the
+	// caller, so executions that never touch artifacts cost nothing.
+	store := opts.Artifacts
+	var arts *artifactBridge
+	if store != nil {
+		// Service calls run in the background and settle via the event loop.
+		var err error
+		arts, err = newArtifactBridge(ctx, sb.iso, sb.v8ctx, store, sb.loop)
+		if err == nil {
+			err = arts.injectService()
+		}
+		if err == nil {
+			err = arts.injectOpenArtifact(res, sb.limits.MaxArtifactBytes)
+		}
+		if err != nil {
+			slog.Error("failed to inject artifact service", "err", err)
+			arts = nil
+		}
+	}
+
+	// Output beyond the budget is only kept if it can be spilled to an artifact.
+	spill := opts.SpillOutput && store != nil
+	sb.spill = spill
+	sb.stdout.reset(sb.limits.OutputBudgetBytes, sb.limits.OutputBudgetLines, spill)
+	sb.stderr.reset(sb.limits.OutputBudgetBytes, sb.limits.OutputBudgetLines, spill)
+
+	// The watchdog covers the initial script run as well as all promise jobs
+	// and timer callbacks processed by the event loop.
+	sb.wd = startWatchdog(ctx, sb.iso, sb.limits)
+	sb.wd.enter()
+	val, runErr := sb.v8ctx.RunScript(js, filename)
+	sb.wd.leave()
+	if runErr == nil {
+		val, runErr = sb.loop.Run(ctx, sb.wd, val)
+	}
+	if runErr == nil && sb.returnBytes == 0 {
+		runErr = sb.exportDefault(ctx, val)
+	}
+	reason := sb.wd.stop()
+	if reason != reasonNone || ctx.Err() != nil || errors.Is(runErr, errTerminated) {
+		sb.tainted = true
+	}
+
+	// Handles the script left open are uploaded or discarded.
+	if arts != nil {
+		arts.finish(runErr == nil)
+	}
+
+	res.Stdout = collectOutput(store, spill, "stdout.txt", &sb.stdout, res)
+	res.Stderr = collectOutput(store, spill, "stderr.txt", &sb.stderr, res)
+	res.StdoutBytes = sb.stdout.totalBytes
+	res.StderrBytes = sb.stderr.totalBytes
+	res.DurationMs = time.Since(start).Milliseconds()
+
+	if runErr != nil {
+		handleExecuteError(ctx, sb.wd.heapUsed(), reason, sb.limits, runErr, filename, sm, res)
+	} else {
+		collectReturnValue(store, spill, sb.returnValue, sb.returnBytes, sb.limits.OutputBudgetBytes, res)
+		res.ExitCode = 0
+		res.Success = true
+		res.Summary = "Execution finished successfully"
+	}
+
+	return res
+}
+
+// exportDefault serializes the default export of the entry module as the
+// return value. The bundle resolves to the exports of the entry module, see
+// bundler.Bundle. A Promise or other thenable is awaited first; its rejection
+// is reported like any other rejection.
+func (sb *sandbox) exportDefault(ctx context.Context, exports *v8.Value) error {
+	if sb.returnFn == nil || exports == nil || !exports.IsObject() || !exports.Object().Has("default") {
+		return nil
+	}
+	sb.wd.enter()
+	def, err := exports.Object().Get("default")
+	thenable := err == nil && isThenable(def)
+	sb.wd.leave()
+	if err != nil {
+		return err
+	}
+	if thenable {
+		// Resolving a new promise with the value adopts its state.
+		resolver, err := v8.NewPromiseResolver(sb.v8ctx)
+		if err != nil {
+			return err
+		}
+		sb.wd.enter()
+		resolver.Resolve(def)
+		sb.wd.leave()
+		if def, err = sb.loop.Run(ctx, sb.wd, resolver.GetPromise().Value); err != nil {
+			return err
+		}
+	}
+
+	sb.wd.enter()
+	_, err = sb.returnFn.Call(v8.Undefined(sb.iso), def)
+	sb.wd.leave()
+	if err != nil {
+		if sb.iso.IsExecutionTerminating() {
+			return errTerminated
+		}
+		// The error location points into the prelude, not the script.
+		var jsErr *v8.JSError
+		if errors.As(err, &jsErr) {
+			return fmt.Errorf("default export: %s", jsErr.Message)
+		}
+		return err
+	}
+	return nil
+}
+
+// collectReturnValue sets the return value of res. A value larger than the
+// output budget is left out; res.Truncated and res.ReturnValueBytes report it
+// and, with spill enabled, the value is saved as returnValue.json.
+func collectReturnValue(store ArtifactStore, spill bool, value json.RawMessage, size, budget int, res *Result) {
+	if size <= budget {
+		res.ReturnValue = value
+		return
+	}
+	res.Truncated = true
+	res.ReturnValueBytes = size
+	if spill && value != nil {
+		spillOutput(store, "returnValue.json", "application/json", value, res)
+	}
+}
+
+// collectOutput returns the output of one stream as reported to the caller.
+// If the stream exceeded its budget, res.Truncated is set and, with spill
+// enabled, the full output is saved as an artifact named name.
+func collectOutput(store ArtifactStore, spill bool, name string, out *outputBuffer, res *Result) string {
+	if !out.truncated() {
+		return out.full()
+	}
+	res.Truncated = true
+	uri := ""
+	if spill {
+		uri = spillOutput(store, name, "text/plain", []byte(out.full()), res)
+	}
+	return out.visible(uri)
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hmsoft0815/wollmilchsau/internal/sourcemap"
+	v8 "rogchap.com/v8go"
+)
+
+// V8 computes source positions lazily, which it cannot do once the stack is
+// exhausted: all frames of a stack overflow would point to 1:1.
+func init() {
+	v8.SetFlags("--no-enable-lazy-source-positions")
+}
+
+// v8Frame is one "at" line of a V8 stack trace, e.g. "at check (main.ts:8:20)".
+type v8Frame struct {
+	function string // empty for anonymous functions
+	file     string
+	line     int // 1-based
+	col      int // 1-based
+}
+
+// parseStack returns the frames of a V8 stack trace. Both "at fn (file:1:2)"
+// and "at file:1:2" are supported; frames without a position, such as
+// "at Array.map (<anonymous>)", are skipped.
+func parseStack(stack string) []v8Frame {
+	var frames []v8Frame
+	for _, line := range strings.Split(stack, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "at ") {
+			continue
+		}
+		var f v8Frame
+		loc := strings.TrimPrefix(line, "at ")
+		if open := strings.Index(loc, " ("); open >= 0 && strings.HasSuffix(loc, ")") {
+			f.function = strings.TrimPrefix(loc[:open], "async ")
+			loc = loc[open+2 : len(loc)-1]
+		} else {
+			loc = strings.TrimPrefix(loc, "async ")
+		}
+
+		// The file name may contain colons, so the position is taken from the end.
+		parts := strings.Split(loc, ":")
+		if len(parts) < 3 {
+			continue
+		}
+		var err1, err2 error
+		f.line, err1 = strconv.Atoi(parts[len(parts)-2])
+		f.col, err2 = strconv.Atoi(parts[len(parts)-1])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		f.file = strings.Join(parts[:len(parts)-2], ":")
+		frames = append(frames, f)
+	}
+	return frames
+}
+
+// resolveFrames maps the frames of stack that lie in the bundle, which runs
+// as filename, back to the original sources, and returns the position of the
+// first of them in the bundle. Frames of built-ins and of the sandbox's own
+// scripts (console.js, polyfills.js, ...) are left out, and so are frames
+// without a mapping, such as the bundle's async wrapper or esbuild's runtime
+// helpers. Without a source map, the frames keep their bundle positions.
+func resolveFrames(stack, filename string, sm *sourcemap.SourceMap) (frames []StackFrame, genLine, genCol int) {
+	for _, f := range parseStack(stack) {
+		if f.file != filename {
+			continue
+		}
+		frame := StackFrame{Function: f.function, Source: "<bundle>", Line: f.line, Column: f.col}
+		if sm != nil {
+			orig := sm.Resolve(f.line, f.col)
+			if orig == nil {
+				continue
+			}
+			frame.Source, frame.Line, frame.Column = orig.Source, orig.Line, orig.Column
+		}
+		if len(frames) == 0 {
+			genLine, genCol = f.line, f.col
+		}
+		frames = append(frames, frame)
+	}
+	return frames, genLine, genCol
+}
+
+// formatTrace renders a stack trace like V8 does, with the original positions:
+//
+//	Error: bad input
+//	    at check (lib/util.ts:6:20)
+//	    at main.ts:7:1
+func formatTrace(msg string, frames []StackFrame) string {
+	var b strings.Builder
+	b.WriteString(msg)
+	b.WriteByte('\n')
+	for _, f := range frames {
+		if f.Function == "" {
+			fmt.Fprintf(&b, "    at %s:%d:%d\n", f.Source, f.Line, f.Column)
+		} else {
+			fmt.Fprintf(&b, "    at %s (%s:%d:%d)\n", f.Function, f.Source, f.Line, f.Column)
+		}
+	}
+	return b.String()
+}
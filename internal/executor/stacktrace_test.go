@@ -0,0 +1,115 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hmsoft0815/wollmilchsau/internal/bundler"
+	"github.com/hmsoft0815/wollmilchsau/internal/parser"
+)
+
+func TestParseStack(t *testing.T) {
+	stack := "Error: a\nb\n" +
+		"    at check (main.ts:8:20)\n" +
+		"    at Array.map (<anonymous>)\n" +
+		"    at new Parser (main.ts:3:5)\n" +
+		"    at format (console.js:12:7)\n" +
+		"    at async main.ts:17:1\n" +
+		"    at async run (C:\\dir\\main.ts:2:9)"
+	var got []string
+	for _, f := range parseStack(stack) {
+		got = append(got, fmt.Sprintf("%s|%s|%d|%d", f.function, f.file, f.line, f.col))
+	}
+	want := []string{
+		"check|main.ts|8|20",
+		"new Parser|main.ts|3|5",
+		"format|console.js|12|7",
+		"|main.ts|17|1",
+		"run|C:\\dir\\main.ts|2|9",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("frames:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if loc := firstFrameLocation("Error: x\n    at Array.map (<anonymous>)\n    at f (main.ts:1:2)"); loc != "main.ts:1:2" {
+		t.Errorf("firstFrameLocation = %q", loc)
+	}
+}
+
+func TestExecute_StackTrace(t *testing.T) {
+	plan := &parser.ExecutionPlan{
+		Files: []parser.VirtualFile{
+			{Name: "main.ts", Content: "import { run } from './lib/util';\n\nasync function main(): Promise<void> {\n  await 1;\n  run([1, 2]);\n}\nawait main();"},
+			{Name: "lib/util.ts", Content: "export function run(xs: number[]): number[] {\n  return xs.map((x) => check(x));\n}\n\nfunction check(x: number): number {\n  if (x > 1) throw new Error(`bad ${x}`);\n  return x;\n}"},
+		},
+		EntryPoint: "main.ts",
+	}
+	b, err := bundler.Bundle(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := Execute(context.Background(), b.JS, plan.EntryPoint, b.SourceMap, Options{})
+	if res.Success || len(res.Diagnostics) != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	var got []string
+	for _, f := range res.Diagnostics[0].Frames {
+		got = append(got, fmt.Sprintf("%s %s:%d", f.Function, f.Source, f.Line))
+	}
+	want := []string{"check lib/util.ts:6", " lib/util.ts:2", "run lib/util.ts:2", "main main.ts:5", " main.ts:7"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("frames = %q, want %q", got, want)
+	}
+	if !strings.HasPrefix(res.Stderr, "Error: bad 2\n    at check (lib/util.ts:6:") || !strings.Contains(res.Stderr, "\n    at main (main.ts:5:") {
+		t.Errorf("unexpected stderr:\n%s", res.Stderr)
+	}
+
+	// Output of the script comes first; errors without a stack add nothing.
+	res = Execute(context.Background(), "console.error('before'); throw 'plain';", "main.js", nil, Options{})
+	if res.Stderr != "before\n" || len(res.Diagnostics) != 1 || res.Diagnostics[0].Frames != nil {
+		t.Errorf("unexpected result of a thrown string: %q %+v", res.Stderr, res.Diagnostics)
+	}
+	res = Execute(context.Background(), "console.error('before');\nnull.x;", "main.js", nil, Options{})
+	if res.Stderr != "before\nTypeError: Cannot read properties of null (reading 'x')\n    at <bundle>:2:6\n" {
+		t.Errorf("unexpected stderr without a source map: %q", res.Stderr)
+	}
+}
+
+func TestExecute_StackTraceWithoutInternalFrames(t *testing.T) {
+	run := func(code string) *Result {
+		plan := &parser.ExecutionPlan{Files: []parser.VirtualFile{{Name: "main.ts", Content: code}}, EntryPoint: "main.ts"}
+		b, err := bundler.Bundle(plan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Execute(context.Background(), b.JS, plan.EntryPoint, b.SourceMap, Options{})
+	}
+
+	// The frame of the bundle's async wrapper has no mapping.
+	res := run("class A {\n  get y(): number {\n    if (this) throw new Error('getter');\n    return 1;\n  }\n}\nconsole.log(new A().y);")
+	if len(res.Diagnostics) != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	d := res.Diagnostics[0]
+	if want := "Error: getter\n    at get y [as y] (main.ts:3:21)\n    at main.ts:7:21\n"; res.Stderr != want {
+		t.Errorf("stderr = %q, want %q", res.Stderr, want)
+	}
+	if d.Source != "main.ts" || d.Line != 3 || d.Column != d.Frames[0].Column {
+		t.Errorf("the diagnostic does not match the first frame: %+v", d)
+	}
+
+	// Without lazy source positions, the frames of a stack overflow resolve.
+	res = run("function f(n: number): number {\n  return f(n + 1) + 1;\n}\nf(0);")
+	if res.ExitCode != ExitCodeStackLimit || res.Summary != "Execution terminated: Stack limit exceeded in main.ts:2" {
+		t.Fatalf("unexpected result: %s", res.Summary)
+	}
+	for _, f := range res.Diagnostics[0].Frames {
+		if f.Function != "f" || f.Source != "main.ts" || f.Line != 2 {
+			t.Errorf("unexpected frame of the stack overflow: %+v", f)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 // Copyright (c) 2026 Michael Lechner. All rights reserved.
 package executor
 
+import "encoding/json"
+
 type Severity string
 
 const (
@@ -17,11 +19,23 @@ type Diagnostic struct {
 	Line     int      `json:"line,omitempty"`   // 1-based in original .ts
 	Column   int      `json:"column,omitempty"` // 1-based in original .ts
 
+	// Frames is the call stack of a runtime error, innermost call first.
+	Frames []StackFrame `json:"frames,omitempty"`
+
 	// Internal debug fields (hidden from LLM/JSON)
 	GeneratedLine   int `json:"-"`
 	GeneratedColumn int `json:"-"`
 }
 
+// StackFrame is one call of a runtime error's stack trace with its position
+// resolved to the original TypeScript.
+type StackFrame struct {
+	Function string `json:"function,omitempty"` // e.g. "check" or "Parser.parse"; empty for anonymous functions
+	Source   string `json:"source"`             // original .ts file, or "<bundle>" without a source map
+	Line     int    `json:"line"`               // 1-based
+	Column   int    `json:"column"`             // 1-based
+}
+
 // ArtifactRef holds the metadata of an artifact created during script execution.
 // It is used by the MCP tool handler to append resource_link content items.
 type ArtifactRef struct {
@@ -30,17 +44,33 @@ type ArtifactRef struct {
 	Name     string `json:"name"`
 	MimeType string `json:"mimeType"`
 	FileSize int64  `json:"fileSize"`
+
+	// Set for artifacts written via wollmilchsau.openArtifact().
+	Part   int    `json:"part,omitempty"`   // 1-based part number of a streamed handle
+	Status string `json:"status,omitempty"` // ArtifactClosed, ArtifactFinalized or ArtifactAborted
 }
 
+// Outcomes of an openArtifact handle, reported in ArtifactRef.Status.
+const (
+	ArtifactClosed    = "closed"    // closed by the script
+	ArtifactFinalized = "finalized" // left open, uploaded after a successful execution
+	ArtifactAborted   = "aborted"   // left open or failed; uploaded parts were deleted
+)
+
 // Result is the output of a single Execute call.
 // It contains stdout, stderr, exit code and potential diagnostics.
 type Result struct {
-	Stdout           string        `json:"stdout"`           // Standard output captured from console.log
-	Stderr           string        `json:"stderr"`           // Standard error captured from console.warn/error
-	ExitCode         int           `json:"exitCode"`         // 0 for success, non-zero for error
-	Success          bool          `json:"success"`          // true if execution finished without runtime errors
-	DurationMs       int64         `json:"durationMs"`       // execution time in milliseconds
-	Summary          string        `json:"summary"`          // High-level summary of the result
-	Diagnostics      []Diagnostic  `json:"diagnostics"`      // list of errors/warnings with mapped positions
-	CreatedArtifacts []ArtifactRef `json:"createdArtifacts"` // artifacts written via wollmilchsau.openArtifact()
+	Stdout           string          `json:"stdout"`                // Standard output captured from console.log
+	Stderr           string          `json:"stderr"`                // Standard error captured from console.warn/error
+	StdoutBytes      int             `json:"stdoutBytes"`           // original stdout size before truncation
+	StderrBytes      int             `json:"stderrBytes"`           // original stderr size before truncation
+	Truncated        bool            `json:"truncated"`             // true if stdout, stderr or the return value exceeded the output budget
+	ExitCode         int             `json:"exitCode"`              // 0 for success, non-zero for error
+	Success          bool            `json:"success"`               // true if execution finished without runtime errors
+	DurationMs       int64           `json:"durationMs"`            // execution time in milliseconds
+	Summary          string          `json:"summary"`               // High-level summary of the result
+	Diagnostics      []Diagnostic    `json:"diagnostics"`           // list of errors/warnings with mapped positions
+	CreatedArtifacts []ArtifactRef   `json:"createdArtifacts"`      // artifacts written via wollmilchsau.openArtifact()
+	ReturnValue      json.RawMessage `json:"returnValue,omitempty"` // JSON from wollmilchsau.return() or the entry module's default export
+	ReturnValueBytes int             `json:"returnValueBytes"`      // size of a return value left out for exceeding the output budget
 }
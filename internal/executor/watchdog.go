@@ -0,0 +1,138 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package executor
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	v8 "rogchap.com/v8go"
+)
+
+// watchdogInterval is how often heap usage and CPU time are polled.
+const watchdogInterval = 10 * time.Millisecond
+
+// terminationReason records which limit made the watchdog stop the isolate.
+type terminationReason int32
+
+const (
+	reasonNone terminationReason = iota
+	reasonWallTime
+	reasonCPUTime
+	reasonMemory
+	reasonOutput
+)
+
+// watchdog enforces the Limits of a single execution. It terminates the
+// isolate when the wall time expires, the heap grows too large, the CPU time
+// budget is used up or the output limit is hit.
+//
+// The heap limit applies to the growth of the heap since the watchdog was
+// started. A pooled isolate still holds the uncollected garbage of earlier
+// executions, which must not count against the current one.
+//
+// CPU time is measured as the time spent actively executing JavaScript
+// (script, promise jobs and timer callbacks), excluding idle waits for timers.
+type watchdog struct {
+	iso      *v8.Isolate
+	limits   Limits
+	reason   atomic.Int32
+	heapBase uint64 // used heap size when the execution started
+
+	cpuUsed   atomic.Int64 // accumulated busy time in nanoseconds
+	busySince atomic.Int64 // unix nanos when JS execution started, 0 while idle
+
+	done   chan struct{}
+	exited chan struct{}
+}
+
+// startWatchdog starts polling in the background until stop is called.
+func startWatchdog(ctx context.Context, iso *v8.Isolate, limits Limits) *watchdog {
+	w := &watchdog{
+		iso:      iso,
+		limits:   limits,
+		heapBase: iso.GetHeapStatistics().UsedHeapSize,
+		done:     make(chan struct{}),
+		exited:   make(chan struct{}),
+	}
+	go func() {
+		defer close(w.exited)
+		ticker := time.NewTicker(watchdogInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				w.terminate(reasonWallTime)
+				return
+			case <-ticker.C:
+				if w.check() != reasonNone {
+					return
+				}
+			case <-w.done:
+				return
+			}
+		}
+	}()
+	return w
+}
+
+// stop waits for the watchdog goroutine to exit and reports why the isolate
+// was terminated, if at all.
+func (w *watchdog) stop() terminationReason {
+	close(w.done)
+	<-w.exited
+	return w.terminated()
+}
+
+// terminated returns the reason the isolate was terminated, or reasonNone.
+func (w *watchdog) terminated() terminationReason {
+	return terminationReason(w.reason.Load())
+}
+
+// terminate stops JS execution; only the first reason is recorded.
+func (w *watchdog) terminate(r terminationReason) {
+	if w.reason.CompareAndSwap(int32(reasonNone), int32(r)) {
+		w.iso.TerminateExecution()
+	}
+}
+
+// check enforces the heap and CPU time limits and returns the termination reason.
+func (w *watchdog) check() terminationReason {
+	if w.heapUsed() > w.limits.MaxHeapBytes {
+		w.terminate(reasonMemory)
+	} else if w.cpuTime() > time.Duration(w.limits.MaxCPUTimeMs)*time.Millisecond {
+		w.terminate(reasonCPUTime)
+	}
+	return w.terminated()
+}
+
+// heapUsed returns the growth of the heap since the execution started. It is
+// 0 if a garbage collection freed more than the execution allocated.
+func (w *watchdog) heapUsed() uint64 {
+	used := w.iso.GetHeapStatistics().UsedHeapSize
+	if used < w.heapBase {
+		return 0
+	}
+	return used - w.heapBase
+}
+
+// enter marks the start of active JS execution.
+func (w *watchdog) enter() {
+	w.busySince.Store(time.Now().UnixNano())
+}
+
+// leave marks the end of active JS execution.
+func (w *watchdog) leave() {
+	if since := w.busySince.Swap(0); since != 0 {
+		w.cpuUsed.Add(time.Now().UnixNano() - since)
+	}
+}
+
+// cpuTime returns the JS execution time used so far.
+func (w *watchdog) cpuTime() time.Duration {
+	used := w.cpuUsed.Load()
+	if since := w.busySince.Load(); since != 0 {
+		used += time.Now().UnixNano() - since
+	}
+	return time.Duration(used)
+}
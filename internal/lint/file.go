@@ -0,0 +1,319 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package lint
+
+import "strings"
+
+// braceKind tells what a "{" opens.
+type braceKind int
+
+const (
+	kindBlock  braceKind = iota // a block or function body
+	kindObject                  // an object literal, pattern or type literal
+	kindBody                    // the body of a class, enum or interface
+	kindSubst                   // a template substitution
+)
+
+// reserved are the reserved words that cannot be variable names.
+var reserved = map[string]bool{
+	"break": true, "case": true, "catch": true, "class": true, "const": true, "continue": true, "debugger": true,
+	"default": true, "delete": true, "do": true, "else": true, "enum": true, "export": true, "extends": true,
+	"false": true, "finally": true, "for": true, "function": true, "if": true, "import": true, "in": true,
+	"instanceof": true, "new": true, "null": true, "return": true, "super": true, "switch": true, "this": true,
+	"throw": true, "true": true, "try": true, "typeof": true, "var": true, "void": true, "while": true,
+	"with": true, "yield": true, "let": true, "static": true, "await": true, "implements": true,
+	"interface": true, "package": true, "private": true, "protected": true, "public": true,
+}
+
+// file is a scanned source file with the bracket structure resolved.
+type file struct {
+	name string
+	toks []token
+
+	open      []int       // for an opening bracket the index of its closing one, otherwise -1
+	close     []int       // for a closing bracket the index of its opening one, otherwise -1
+	kind      []braceKind // for an opening bracket what it opens
+	enclosing []braceKind // the kind of the innermost brace around each token
+
+	asyncNames map[string]bool // names of async functions in all files of the project
+	decls      []decl          // see declarations
+}
+
+func newFile(name, src string, asyncNames map[string]bool) *file {
+	f := &file{name: name, toks: scan(src), asyncNames: asyncNames}
+	n := len(f.toks)
+	f.open = make([]int, n)
+	f.close = make([]int, n)
+	f.kind = make([]braceKind, n)
+	f.enclosing = make([]braceKind, n)
+
+	var stack []int
+	var kinds []braceKind
+	pendingBody := false
+	for i, t := range f.toks {
+		f.open[i], f.close[i] = -1, -1
+		f.enclosing[i] = kindBlock
+		if len(kinds) > 0 {
+			f.enclosing[i] = kinds[len(kinds)-1]
+		}
+		if isCloser(t) && len(stack) > 0 {
+			o := stack[len(stack)-1]
+			stack, kinds = stack[:len(stack)-1], kinds[:len(kinds)-1]
+			f.open[o], f.close[i] = i, o
+		}
+		switch {
+		case t.kind == tokIdent && (t.text == "class" || t.text == "enum" || t.text == "interface") && !f.isMember(i):
+			pendingBody = true
+		case t.text == "{" && t.kind == tokPunct:
+			f.kind[i] = f.braceKind(i, pendingBody)
+			pendingBody = false
+		case t.kind == tokTemplate:
+			f.kind[i] = kindSubst
+		case t.kind == tokPunct && (t.text == "(" || t.text == "["):
+			f.kind[i] = kindObject // no statements inside, as in an object literal
+		}
+		if isOpener(t) {
+			stack = append(stack, i)
+			kinds = append(kinds, f.kind[i])
+		}
+	}
+	return f
+}
+
+func isOpener(t token) bool {
+	if t.kind == tokTemplate {
+		return strings.HasSuffix(t.text, "${")
+	}
+	return t.kind == tokPunct && (t.text == "(" || t.text == "[" || t.text == "{")
+}
+
+func isCloser(t token) bool {
+	if t.kind == tokTemplate {
+		return strings.HasPrefix(t.text, "}")
+	}
+	return t.kind == tokPunct && (t.text == ")" || t.text == "]" || t.text == "}")
+}
+
+// braceKind decides what the "{" at i opens by the token before it.
+func (f *file) braceKind(i int, pendingBody bool) braceKind {
+	if pendingBody {
+		return kindBody
+	}
+	if i == 0 {
+		return kindBlock
+	}
+	p := f.toks[i-1]
+	switch p.kind {
+	case tokPunct:
+		switch p.text {
+		case ")", ";", "{", "}", "=>":
+			return kindBlock
+		case ":":
+			if f.enclosing[i] == kindBlock && f.afterLabel(i) {
+				return kindBlock
+			}
+		}
+		return kindObject
+	case tokIdent:
+		switch p.text {
+		case "return", "typeof", "in", "of", "case", "yield", "await", "new", "void", "delete", "throw", "instanceof":
+			return kindObject
+		}
+	}
+	return kindBlock
+}
+
+// isMember reports whether the identifier at i is a property name, as in a.b.
+func (f *file) isMember(i int) bool {
+	return i > 0 && f.toks[i-1].kind == tokPunct && (f.toks[i-1].text == "." || f.toks[i-1].text == "?.")
+}
+
+// isName reports whether the token at i can be a variable name.
+func (f *file) isName(i int) bool {
+	return i >= 0 && i < len(f.toks) && f.toks[i].kind == tokIdent && !reserved[f.toks[i].text] && !strings.HasPrefix(f.toks[i].text, "#")
+}
+
+func (f *file) text(i int) string {
+	if i < 0 || i >= len(f.toks) {
+		return ""
+	}
+	return f.toks[i].text
+}
+
+// endsExpr reports whether t can be the last token of an expression, so
+// that a line break after it ends the statement.
+func endsExpr(t token) bool {
+	switch t.kind {
+	case tokIdent:
+		return !reserved[t.text] || t.text == "this" || t.text == "super" || t.text == "true" || t.text == "false" || t.text == "null"
+	case tokPunct:
+		return t.text == ")" || t.text == "]" || t.text == "}" || t.text == "++" || t.text == "--"
+	case tokTemplate:
+		return strings.HasSuffix(t.text, "`")
+	}
+	return true
+}
+
+// beginsExpr reports whether t can only begin a new expression rather than
+// continue the one before it.
+func beginsExpr(t token) bool {
+	switch t.kind {
+	case tokIdent:
+		switch t.text {
+		case "instanceof", "in", "of", "as", "satisfies":
+			return false
+		}
+		return true
+	case tokTemplate:
+		return strings.HasPrefix(t.text, "`")
+	case tokPunct:
+		return false
+	}
+	return true
+}
+
+// stmtStart reports whether the token at i starts a statement.
+func (f *file) stmtStart(i int) bool {
+	if i == 0 {
+		return true
+	}
+	if f.enclosing[i] != kindBlock {
+		return false // class bodies, object literals and parentheses
+	}
+	p := f.toks[i-1]
+	switch {
+	case p.kind == tokPunct && p.text == ";":
+		return true
+	case p.kind == tokPunct && p.text == "{":
+		return f.kind[i-1] == kindBlock
+	case p.kind == tokPunct && p.text == "}" && f.close[i-1] >= 0 && f.kind[f.close[i-1]] == kindBlock:
+		return true
+	case p.kind == tokPunct && p.text == ")" && f.isControlHead(i-1):
+		return true
+	case p.kind == tokPunct && p.text == ":":
+		return f.afterLabel(i)
+	case p.kind == tokIdent && (p.text == "else" || p.text == "do"):
+		return true
+	}
+	return f.toks[i].nl && endsExpr(p)
+}
+
+// isControlHead reports whether the ")" at i closes the head of an if, for,
+// while or with statement.
+func (f *file) isControlHead(i int) bool {
+	o := f.close[i]
+	if o <= 0 {
+		return false
+	}
+	switch f.text(o - 1) {
+	case "if", "for", "while", "with":
+		return true
+	case "await":
+		return f.text(o-2) == "for"
+	}
+	return false
+}
+
+// isForHead reports whether the token at i is the first one in the head of
+// a for statement.
+func (f *file) isForHead(i int) bool {
+	return i > 0 && f.text(i-1) == "(" && (f.text(i-2) == "for" || (f.text(i-2) == "await" && f.text(i-3) == "for"))
+}
+
+// afterLabel reports whether the ":" before i ends a case clause or a label
+// rather than being part of a conditional expression.
+func (f *file) afterLabel(i int) bool {
+	if f.isName(i-2) && f.stmtStart(i-2) {
+		return true
+	}
+	for k := i - 2; k >= 0; k-- {
+		t := f.toks[k]
+		if isCloser(t) && f.close[k] >= 0 {
+			k = f.close[k]
+			continue
+		}
+		switch {
+		case t.kind == tokIdent && (t.text == "case" || t.text == "default"):
+			return true
+		case t.kind == tokPunct && (t.text == "?" || t.text == ";" || t.text == "{" || t.text == "}"):
+			return false
+		}
+	}
+	return false
+}
+
+// skipExpr returns the index of the token that ends the expression starting
+// at i: a ";" or, with stopAtComma, a "," outside of brackets, a closing
+// bracket of an enclosing group, or the first token of the next statement
+// after a line break.
+func (f *file) skipExpr(i int, stopAtComma bool) int {
+	n := len(f.toks)
+	for j := i; j < n; {
+		t := f.toks[j]
+		if j > i && t.nl && endsExpr(f.toks[j-1]) && beginsExpr(t) {
+			return j
+		}
+		switch {
+		case isOpener(t) && !isCloser(t):
+			if j = f.skipGroup(j); j < 0 {
+				return n
+			}
+			continue
+		case isCloser(t):
+			return j
+		case t.kind == tokPunct && t.text == ";":
+			return j
+		case t.kind == tokPunct && t.text == "," && stopAtComma:
+			return j
+		}
+		j++
+	}
+	return n
+}
+
+// skipGroup returns the index after the bracket group opened at i, or -1 if
+// it is not closed. A template literal with substitutions is one group.
+func (f *file) skipGroup(i int) int {
+	j := f.open[i]
+	for j >= 0 && isOpener(f.toks[j]) {
+		j = f.open[j]
+	}
+	if j < 0 {
+		return -1
+	}
+	return j + 1
+}
+
+// skipType returns the index of the token that ends the type annotation
+// starting at i, like skipExpr with stopAtComma, but outside of angle
+// brackets and also at "=" and at "in" or "of" of a for statement.
+func (f *file) skipType(i int) int {
+	n := len(f.toks)
+	angle := 0
+	for j := i; j < n; {
+		t := f.toks[j]
+		if j > i && t.nl && angle == 0 && endsExpr(f.toks[j-1]) && beginsExpr(t) {
+			return j
+		}
+		switch {
+		case isOpener(t) && !isCloser(t):
+			if j = f.skipGroup(j); j < 0 {
+				return n
+			}
+			continue
+		case isCloser(t):
+			return j
+		case t.kind == tokPunct && t.text == "<":
+			angle++
+		case t.kind == tokPunct && t.text == ">" && angle > 0:
+			angle--
+		case t.kind == tokPunct && t.text == ">>" && angle > 0:
+			angle = max(angle-2, 0)
+		case angle == 0 && t.kind == tokPunct && (t.text == "=" || t.text == "," || t.text == ";"):
+			return j
+		case angle == 0 && t.kind == tokIdent && (t.text == "of" || t.text == "in"):
+			return j
+		}
+		j++
+	}
+	return n
+}
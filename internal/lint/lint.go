@@ -0,0 +1,172 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+// Package lint finds common bugs of generated scripts that esbuild accepts,
+// such as unused variables, loose comparisons or promises that are never
+// awaited. The rules work on the tokens of a file rather than a full syntax
+// tree, so they are heuristics: they prefer missing a finding to reporting a
+// false one.
+package lint
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hmsoft0815/wollmilchsau/internal/executor"
+	"github.com/hmsoft0815/wollmilchsau/internal/parser"
+)
+
+// Level is the severity of the findings of a rule.
+type Level string
+
+const (
+	LevelOff     Level = "off"
+	LevelInfo    Level = "info"
+	LevelWarning Level = "warning"
+)
+
+// Rule is a built-in lint rule.
+type Rule struct {
+	Name        string
+	Description string
+	Default     Level // level if not configured
+
+	check func(f *file, report func(at token, msg string))
+}
+
+// rules are the built-in rules, named like their ESLint counterparts.
+var rules = []Rule{
+	{
+		Name:        "no-unused-vars",
+		Description: "variables, functions, classes and imports that are never used",
+		Default:     LevelInfo,
+		check:       checkUnusedVars,
+	},
+	{
+		Name:        "eqeqeq",
+		Description: "== and != instead of === and !== (comparisons with null are allowed)",
+		Default:     LevelWarning,
+		check:       checkEqeqeq,
+	},
+	{
+		Name:        "no-floating-promises",
+		Description: "calls of async functions and Promise.all/allSettled/any/race whose promise is neither awaited nor handled",
+		Default:     LevelWarning,
+		check:       checkFloatingPromises,
+	},
+	{
+		Name:        "no-implicit-globals",
+		Description: "assignments to undeclared variables, which create globals",
+		Default:     LevelWarning,
+		check:       checkImplicitGlobals,
+	},
+	{
+		Name:        "no-unreachable",
+		Description: "statements after return, throw, break and continue",
+		Default:     LevelWarning,
+		check:       checkUnreachable,
+	},
+}
+
+// Rules returns the built-in rules.
+func Rules() []Rule {
+	return append([]Rule(nil), rules...)
+}
+
+// Config sets the level of rules by name, e.g. {"no-unused-vars": "off"}.
+// Rules that are not configured use their default level.
+type Config map[string]Level
+
+// Validate rejects unknown rules and levels.
+func (c Config) Validate() error {
+	for name, level := range c {
+		if !isRule(name) {
+			return fmt.Errorf("unknown lint rule %q", name)
+		}
+		switch level {
+		case LevelOff, LevelInfo, LevelWarning:
+		default:
+			return fmt.Errorf("lint rule %q: level must be off, info or warning, got %q", name, level)
+		}
+	}
+	return nil
+}
+
+// Level returns the level of the rule name.
+func (c Config) Level(name string) Level {
+	if level, ok := c[name]; ok {
+		return level
+	}
+	for _, r := range rules {
+		if r.Name == name {
+			return r.Default
+		}
+	}
+	return LevelOff
+}
+
+// Enabled returns the rules that are not off.
+func (c Config) Enabled() []Rule {
+	var enabled []Rule
+	for _, r := range rules {
+		if c.Level(r.Name) != LevelOff {
+			enabled = append(enabled, r)
+		}
+	}
+	return enabled
+}
+
+func isRule(name string) bool {
+	for _, r := range rules {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// lintExtensions are the extensions of the files that are linted. JSX is
+// not supported by the scanner.
+var lintExtensions = map[string]bool{".ts": true, ".mts": true, ".cts": true, ".js": true, ".mjs": true, ".cjs": true}
+
+// Lint checks the JavaScript and TypeScript files with the rules enabled in
+// cfg. The findings are warnings or infos, with the rule name appended to
+// the message, e.g. "Expected '===' and instead saw '==' (eqeqeq)".
+// Declaration files and other files are skipped.
+func Lint(files []parser.VirtualFile, cfg Config) []executor.Diagnostic {
+	var parsed []*file
+	asyncNames := map[string]bool{}
+	for _, f := range files {
+		if !lintExtensions[path.Ext(f.Name)] || strings.HasSuffix(strings.TrimSuffix(f.Name, path.Ext(f.Name)), ".d") {
+			continue
+		}
+		pf := newFile(f.Name, f.Content, asyncNames)
+		pf.collectAsyncNames()
+		parsed = append(parsed, pf)
+	}
+
+	var diags []executor.Diagnostic
+	for _, f := range parsed {
+		start := len(diags)
+		for _, r := range cfg.Enabled() {
+			sev := executor.Severity(cfg.Level(r.Name))
+			r.check(f, func(at token, msg string) {
+				diags = append(diags, executor.Diagnostic{
+					Severity: sev,
+					Message:  fmt.Sprintf("%s (%s)", msg, r.Name),
+					Source:   f.name,
+					Line:     at.line,
+					Column:   at.col,
+				})
+			})
+		}
+		found := diags[start:]
+		sort.SliceStable(found, func(i, j int) bool {
+			if found[i].Line != found[j].Line {
+				return found[i].Line < found[j].Line
+			}
+			return found[i].Column < found[j].Column
+		})
+	}
+	return diags
+}
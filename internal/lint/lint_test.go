@@ -0,0 +1,178 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package lint
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hmsoft0815/wollmilchsau/internal/parser"
+)
+
+func lint(files []parser.VirtualFile, cfg Config) []string {
+	var out []string
+	for _, d := range Lint(files, cfg) {
+		out = append(out, fmt.Sprintf("%s:%d:%d %s %s", d.Source, d.Line, d.Column, d.Severity, d.Message))
+	}
+	return out
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string // without the file name
+	}{
+		{
+			name: "no-unused-vars",
+			src:  "import { a, b as c } from './x';\nconst unused = 1, { d, e: renamed } = a;\nfunction f() {}\nclass K {}\nexport const kept = d;\nconst _ignored = 1;",
+			want: []string{
+				"1:18 info 'c' is imported but never used (no-unused-vars)",
+				"2:7 info 'unused' is declared but never used (no-unused-vars)",
+				"2:27 info 'renamed' is declared but never used (no-unused-vars)",
+				"3:10 info 'f' is declared but never used (no-unused-vars)",
+				"4:7 info 'K' is declared but never used (no-unused-vars)",
+			},
+		},
+		{
+			name: "eqeqeq",
+			src:  "const a = 1 as number;\nif (a == 1 || a != 2 || a == null) console.log(a === 1, `${a == 2}`);",
+			want: []string{
+				"2:7 warning Expected '===' and instead saw '==' (eqeqeq)",
+				"2:17 warning Expected '!==' and instead saw '!=' (eqeqeq)",
+				"2:62 warning Expected '===' and instead saw '==' (eqeqeq)",
+			},
+		},
+		{
+			name: "no-floating-promises",
+			src: "async function load() { return 1; }\nconst save = async () => {};\nclass S { async put() {} }\nconst s = new S();\n" +
+				"load();\nsave()\ns.put();\nPromise.all([load()]);\n" +
+				"await load();\nvoid save();\nload().catch(console.error);\nconst p = load();\nconsole.log(p);\nconsole.log(load());",
+			want: []string{
+				"5:1 warning The promise returned by load() is neither awaited nor handled; await it, return it or add .catch() (no-floating-promises)",
+				"6:1 warning The promise returned by save() is neither awaited nor handled; await it, return it or add .catch() (no-floating-promises)",
+				"7:1 warning The promise returned by s.put() is neither awaited nor handled; await it, return it or add .catch() (no-floating-promises)",
+				"8:1 warning The promise returned by Promise.all() is neither awaited nor handled; await it, return it or add .catch() (no-floating-promises)",
+			},
+		},
+		{
+			name: "no-implicit-globals",
+			src:  "let y;\ny = 1;\ntotal = 2\nif (y) z = 3;\nfor (k in {}) {}\nclass C { field = 1; m(p) { p = 2; } }\nconst o = { a: 1 };\nconsole.log(o, C);",
+			want: []string{
+				"3:1 warning 'total' is assigned but never declared, which creates a global variable; declare it with let or const (no-implicit-globals)",
+				"4:8 warning 'z' is assigned but never declared, which creates a global variable; declare it with let or const (no-implicit-globals)",
+				"5:6 warning 'k' is assigned but never declared, which creates a global variable; declare it with let or const (no-implicit-globals)",
+			},
+		},
+		{
+			name: "no-unreachable",
+			src: "function f(x: number) {\n  if (x) return 1;\n  switch (x) {\n    case 1: return 2;\n    default: break;\n  }\n  return g()\n  x++;\n" +
+				"  function g() { return 3; }\n}\nwhile (f(1)) {\n  break\n  console.log('never');\n}\nthrow new Error('x');",
+			want: []string{
+				"8:3 warning Unreachable code after 'return' (no-unreachable)",
+				"13:3 warning Unreachable code after 'break' (no-unreachable)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Only the rule under test, at its default level.
+			cfg := Config{}
+			for _, r := range Rules() {
+				if r.Name != tt.name {
+					cfg[r.Name] = LevelOff
+				}
+			}
+			got := lint([]parser.VirtualFile{{Name: "main.ts", Content: tt.src}}, cfg)
+			for i := range got {
+				got[i] = strings.TrimPrefix(got[i], "main.ts:")
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("findings =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+// TestLint_Clean checks that typical valid code has no findings.
+func TestLint_Clean(t *testing.T) {
+	src := `import type { Row } from './types';
+import { parse, type Opts } from './csv';
+export interface Point { x: number; y: number }
+enum Color { Red = 1, Green = 2 }
+declare const injected: number;
+const { rows, meta: { total = 0 } = {} } = wollmilchsau.input as { rows: Row[]; meta?: { total?: number } };
+const [first, , third] = rows;
+let counter = 0, limit: Map<string, number> = new Map();
+for (const r of rows) { counter += r.value; }
+const re = /ab+c/gi, half = counter / 2;
+const msg = ` + "`total ${counter} ${`nested ${half}`}`" + `;
+class Store<T> {
+  private items: T[] = [];
+  count = 0;
+  constructor(private readonly name: string) {}
+  async save(item: T): Promise<void> { this.items.push(item); this.count++; }
+}
+const store = new Store<number>('s');
+async function main(): Promise<number> {
+  const results = await Promise.all([store.save(2), store.save(3)]);
+  console.log(results);
+  store.save(5).catch(console.error);
+  try {
+    JSON.parse('{');
+  } catch (err) {
+    console.error(err);
+  }
+  switch (first) {
+    case 1:
+      return 1;
+    default:
+      throw new Error('x');
+  }
+}
+const fn = (a: number, b = 2) => a + b;
+const ok = first === 1 ? fn(1) : { fn }.fn(2);
+console.log(ok, limit, first == null, third, total, re, msg, Color.Red, injected, parse('', {} as Opts));
+main().then((n) => console.log(n));
+`
+	if got := lint([]parser.VirtualFile{{Name: "main.ts", Content: src}}, nil); len(got) != 0 {
+		t.Errorf("findings in valid code:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestLint_Project(t *testing.T) {
+	files := []parser.VirtualFile{
+		{Name: "main.ts", Content: "import { load } from './lib/load';\nload();"},
+		{Name: "lib/load.ts", Content: "export async function load() { return 1 == 1; }"},
+		{Name: "types.d.ts", Content: "declare const x: number;\nlet unused;"},
+		{Name: "view.tsx", Content: "const a = <div>it's</div>;"},
+		{Name: "data.json", Content: `{"a": 1}`},
+	}
+	got := lint(files, Config{"eqeqeq": LevelInfo})
+	want := []string{
+		// Async functions are known across files.
+		"main.ts:2:1 warning The promise returned by load() is neither awaited nor handled; await it, return it or add .catch() (no-floating-promises)",
+		"lib/load.ts:1:41 info Expected '===' and instead saw '==' (eqeqeq)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("findings =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestConfig(t *testing.T) {
+	cfg := Config{"eqeqeq": LevelOff, "no-unused-vars": LevelWarning}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Level("eqeqeq") != LevelOff || cfg.Level("no-unused-vars") != LevelWarning || cfg.Level("no-unreachable") != LevelWarning {
+		t.Errorf("unexpected levels: %v", cfg)
+	}
+	if n := len(cfg.Enabled()); n != len(Rules())-1 {
+		t.Errorf("%d rules enabled", n)
+	}
+	for _, bad := range []Config{{"no-such-rule": LevelInfo}, {"eqeqeq": "error"}} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("Validate(%v) = nil", bad)
+		}
+	}
+}
@@ -0,0 +1,358 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package lint
+
+import (
+	"fmt"
+	"strings"
+)
+
+// declKind is the kind of a declared name.
+type declKind int
+
+const (
+	declVar      declKind = iota // var, let or const
+	declFunction                 // function declaration
+	declClass                    // class declaration
+	declImport                   // import binding
+	declOther                    // parameters, enums, namespaces, exported or ambient declarations
+)
+
+// decl is a declared name.
+type decl struct {
+	at   int // token index of the name
+	kind declKind
+}
+
+// declarations returns the names declared in the file. Names that are
+// exported or declared with declare are declOther, since they may be used
+// elsewhere.
+func (f *file) declarations() []decl {
+	if f.decls != nil {
+		return f.decls
+	}
+	f.decls = []decl{}
+	add := func(at int, kind declKind) {
+		if f.isName(at) {
+			f.decls = append(f.decls, decl{at: at, kind: kind})
+		}
+	}
+	for i, t := range f.toks {
+		if t.kind != tokIdent || f.isMember(i) {
+			continue
+		}
+		switch t.text {
+		case "var", "let", "const":
+			if f.text(i+1) == "enum" || (t.text == "let" && !f.isName(i+1) && f.text(i+1) != "{" && f.text(i+1) != "[") {
+				continue
+			}
+			kind := declVar
+			if f.isExported(i) {
+				kind = declOther
+			}
+			f.declarators(i+1, func(at int) { add(at, kind) })
+		case "function", "class":
+			name := i + 1
+			if f.text(name) == "*" {
+				name++
+			}
+			start := i
+			if f.text(i-1) == "async" {
+				start = i - 1
+			}
+			kind := declFunction
+			if t.text == "class" {
+				kind = declClass
+			}
+			switch {
+			case f.isExported(start):
+				add(name, declOther)
+			case f.stmtStart(start):
+				add(name, kind)
+			}
+		case "enum", "namespace", "module":
+			if f.stmtStart(i) || f.isExported(i) || f.text(i-1) == "const" {
+				add(i+1, declOther)
+			}
+		case "import":
+			if f.stmtStart(i) {
+				f.importBindings(i+1, func(at int) { add(at, declImport) })
+			}
+		}
+	}
+
+	// Parameters of functions, arrow functions, methods and catch clauses.
+	for i, t := range f.toks {
+		switch {
+		case t.kind == tokPunct && t.text == "(" && f.open[i] >= 0 && !controlKeywords[f.text(i-1)]:
+			switch f.text(f.open[i] + 1) {
+			case "=>", "{", ":":
+				for k := i + 1; k < f.open[i]; k++ {
+					if !f.isMember(k) {
+						add(k, declOther) // includes type names, which does no harm
+					}
+				}
+			}
+		case t.kind == tokPunct && t.text == "=>":
+			add(i-1, declOther)
+		}
+	}
+	return f.decls
+}
+
+// controlKeywords are followed by parentheses that hold no parameters.
+var controlKeywords = map[string]bool{"if": true, "for": true, "while": true, "switch": true, "with": true, "await": true}
+
+// isExported reports whether the declaration starting at i is exported or
+// ambient.
+func (f *file) isExported(i int) bool {
+	switch f.text(i - 1) {
+	case "export", "declare":
+		return true
+	case "default":
+		return f.text(i-2) == "export"
+	}
+	return false
+}
+
+// declarators calls add for the names declared by the declarators of a
+// var, let or const statement starting at i.
+func (f *file) declarators(i int, add func(at int)) {
+	for i < len(f.toks) {
+		switch {
+		case f.isName(i):
+			add(i)
+			i++
+		case (f.text(i) == "{" || f.text(i) == "[") && f.open[i] >= 0:
+			f.pattern(i, add)
+			i = f.open[i] + 1
+		default:
+			return
+		}
+		if f.text(i) == "!" {
+			i++ // definite assignment, let x!: T
+		}
+		if f.text(i) == ":" {
+			i = f.skipType(i + 1)
+		}
+		if f.text(i) == "=" {
+			i = f.skipExpr(i+1, true)
+		}
+		if f.text(i) != "," {
+			return
+		}
+		i++
+	}
+}
+
+// pattern calls add for the names bound by the destructuring pattern opened
+// at i. Property names and default values are skipped.
+func (f *file) pattern(i int, add func(at int)) {
+	end := f.open[i]
+	for k := i + 1; k < end; k++ {
+		switch {
+		case f.text(k) == "=":
+			k = f.skipExpr(k+1, true) - 1
+		case f.isName(k) && f.text(k+1) != ":":
+			add(k)
+		}
+	}
+}
+
+// importBindings calls add for the bindings of the import declaration whose
+// clause starts at i. Imports of types only are skipped.
+func (f *file) importBindings(i int, add func(at int)) {
+	if f.text(i) == "type" && f.text(i+1) != "," && f.text(i+1) != "from" {
+		return
+	}
+	for k := i; k < len(f.toks); k++ {
+		t := f.toks[k]
+		switch {
+		case t.kind == tokString, t.text == "from", t.text == ";", t.text == "(", t.text == ".", t.text == "=":
+			return
+		case t.text == "{" && f.open[k] >= 0:
+			for m := k + 1; m < f.open[k]; m++ {
+				switch {
+				case f.text(m) == "type" && f.isName(m+1):
+					m++
+					for m < f.open[k] && f.text(m) != "," {
+						m++
+					}
+				case f.isName(m) && f.text(m) != "as" && f.text(m+1) != "as":
+					add(m)
+				}
+			}
+			k = f.open[k]
+		case f.isName(k) && t.text != "as" && f.text(k+1) != "as":
+			add(k)
+		}
+	}
+}
+
+// uses counts the uses of each name: the identifiers that are neither
+// declarations of a checked kind nor property names.
+func (f *file) uses() map[string]int {
+	declared := map[int]bool{}
+	for _, d := range f.declarations() {
+		if d.kind != declOther {
+			declared[d.at] = true
+		}
+	}
+	uses := map[string]int{}
+	for i, t := range f.toks {
+		if t.kind == tokIdent && !declared[i] && !f.isMember(i) {
+			uses[t.text]++
+		}
+	}
+	return uses
+}
+
+func checkUnusedVars(f *file, report func(at token, msg string)) {
+	uses := f.uses()
+	for _, d := range f.declarations() {
+		t := f.toks[d.at]
+		if d.kind == declOther || uses[t.text] > 0 || strings.HasPrefix(t.text, "_") {
+			continue
+		}
+		if d.kind == declImport {
+			report(t, fmt.Sprintf("'%s' is imported but never used", t.text))
+		} else {
+			report(t, fmt.Sprintf("'%s' is declared but never used", t.text))
+		}
+	}
+}
+
+func checkEqeqeq(f *file, report func(at token, msg string)) {
+	for i, t := range f.toks {
+		if t.kind != tokPunct || (t.text != "==" && t.text != "!=") {
+			continue
+		}
+		// x == null is the idiom for null or undefined.
+		if f.text(i-1) == "null" || f.text(i+1) == "null" {
+			continue
+		}
+		report(t, fmt.Sprintf("Expected '%s=' and instead saw '%s'", t.text, t.text))
+	}
+}
+
+// moduleGlobals are assigned by CommonJS modules.
+var moduleGlobals = map[string]bool{"exports": true, "module": true, "require": true}
+
+func checkImplicitGlobals(f *file, report func(at token, msg string)) {
+	declared := map[string]bool{}
+	for _, d := range f.declarations() {
+		declared[f.toks[d.at].text] = true
+	}
+	for i, t := range f.toks {
+		if !f.isName(i) || f.isMember(i) || declared[t.text] || moduleGlobals[t.text] {
+			continue
+		}
+		next := f.text(i + 1)
+		assigned := next == "=" && f.toks[i+1].kind == tokPunct && f.stmtStart(i)
+		if f.isForHead(i) && (next == "=" || next == "of" || next == "in") {
+			assigned = true
+		}
+		if assigned {
+			report(t, fmt.Sprintf("'%s' is assigned but never declared, which creates a global variable; declare it with let or const", t.text))
+		}
+	}
+}
+
+// collectAsyncNames adds the names of the async functions and methods
+// declared in the file to f.asyncNames.
+func (f *file) collectAsyncNames() {
+	for i, t := range f.toks {
+		if t.kind != tokIdent || t.text != "async" || f.isMember(i) || i+1 >= len(f.toks) {
+			continue
+		}
+		switch {
+		case f.text(i+1) == "function" && f.isName(i+2):
+			f.asyncNames[f.text(i+2)] = true // async function name()
+		case f.toks[i+1].kind == tokIdent && !f.toks[i+1].nl && (f.text(i+2) == "(" || f.text(i+2) == "<"):
+			f.asyncNames[f.text(i+1)] = true // async name() in a class or object
+		case (f.text(i-1) == "=" || f.text(i-1) == ":") && i >= 2 && f.toks[i-2].kind == tokIdent:
+			f.asyncNames[f.text(i-2)] = true // name = async () => ..., name: async function
+		}
+	}
+}
+
+// promiseCombinators are the static methods of Promise that return a new promise.
+var promiseCombinators = map[string]bool{"all": true, "allSettled": true, "any": true, "race": true}
+
+func checkFloatingPromises(f *file, report func(at token, msg string)) {
+	n := len(f.toks)
+	for i, t := range f.toks {
+		if t.kind != tokIdent || (reserved[t.text] && t.text != "this") || f.isMember(i) || !f.stmtStart(i) {
+			continue
+		}
+		// A call like name(...) or a.b.name(...) as a statement of its own.
+		var chain []string
+		j := i
+		for j < n && f.toks[j].kind == tokIdent {
+			chain = append(chain, f.toks[j].text)
+			j++
+			if (f.text(j) == "." || f.text(j) == "?.") && j+1 < n && f.toks[j+1].kind == tokIdent {
+				j++
+				continue
+			}
+			break
+		}
+		if f.text(j) != "(" || f.open[j] < 0 {
+			continue
+		}
+		k := f.open[j] + 1
+		if k < n {
+			switch f.text(k) {
+			case ".", "?.", "(", "[":
+				continue // handled by the chained call, e.g. .catch()
+			}
+			if f.text(k) != ";" && f.text(k) != "}" && !f.toks[k].nl {
+				continue // part of a larger expression
+			}
+		}
+		last := chain[len(chain)-1]
+		if f.asyncNames[last] || (len(chain) == 2 && chain[0] == "Promise" && promiseCombinators[last]) {
+			report(t, fmt.Sprintf("The promise returned by %s() is neither awaited nor handled; await it, return it or add .catch()", strings.Join(chain, ".")))
+		}
+	}
+}
+
+func checkUnreachable(f *file, report func(at token, msg string)) {
+	n := len(f.toks)
+	for i, t := range f.toks {
+		if t.kind != tokIdent || f.isMember(i) {
+			continue
+		}
+		switch t.text {
+		case "return", "throw", "break", "continue":
+		default:
+			continue
+		}
+		// The body of an if, else or loop without braces is a single statement.
+		if i > 0 && (f.text(i-1) == ")" || f.text(i-1) == "else" || f.text(i-1) == "do") || !f.stmtStart(i) {
+			continue
+		}
+		j := i + 1
+		switch t.text {
+		case "break", "continue":
+			if f.isName(j) && !f.toks[j].nl {
+				j++ // label
+			}
+		default:
+			if j < n && !f.toks[j].nl && f.text(j) != ";" && f.text(j) != "}" {
+				j = f.skipExpr(j, false)
+			}
+		}
+		if f.text(j) == ";" {
+			j++
+		}
+		if j >= n || isCloser(f.toks[j]) {
+			continue
+		}
+		switch f.text(j) {
+		case "case", "default", "function", "type", "interface", "declare":
+			// Other clauses and hoisted declarations are reachable.
+			continue
+		}
+		report(f.toks[j], fmt.Sprintf("Unreachable code after '%s'", t.text))
+	}
+}
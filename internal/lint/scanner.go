@@ -0,0 +1,237 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package lint
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota // identifiers and keywords
+	tokNumber
+	tokString
+	tokTemplate // a part of a template literal between substitutions
+	tokRegex
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int  // 1-based
+	col  int  // 1-based, in bytes
+	nl   bool // preceded by a line break
+}
+
+// punctuators are matched longest first.
+var punctuators = []string{
+	">>>=", "...", "===", "!==", "**=", "<<=", ">>=", ">>>", "&&=", "||=", "??=",
+	"=>", "==", "!=", "<=", ">=", "&&", "||", "??", "?.", "++", "--", "+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "**", "<<", ">>",
+}
+
+// regexAfter are the keywords after which a slash starts a regular expression.
+var regexAfter = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true, "new": true, "delete": true,
+	"void": true, "throw": true, "case": true, "do": true, "else": true, "yield": true, "await": true,
+}
+
+// scan splits JavaScript or TypeScript source into tokens, without comments.
+// It does not validate the source: unterminated literals end at the end of
+// the source, so a file with syntax errors is still scanned completely.
+func scan(src string) []token {
+	s := &scanner{src: src, line: 1, lineStart: 0}
+	s.run()
+	return s.toks
+}
+
+type scanner struct {
+	src       string
+	pos       int
+	line      int
+	lineStart int
+	nl        bool
+	toks      []token
+	braces    []bool // open braces; true for a template substitution
+}
+
+func (s *scanner) emit(kind tokenKind, start, line, col int) {
+	s.toks = append(s.toks, token{kind: kind, text: s.src[start:s.pos], line: line, col: col, nl: s.nl})
+	s.nl = false
+}
+
+// advance moves past one byte, counting lines.
+func (s *scanner) advance() {
+	if s.src[s.pos] == '\n' {
+		s.line++
+		s.lineStart = s.pos + 1
+	}
+	s.pos++
+}
+
+func (s *scanner) run() {
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case c == '\n':
+			s.advance()
+			s.nl = true
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			s.pos++
+			continue
+		case strings.HasPrefix(s.src[s.pos:], "//"):
+			for s.pos < len(s.src) && s.src[s.pos] != '\n' {
+				s.pos++
+			}
+			continue
+		case strings.HasPrefix(s.src[s.pos:], "/*"):
+			s.pos += 2
+			for s.pos < len(s.src) && !strings.HasPrefix(s.src[s.pos:], "*/") {
+				if s.src[s.pos] == '\n' {
+					s.nl = true
+				}
+				s.advance()
+			}
+			s.pos = min(s.pos+2, len(s.src))
+			continue
+		}
+
+		start, line, col := s.pos, s.line, s.pos-s.lineStart+1
+		switch {
+		case isIdentStart(s.src[s.pos:]) || c == '#':
+			s.pos++
+			for s.pos < len(s.src) && isIdentPart(s.src[s.pos:]) {
+				_, size := utf8.DecodeRuneInString(s.src[s.pos:])
+				s.pos += size
+			}
+			s.emit(tokIdent, start, line, col)
+		case isDigit(c) || (c == '.' && s.pos+1 < len(s.src) && isDigit(s.src[s.pos+1])):
+			for s.pos < len(s.src) && (isIdentPart(s.src[s.pos:]) || s.src[s.pos] == '.') {
+				s.pos++
+			}
+			s.emit(tokNumber, start, line, col)
+		case c == '"' || c == '\'':
+			s.pos++
+			for s.pos < len(s.src) && s.src[s.pos] != c && s.src[s.pos] != '\n' {
+				if s.src[s.pos] == '\\' && s.pos+1 < len(s.src) {
+					s.pos++
+				}
+				s.advance()
+			}
+			s.pos = min(s.pos+1, len(s.src))
+			s.emit(tokString, start, line, col)
+		case c == '`':
+			s.pos++
+			s.template(start, line, col)
+		case c == '}' && len(s.braces) > 0 && s.braces[len(s.braces)-1]:
+			// The end of a template substitution continues the template.
+			s.braces = s.braces[:len(s.braces)-1]
+			s.pos++
+			s.template(start, line, col)
+		case c == '/' && s.regexAllowed():
+			s.regex()
+			s.emit(tokRegex, start, line, col)
+		default:
+			s.punct()
+			switch s.src[start:s.pos] {
+			case "{":
+				s.braces = append(s.braces, false)
+			case "}":
+				if len(s.braces) > 0 {
+					s.braces = s.braces[:len(s.braces)-1]
+				}
+			}
+			s.emit(tokPunct, start, line, col)
+		}
+	}
+}
+
+// template scans a template literal up to its end or the next substitution.
+func (s *scanner) template(start, line, col int) {
+	for s.pos < len(s.src) {
+		switch {
+		case s.src[s.pos] == '\\':
+			s.pos++
+			if s.pos < len(s.src) {
+				s.advance()
+			}
+		case s.src[s.pos] == '`':
+			s.pos++
+			s.emit(tokTemplate, start, line, col)
+			return
+		case strings.HasPrefix(s.src[s.pos:], "${"):
+			s.pos += 2
+			s.braces = append(s.braces, true)
+			s.emit(tokTemplate, start, line, col)
+			return
+		default:
+			s.advance()
+		}
+	}
+	s.emit(tokTemplate, start, line, col)
+}
+
+// regexAllowed reports whether a slash starts a regular expression rather
+// than a division, judged by the previous token.
+func (s *scanner) regexAllowed() bool {
+	if len(s.toks) == 0 {
+		return true
+	}
+	prev := s.toks[len(s.toks)-1]
+	switch prev.kind {
+	case tokIdent:
+		return regexAfter[prev.text]
+	case tokPunct:
+		return prev.text != ")" && prev.text != "]" && prev.text != "}"
+	}
+	return false
+}
+
+func (s *scanner) regex() {
+	s.pos++
+	inClass := false
+	for s.pos < len(s.src) && s.src[s.pos] != '\n' {
+		c := s.src[s.pos]
+		s.pos++
+		switch {
+		case c == '\\':
+			s.pos++
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == '/' && !inClass:
+			for s.pos < len(s.src) && isIdentPart(s.src[s.pos:]) {
+				s.pos++
+			}
+			return
+		}
+	}
+	s.pos = min(s.pos, len(s.src))
+}
+
+func (s *scanner) punct() {
+	for _, p := range punctuators {
+		if strings.HasPrefix(s.src[s.pos:], p) {
+			s.pos += len(p)
+			return
+		}
+	}
+	_, size := utf8.DecodeRuneInString(s.src[s.pos:])
+	s.pos += size
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(s string) bool {
+	c := s[0]
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '$' || c >= utf8.RuneSelf
+}
+
+func isIdentPart(s string) bool {
+	return isIdentStart(s) || isDigit(s[0])
+}
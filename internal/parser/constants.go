@@ -14,3 +14,9 @@ const (
 const (
 	onlyAllowedChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789._-/"
 )
+
+// Bounds for ExecutionPlan.TimeoutMs.
+const (
+	MinTimeoutMs        = 100
+	DefaultMaxTimeoutMs = 30_000
+)
@@ -8,7 +8,14 @@ import (
 )
 
 // ValidatePlan ensures the ExecutionPlan is logically sound.
+// The timeout is clamped to [MinTimeoutMs, DefaultMaxTimeoutMs].
 func ValidatePlan(plan *ExecutionPlan) error {
+	return ValidatePlanWithMaxTimeout(plan, DefaultMaxTimeoutMs)
+}
+
+// ValidatePlanWithMaxTimeout is like ValidatePlan, but clamps the timeout to
+// the given operator-configured maximum instead of DefaultMaxTimeoutMs.
+func ValidatePlanWithMaxTimeout(plan *ExecutionPlan, maxTimeoutMs int) error {
 	if len(plan.Files) == 0 {
 		return &ParseError{Message: "at least one file required"}
 	}
@@ -37,11 +44,14 @@ func ValidatePlan(plan *ExecutionPlan) error {
 	}
 
 	// Clamp timeout
-	if plan.TimeoutMs < 100 {
-		plan.TimeoutMs = 100
+	if maxTimeoutMs < MinTimeoutMs {
+		maxTimeoutMs = MinTimeoutMs
+	}
+	if plan.TimeoutMs < MinTimeoutMs {
+		plan.TimeoutMs = MinTimeoutMs
 	}
-	if plan.TimeoutMs > 30_000 {
-		plan.TimeoutMs = 30_000
+	if plan.TimeoutMs > maxTimeoutMs {
+		plan.TimeoutMs = maxTimeoutMs
 	}
 
 	return nil
@@ -83,3 +83,28 @@ func TestValidateFilename(t *testing.T) {
 		})
 	}
 }
+
+func TestValidatePlanClampsTimeout(t *testing.T) {
+	tests := []struct {
+		timeout, max, want int
+	}{
+		{50, DefaultMaxTimeoutMs, MinTimeoutMs},
+		{60_000, DefaultMaxTimeoutMs, DefaultMaxTimeoutMs},
+		{5_000, 2_000, 2_000},
+		{5_000, 10, MinTimeoutMs},
+	}
+
+	for _, tt := range tests {
+		plan := &ExecutionPlan{
+			Files:      []VirtualFile{{Name: mainTS, Content: "1"}},
+			EntryPoint: mainTS,
+			TimeoutMs:  tt.timeout,
+		}
+		if err := ValidatePlanWithMaxTimeout(plan, tt.max); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if plan.TimeoutMs != tt.want {
+			t.Errorf("timeout %d with max %d: got %d, want %d", tt.timeout, tt.max, plan.TimeoutMs, tt.want)
+		}
+	}
+}
@@ -21,6 +21,7 @@ type Entry struct {
 	RemoteIP  string                `json:"remoteIp"`
 	Tool      string                `json:"tool"`
 	Plan      *parser.ExecutionPlan `json:"plan"`
+	Input     *executor.Input       `json:"-"` // stored as a separate file
 	Result    *executor.Result      `json:"result"`
 }
 
@@ -62,7 +63,18 @@ func LogRequest(dir string, entry Entry) (string, error) {
 		}
 	}
 
-	// 3. Add response.json
+	// 3. Add the input data, if any
+	if entry.Input != nil {
+		name := "input.txt"
+		if entry.Input.JSON {
+			name = "input.json"
+		}
+		if err := addFileToZip(zw, name, []byte(entry.Input.Text)); err != nil {
+			return path, err
+		}
+	}
+
+	// 4. Add response.json
 	respJSON, _ := json.MarshalIndent(entry.Result, "", "  ")
 	if err := addFileToZip(zw, "response.json", respJSON); err != nil {
 		return path, err
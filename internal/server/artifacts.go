@@ -0,0 +1,145 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/hmsoft0815/wollmilchsau/internal/executor"
+)
+
+// inputArtifactRef is one entry of the 'inputArtifacts' tool parameter.
+type inputArtifactRef struct {
+	ID     string `json:"id"`
+	Alias  string `json:"alias,omitempty"`
+	UserID string `json:"userId,omitempty"`
+}
+
+// inputArtifactsFromArgs decodes the optional 'inputArtifacts' tool parameter.
+// Entries may be objects or plain artifact IDs.
+func inputArtifactsFromArgs(args map[string]any) []inputArtifactRef {
+	raw, _ := args[ParamInputArtifacts].([]any)
+	refs := make([]inputArtifactRef, 0, len(raw))
+	for _, item := range raw {
+		if id, ok := item.(string); ok {
+			refs = append(refs, inputArtifactRef{ID: id})
+			continue
+		}
+		var ref inputArtifactRef
+		b, err := json.Marshal(item)
+		if err == nil {
+			err = json.Unmarshal(b, &ref)
+		}
+		if err != nil {
+			slog.Warn("ignoring malformed input artifact", "err", err)
+			continue
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// artifactStore returns the artifact store shared by all executions, or nil
+// if artifacts are disabled.
+func (s *WollmilchsauServer) artifactStore() executor.ArtifactStore {
+	if !s.EnableArtifacts {
+		return nil
+	}
+	return s.Artifacts
+}
+
+// fetchInputArtifacts reads the requested artifacts before the execution.
+// Each file is exposed under its alias, which defaults to the artifact's
+// filename.
+//
+// Together the files may hold at most maxBytes. The size of each artifact is
+// looked up in the listing of the store first, so that an artifact larger
+// than the rest of the limit is rejected without downloading it.
+func (s *WollmilchsauServer) fetchInputArtifacts(ctx context.Context, refs []inputArtifactRef, maxBytes int) ([]executor.InputFile, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	store := s.artifactStore()
+	if store == nil {
+		return nil, errors.New("input artifacts require the artifact service (-enable-artifacts)")
+	}
+
+	files := make([]executor.InputFile, 0, len(refs))
+	seen := make(map[string]bool, len(refs))
+	listings := make(map[string][]*executor.ArtifactInfo)
+	remaining := maxBytes
+	for _, ref := range refs {
+		if ref.ID == "" {
+			return nil, errors.New("input artifact without id")
+		}
+		if size, ok := listedSize(ctx, store, listings, ref); ok && size > int64(remaining) {
+			return nil, inputArtifactTooLarge(ref, size, remaining)
+		}
+		res, err := store.Read(ctx, ref.ID, ref.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("artifact %q: %w", ref.ID, err)
+		}
+		// The listing may lack the size or be outdated.
+		if len(res.Content) > remaining {
+			return nil, inputArtifactTooLarge(ref, int64(len(res.Content)), remaining)
+		}
+		remaining -= len(res.Content)
+
+		alias := ref.Alias
+		if alias == "" {
+			alias = res.Filename
+		}
+		if alias == "" {
+			alias = ref.ID
+		}
+		if seen[alias] {
+			return nil, fmt.Errorf("duplicate input artifact alias %q", alias)
+		}
+		seen[alias] = true
+
+		files = append(files, executor.InputFile{
+			Alias:    alias,
+			ID:       ref.ID,
+			Name:     res.Filename,
+			MimeType: res.MimeType,
+			Content:  res.Content,
+		})
+	}
+	return files, nil
+}
+
+// listedSize returns the size of the artifact ref from the listing of its
+// user, which is fetched once per user into listings. It reports false if
+// the listing fails or does not identify the artifact unambiguously.
+func listedSize(ctx context.Context, store executor.ArtifactStore, listings map[string][]*executor.ArtifactInfo, ref inputArtifactRef) (int64, bool) {
+	infos, ok := listings[ref.UserID]
+	if !ok {
+		var err error
+		if infos, err = store.List(ctx, ref.UserID); err != nil {
+			slog.Warn("failed to list artifacts for the input size check", "err", err)
+		}
+		listings[ref.UserID] = infos
+	}
+	var match *executor.ArtifactInfo
+	for _, info := range infos {
+		switch {
+		case info.ID == ref.ID:
+			return info.SizeBytes, info.SizeBytes > 0
+		case info.Filename == ref.ID && match == nil:
+			match = info
+		case info.Filename == ref.ID:
+			return 0, false // several versions, Read picks one of them
+		}
+	}
+	if match == nil || match.SizeBytes == 0 {
+		return 0, false
+	}
+	return match.SizeBytes, true
+}
+
+func inputArtifactTooLarge(ref inputArtifactRef, size int64, remaining int) error {
+	return fmt.Errorf("artifact %q is too large: %d bytes, but only %d bytes of the input limit (maxInputBytes) are left", ref.ID, size, remaining)
+}
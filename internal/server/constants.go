@@ -1,11 +1,26 @@
 // Copyright (c) 2026 Michael Lechner. All rights reserved.
 package server
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hmsoft0815/wollmilchsau/internal/bundler"
+	"github.com/hmsoft0815/wollmilchsau/internal/executor"
+	"github.com/hmsoft0815/wollmilchsau/internal/lint"
+)
+
 const (
 	ServerName    = "wollmilchsau"
 	ServerVersion = "2.2.2"
 	ServerTitle   = "Wollmilchsau – TypeScript Execution Engine"
 
+	// DefaultPoolSize is the default number of warm V8 isolates.
+	DefaultPoolSize = 2
+
+	// defaultTimeoutMs is used when a tool call does not specify timeoutMs.
+	defaultTimeoutMs = 10_000
+
 	// mimeTypeSVG is the shared MIME type constant for inline SVG icons.
 	mimeTypeSVG = "image/svg+xml"
 
@@ -13,17 +28,36 @@ const (
 	executionConstraintsBase = "\n\nExecution Environment Constraints:\n" +
 		"- Standard: Pure ECMA-262 compliant JavaScript (V8 Sandbox).\n" +
 		"- No Network: 'fetch', 'XMLHttpRequest' or any other network access is NOT available.\n" +
-		"- No Timers: 'setTimeout', 'setInterval', 'setImmediate' are NOT available (Execution is synchronous).\n" +
-		"- No Node.js/Web APIs: No 'fs', 'os', 'process' (except basic console), or DOM APIs.\n" +
+		"- Timers: 'setTimeout', 'setInterval', 'clearTimeout', 'clearInterval' and 'queueMicrotask' are available. The script runs until no timers are pending or the timeout expires. 'setImmediate' is NOT available.\n" +
+		"- Async: 'async'/'await', top-level 'await' and Promise chains are supported. A promise that is rejected without a handler (e.g. an async main() called without 'await') ends the script with a runtime error.\n" +
+		"- No Node.js/Web APIs: No 'fs', 'os', 'process' or DOM APIs.\n" +
 		"- Limited i18n: The 'Intl' object is available but limited to 'en-US' locale.\n"
 
 	executionConstraintsArtifacts = "- Artifact Service: A global 'artifact' object is available for persistent storage:\n" +
-		"  - artifact.write(filename: string, content: string|Uint8Array, mimeType?: string, expiresHours?: number, description?: string, userId?: string): Promise<{id, filename, uri, expires_at}>\n" +
-		"  - artifact.read(id: string, userId?: string): Promise<{content: Uint8Array, mime_type, filename}>\n" +
-		"  - artifact.list(userId?: string): Promise<Array<{id, filename, mime_type, ...}>>\n" +
-		"  - artifact.delete(id: string, userId?: string): Promise<{deleted: boolean}>\n"
+		"  - artifact.write(filename: string, content: string|Uint8Array|ArrayBuffer, mimeType?: string, expiresHours?: number, description?: string, userId?: string): Promise<ArtifactInfo>\n" +
+		"  - artifact.read(idOrFilename: string, userId?: string): Promise<ArtifactInfo & {content: Uint8Array}>\n" +
+		"  - artifact.list(userId?: string): Promise<ArtifactInfo[]>\n" +
+		"  - artifact.delete(idOrFilename: string, userId?: string): Promise<{deleted: boolean}>\n" +
+		"  - ArtifactInfo is {id, filename, uri?, mime_type?, source?, created_at?, expires_at?, size_bytes?, user_id?, description?, metadata?}.\n" +
+		"  - Failures reject with an ArtifactError (subclasses ArtifactNotFoundError, ArtifactPermissionError, ArtifactUnavailableError) that has a 'code'. Always 'await' the calls.\n" +
+		"  - wollmilchsau.openArtifact(name: string, mimeType?: string): {write(data: string|Uint8Array|ArrayBuffer): void, close(): Promise<{id, uri, name, mimeType, fileSize, status}>}\n" +
+		"  - For large outputs use openArtifact(name, mimeType, {stream: true, chunkBytes?: number}): the content is uploaded in parts (name.part-001.ext, ...) while writing; " +
+		"write() returns a Promise, 'await fh.write(...)' to bound memory, and close() resolves to {name, mimeType, fileSize, status, parts}. " +
+		"Each handle is limited to maxArtifactBytes. Handles left open are uploaded after a successful run and deleted after a failed one.\n" +
+		"- Input Artifacts: Artifacts listed in the 'inputArtifacts' parameter are read before execution and available as 'wollmilchsau.files[alias]' " +
+		"with text(), bytes() and json() accessors.\n"
+
+	executionConstraintsFooter = "- Output: Use 'console.log()' to return data to the user. Objects are formatted like Node's util.inspect; " +
+		"printf-style placeholders (%s %d %i %f %j %o %O), console.table, console.dir, console.group, console.count, console.time/timeEnd, console.assert and console.trace are supported.\n" +
+		"- Return Value: To return structured data, 'export default' a JSON-serializable value (or a Promise of one, which is awaited) from the entry file or call 'wollmilchsau.return(value)'. " +
+		"It is returned as 'returnValue' in the structured result; a value larger than the output budget is left out and reported as 'truncated' with its size in 'returnValueBytes'.\n" +
+		"- Input: Data passed in the 'input' parameter is available read-only as 'wollmilchsau.input' (the JSON value; a string is never parsed) and 'wollmilchsau.inputText' (its text).\n" +
+		"- Types: The TypeScript declarations of all sandbox globals are available as the MCP resource " + TypesResourceURI + "."
 
-	executionConstraintsFooter = "- Output: Use 'console.log()' to return data to the user."
+	stdlibUsageHeader        = "\n- Standard Library: These helper modules can be imported by name:\n"
+	stdlibDeclarationsHeader = "\n\nThe standard library modules are declared as follows:\n"
+
+	executionConstraintsPackages = "\n- Packages: Only these npm packages can be imported by name (offline, fixed versions; no other packages are available): "
 
 	ToolExecuteScript     = "execute_script"
 	toolExecuteScriptDesc = "Executes a single TypeScript or JavaScript code snippet. " +
@@ -45,6 +79,19 @@ const (
 		"Returns success and any syntax errors found. " +
 		"Use this tool when you only need to validate the syntax of the code without running it."
 
+	ToolCheckProject            = "check_project"
+	ToolCheckProjectDescription = "Checks a multi-file TypeScript/JavaScript project without executing it. " +
+		"Takes the same files and entry point as execute_project and reports the problems of all files as diagnostics: " +
+		"syntax errors, imports that do not resolve, circular imports and files that the entry point never imports. " +
+		"Use this tool to validate a project before running it."
+
+	ToolLint            = "lint"
+	ToolLintDescription = "Lints TypeScript or JavaScript code without executing it and reports likely bugs that still compile, " +
+		"such as unused variables, == comparisons, promises that are never awaited, assignments to undeclared variables and unreachable code. " +
+		"Pass either 'code' or a list of 'files'. Findings are returned as warning or info diagnostics. " +
+		"Use this tool to review generated code before running it."
+	lintRulesHeader = "\n\nEnabled rules:\n"
+
 	ParamCode = "code"
 
 	ParamCodeDescription       = "The TypeScript/JavaScript code to execute."
@@ -53,7 +100,29 @@ const (
 	ParamEntryPoint            = "entryPoint"
 	ParamEntryPointDescription = "The name of the file to start execution from (e.g. 'main.ts')."
 	ParamTimeoutMs             = "timeoutMs"
-	ParamTimeoutMsDescription  = "Maximum execution time in milliseconds (100 - 30000)."
+	ParamTimeoutMsDescription  = "Maximum execution time in milliseconds (min 100, default 10000, capped by the server's wall time limit)."
+	ParamInput                 = "input"
+	ParamInputDescription      = "Optional input data for the script: any JSON value. " +
+		"Available read-only as 'wollmilchsau.input' and 'wollmilchsau.inputText' (the serialized JSON). " +
+		"A string is passed as raw text and never parsed, even if it looks like JSON: pass objects, arrays, numbers or booleans as JSON values, not as strings. " +
+		"Use this instead of embedding large data in the code."
+	ParamInputArtifacts            = "inputArtifacts"
+	ParamInputArtifactsDescription = "Optional artifacts to read before execution, as a list of {id, alias?, userId?}. " +
+		"Each is available as 'wollmilchsau.files[alias]' (alias defaults to the artifact filename) with the fields id, name, mimeType, size " +
+		"and the methods text(), bytes() (Uint8Array) and json()."
+	ParamTiming               = "timing"
+	ParamTimingDescription    = "Optional. If true, the result includes a timing breakdown (bundling incl. cache hit, input artifacts, execution)."
+	ParamTypecheck            = "typecheck"
+	ParamTypecheckDescription = "Optional. If true, the files are type checked with the TypeScript compiler first; " +
+		"type errors are returned as diagnostics and the code is not run."
+	ParamLint            = "lint"
+	ParamLintDescription = "Optional. If true, the files are linted before they run (see the lint tool); " +
+		"the findings are added to the diagnostics as warnings or infos and do not stop the execution."
+	ParamLintCodeDescription  = "The TypeScript/JavaScript code to lint. Use either this or 'files'."
+	ParamLintFilesDescription = "A list of virtual files {name, content} to lint. Use either this or 'code'."
+	ParamLimits               = "limits"
+	ParamLimitsDescription    = "Optional resource limits for this call. Limits can only be tightened below the server's limits, never loosened. " +
+		"The V8 stack size (maxStackKb) is process-wide and can only be set by the server operator."
 
 	ParamArtifactID            = "artifactId"
 	ParamArtifactIDDescription = "The ID or filename of the artifact to execute."
@@ -70,6 +139,8 @@ const (
 		"- execute_script: For single file execution.\n" +
 		"- execute_project: For multi-file project execution.\n" +
 		"- check_syntax: For pure syntax validation without execution.\n" +
+		"- check_project: For validating a multi-file project (syntax, imports, circular and unused files) without execution.\n" +
+		"- lint: For finding likely bugs (unused variables, ==, unawaited promises, implicit globals, unreachable code) without execution.\n" +
 		"\n\nWhen to use wollmilchsau:\n" +
 		"- Mathematical Complexity: For any calculation beyond basic arithmetic or involving many steps.\n" +
 		"- Algorithm Verification: To verify logic, sorting, searching, or any procedural task.\n" +
@@ -80,6 +151,22 @@ const (
 		"1. Don't guess, EXECUTE: If you are unsure about a result, write code to verify it.\n" +
 		"2. Offload Thinking: Instead of writing a long explanation of how to solve a math problem, write code that DOES it and show the result.\n"
 	promptUsageTextArtifacts = "3. Use Artifacts: For repetitive tasks or long-term data storage, use the global 'artifact' object.\n"
+
+	// ArtifactResourceTemplate is the URI template of the MCP resources under
+	// which artifacts created in a session are served.
+	ArtifactResourceTemplate     = "wollmilchsau://artifacts/{id}/{name}"
+	artifactResourcePrefix       = "wollmilchsau://artifacts/"
+	artifactResourceTemplateName = "Created artifacts"
+	// TypesResourceURI is the MCP resource of the declarations of the sandbox
+	// globals and the stdlib modules, see GetDeclarations.
+	TypesResourceURI         = "wollmilchsau://types/wollmilchsau.d.ts"
+	typesResourceName        = "wollmilchsau.d.ts"
+	typesResourceDescription = "TypeScript declarations of all globals of the sandbox (console, timers, Buffer, crypto, wollmilchsau, artifact, ...) " +
+		"and of the standard library modules."
+	mimeTypeTypeScript = "application/typescript"
+
+	artifactResourceTemplateDescription = "Artifacts created by scripts in this session via wollmilchsau.openArtifact() or saved output. " +
+		"The links in the tool results point here."
 )
 
 func GetExecutionConstraints(enableArtifacts bool) string {
@@ -91,6 +178,73 @@ func GetExecutionConstraints(enableArtifacts bool) string {
 	return res
 }
 
+// GetPackagesConstraint lists the importable packages of the module registry,
+// or returns "" without a registry.
+func GetPackagesConstraint(modules *bundler.Registry) string {
+	pkgs := modules.Packages()
+	if len(pkgs) == 0 {
+		return ""
+	}
+	names := make([]string, len(pkgs))
+	for i, p := range pkgs {
+		names[i] = p.String()
+	}
+	return executionConstraintsPackages + strings.Join(names, ", ") + "."
+}
+
+// GetStdlibUsage lists the stdlib modules with their exports for the usage
+// prompt, or returns "" without a stdlib.
+func GetStdlibUsage(stdlib *bundler.Stdlib) string {
+	mods := stdlib.Modules()
+	if len(mods) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(stdlibUsageHeader)
+	for _, m := range mods {
+		b.WriteString("  - " + m.Name)
+		if len(m.Exports) > 0 {
+			b.WriteString(": " + strings.Join(m.Exports, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// GetStdlibDeclarations returns the declarations of the stdlib modules for
+// the check_syntax description, or "" without a stdlib.
+func GetStdlibDeclarations(stdlib *bundler.Stdlib) string {
+	if len(stdlib.Modules()) == 0 {
+		return ""
+	}
+	return stdlibDeclarationsHeader + stdlib.Declarations()
+}
+
+// GetDeclarations returns the ambient declaration file of the sandbox
+// globals, followed by the declarations of the stdlib modules. It is served
+// as TypesResourceURI and used for type checking.
+func GetDeclarations(enableArtifacts bool, stdlib *bundler.Stdlib) string {
+	decl := executor.Declarations(enableArtifacts)
+	if std := stdlib.Declarations(); std != "" {
+		decl += "\n" + std
+	}
+	return decl
+}
+
+// GetLintRules lists the lint rules enabled in rules with their levels.
+func GetLintRules(rules lint.Config) string {
+	enabled := rules.Enabled()
+	if len(enabled) == 0 {
+		return "\n\nAll rules are disabled by the server configuration."
+	}
+	var b strings.Builder
+	b.WriteString(lintRulesHeader)
+	for _, r := range enabled {
+		fmt.Fprintf(&b, "- %s (%s): %s\n", r.Name, rules.Level(r.Name), r.Description)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
 func GetToolExecuteScriptDescription(enableArtifacts bool) string {
 	return toolExecuteScriptDesc + GetExecutionConstraints(enableArtifacts)
 }
@@ -11,23 +11,57 @@ import (
 
 	"github.com/hmsoft0815/wollmilchsau/internal/bundler"
 	"github.com/hmsoft0815/wollmilchsau/internal/executor"
+	"github.com/hmsoft0815/wollmilchsau/internal/lint"
 	"github.com/hmsoft0815/wollmilchsau/internal/parser"
 	"github.com/hmsoft0815/wollmilchsau/internal/requestlog"
+	"github.com/hmsoft0815/wollmilchsau/internal/typecheck"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
-func (s *WollmilchsauServer) runExecution(ctx context.Context, plan *parser.ExecutionPlan, toolName string) (*mcp.CallToolResult, error) {
+// callOptions holds the optional tool arguments shared by all execution tools.
+type callOptions struct {
+	limits         executor.Limits    // requested limits, only used to tighten the server limits
+	input          *executor.Input    // data exposed as wollmilchsau.input, nil if none
+	inputArtifacts []inputArtifactRef // artifacts exposed as wollmilchsau.files
+	timing         bool               // report a timing breakdown
+	typecheck      bool               // type check before running
+	lint           bool               // lint before running
+}
+
+func callOptionsFromArgs(args map[string]any) callOptions {
+	return callOptions{
+		limits:         limitsFromArgs(args),
+		input:          inputFromArgs(args),
+		inputArtifacts: inputArtifactsFromArgs(args),
+		timing:         args[ParamTiming] == true,
+		typecheck:      args[ParamTypecheck] == true,
+		lint:           args[ParamLint] == true,
+	}
+}
+
+func (s *WollmilchsauServer) runExecution(ctx context.Context, plan *parser.ExecutionPlan, call callOptions, toolName string) (*mcp.CallToolResult, error) {
+	start := time.Now()
 	if plan.TimeoutMs == 0 {
-		plan.TimeoutMs = 10_000
+		plan.TimeoutMs = defaultTimeoutMs
 	}
 
-	if err := parser.ValidatePlan(plan); err != nil {
+	// The tool call may only tighten the operator limits. The plan timeout is
+	// clamped to the resulting wall time limit and then used as such.
+	limits := s.Limits.Tighten(call.limits)
+	if call.limits.MaxStackKB != 0 {
+		res := mcp.NewToolResultText("validation error: maxStackKb is a server-only limit and cannot be set per call")
+		res.IsError = true
+		return res, nil
+	}
+	if err := parser.ValidatePlanWithMaxTimeout(plan, limits.MaxWallTimeMs); err != nil {
 		res := mcp.NewToolResultText("validation error: " + err.Error())
 		res.IsError = true
 		return res, nil
 	}
 
-	bundle, bundleErr := bundler.Bundle(plan)
+	bundleStart := time.Now()
+	bundle, cached, bundleErr := s.Bundles.Bundle(plan)
+	timing := Timing{BundleMs: time.Since(bundleStart).Milliseconds(), BundleCached: cached}
 	if bundleErr != nil {
 		if be, ok := bundleErr.(*bundler.BundleError); ok {
 			result := buildFailResult(be)
@@ -45,7 +79,7 @@ func (s *WollmilchsauServer) runExecution(ctx context.Context, plan *parser.Exec
 			slog.Warn("build failed", "err", result.Summary)
 
 			// Log to ZIP if enabled
-			s.maybeLogRequest(ctx, toolName, plan, result)
+			s.maybeLogRequest(ctx, toolName, plan, call.input, result)
 
 			return &mcp.CallToolResult{
 				Content:           []mcp.Content{mcp.NewTextContent("### Build Failure\n" + mustJSON(meta))},
@@ -58,11 +92,69 @@ func (s *WollmilchsauServer) runExecution(ctx context.Context, plan *parser.Exec
 		return res, nil
 	}
 
-	execCtx, cancel := context.WithTimeout(ctx, time.Duration(plan.TimeoutMs)*time.Millisecond)
-	defer cancel()
+	var typeDiags []executor.Diagnostic
+	if call.typecheck {
+		typecheckStart := time.Now()
+		diags, err := s.typeCheck(ctx, plan)
+		if err != nil {
+			return mcp.NewToolResultErrorFromErr("Type check failed", err), nil
+		}
+		timing.TypecheckMs = time.Since(typecheckStart).Milliseconds()
+		if typecheck.HasErrors(diags) {
+			result := typeFailResult(diags)
+			meta := ExecutionResult{
+				Summary:     result.Summary,
+				Success:     result.Success,
+				ExitCode:    result.ExitCode,
+				Diagnostics: result.Diagnostics,
+			}
+			if call.timing {
+				timing.TotalMs = time.Since(start).Milliseconds()
+				meta.Timing = &timing
+			}
+			slog.Warn("type check failed", "err", result.Summary)
+			s.maybeLogRequest(ctx, toolName, plan, call.input, result)
+			return &mcp.CallToolResult{
+				Content:           []mcp.Content{mcp.NewTextContent("### Type Check Failure\n" + mustJSON(meta))},
+				StructuredContent: meta,
+				IsError:           true,
+			}, nil
+		}
+		typeDiags = diags
+	}
+
+	// Lint findings are only warnings and infos, so they never stop the run.
+	var lintDiags []executor.Diagnostic
+	if call.lint {
+		lintDiags = lint.Lint(plan.Files, s.LintRules)
+	}
 
-	result := executor.Execute(execCtx, bundle.JS, plan.EntryPoint, bundle.SourceMap, s.ArtifactAddr)
+	fetchStart := time.Now()
+	// The input limit covers the input parameter and all input artifacts.
+	maxInputBytes := limits.WithDefaults().MaxInputBytes
+	if call.input != nil {
+		maxInputBytes -= len(call.input.Text)
+	}
+	files, err := s.fetchInputArtifacts(ctx, call.inputArtifacts, max(maxInputBytes, 0))
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("Failed to read input artifacts", err), nil
+	}
+	timing.InputFetchMs = time.Since(fetchStart).Milliseconds()
 
+	limits.MaxWallTimeMs = plan.TimeoutMs
+
+	execStart := time.Now()
+	result := s.Pool.Execute(ctx, bundle.JS, plan.EntryPoint, bundle.SourceMap, executor.Options{
+		Artifacts:   s.artifactStore(),
+		Limits:      limits,
+		SpillOutput: s.SpillOutput,
+		Input:       call.input,
+		Files:       files,
+	})
+	timing.ExecuteMs = time.Since(execStart).Milliseconds()
+
+	result.Diagnostics = append(result.Diagnostics, typeDiags...)
+	result.Diagnostics = append(result.Diagnostics, lintDiags...)
 	for _, w := range bundle.Warnings {
 		result.Diagnostics = append(result.Diagnostics, executor.Diagnostic{
 			Severity: executor.SeverityWarning,
@@ -74,18 +166,27 @@ func (s *WollmilchsauServer) runExecution(ctx context.Context, plan *parser.Exec
 	}
 
 	contents := []mcp.Content{}
-	meta := struct {
-		Summary     string                `json:"summary"`
-		Success     bool                  `json:"success"`
-		ExitCode    int                   `json:"exitCode"`
-		DurationMs  int64                 `json:"durationMs"`
-		Diagnostics []executor.Diagnostic `json:"diagnostics,omitempty"`
-	}{
+	meta := ExecutionResult{
 		Summary:     result.Summary,
 		Success:     result.Success,
 		ExitCode:    result.ExitCode,
 		DurationMs:  result.DurationMs,
 		Diagnostics: result.Diagnostics,
+
+		CreatedArtifacts: result.CreatedArtifacts,
+	}
+	if result.ReturnValue != nil {
+		meta.ReturnValue = result.ReturnValue
+	}
+	if call.timing {
+		timing.TotalMs = time.Since(start).Milliseconds()
+		meta.Timing = &timing
+	}
+	if result.Truncated {
+		meta.Truncated = true
+		meta.StdoutBytes = result.StdoutBytes
+		meta.StderrBytes = result.StderrBytes
+		meta.ReturnValueBytes = result.ReturnValueBytes
 	}
 	contents = append(contents, mcp.NewTextContent("### Status\n"+mustJSON(meta)))
 
@@ -97,19 +198,16 @@ func (s *WollmilchsauServer) runExecution(ctx context.Context, plan *parser.Exec
 	}
 
 	// Append resource_link items for any artifacts created via wollmilchsau.openArtifact()
-	for _, a := range result.CreatedArtifacts {
-		contents = append(contents, mcp.ResourceLink{
-			Type:     "resource_link",
-			URI:      a.URI,
-			Name:     a.Name,
-			MIMEType: a.MimeType,
-		})
-	}
+	// or the spilled output. They point at MCP resources served by this server.
+	contents = append(contents, s.publishArtifacts(ctx, result.CreatedArtifacts)...)
 
 	// Log to ZIP if enabled
-	s.maybeLogRequest(ctx, toolName, plan, result)
+	s.maybeLogRequest(ctx, toolName, plan, call.input, result)
 
-	slog.Info("tool executed", "tool", toolName, "summary", result.Summary, "duration_ms", result.DurationMs, "success", result.Success)
+	poolStats := s.Pool.Stats()
+	slog.Info("tool executed", "tool", toolName, "summary", result.Summary, "duration_ms", result.DurationMs, "success", result.Success,
+		"pool_hit_rate", poolStats.HitRate, "pool_time_saved_ms", poolStats.TimeSavedMs,
+		"bundle_cached", cached, "bundle_ms", timing.BundleMs, "bundle_cache_hit_rate", s.Bundles.Stats().HitRate)
 
 	return &mcp.CallToolResult{
 		Content:           contents,
@@ -118,7 +216,7 @@ func (s *WollmilchsauServer) runExecution(ctx context.Context, plan *parser.Exec
 	}, nil
 }
 
-func (s *WollmilchsauServer) maybeLogRequest(ctx context.Context, tool string, plan *parser.ExecutionPlan, result *executor.Result) {
+func (s *WollmilchsauServer) maybeLogRequest(ctx context.Context, tool string, plan *parser.ExecutionPlan, input *executor.Input, result *executor.Result) {
 	if s.LogDir == "" {
 		return
 	}
@@ -128,6 +226,7 @@ func (s *WollmilchsauServer) maybeLogRequest(ctx context.Context, tool string, p
 		RemoteIP:  remoteIP,
 		Tool:      tool,
 		Plan:      plan,
+		Input:     input,
 		Result:    result,
 		Timestamp: time.Now(),
 	}
@@ -165,6 +264,31 @@ func buildFailResult(be *bundler.BundleError) *executor.Result {
 	}
 }
 
+// typeCheck type checks the files of plan against the sandbox globals and
+// the stdlib modules.
+func (s *WollmilchsauServer) typeCheck(ctx context.Context, plan *parser.ExecutionPlan) ([]executor.Diagnostic, error) {
+	return s.TypeChecker.Check(ctx, typecheck.Input{
+		Files:        plan.Files,
+		Declarations: GetDeclarations(s.EnableArtifacts, s.Bundles.Imports().Stdlib),
+	})
+}
+
+func typeFailResult(diags []executor.Diagnostic) *executor.Result {
+	summary := "Type check failed"
+	for _, d := range diags {
+		if d.Severity == executor.SeverityError {
+			summary = fmt.Sprintf("Type Error: %s in %s:%d", d.Message, d.Source, d.Line)
+			break
+		}
+	}
+	return &executor.Result{
+		ExitCode:    1,
+		Success:     false,
+		Summary:     summary,
+		Diagnostics: diags,
+	}
+}
+
 func mustJSON(v any) string {
 	b, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
@@ -172,3 +296,42 @@ func mustJSON(v any) string {
 	}
 	return string(b)
 }
+
+// limitsFromArgs decodes the optional 'limits' tool parameter.
+// Malformed values are ignored, since limits can only ever be tightened.
+func limitsFromArgs(args map[string]any) executor.Limits {
+	var l executor.Limits
+	raw, ok := args[ParamLimits]
+	if !ok {
+		return l
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return l
+	}
+	if err := json.Unmarshal(b, &l); err != nil {
+		slog.Warn("ignoring malformed limits parameter", "err", err)
+		return executor.Limits{}
+	}
+	return l
+}
+
+// inputFromArgs converts the optional 'input' tool parameter. Strings are
+// passed through unchanged as text; all other values are passed as JSON.
+func inputFromArgs(args map[string]any) *executor.Input {
+	raw, ok := args[ParamInput]
+	if !ok || raw == nil {
+		return nil
+	}
+	// A string is always text, even if it happens to be valid JSON such as
+	// "123"; only JSON values other than strings are parsed.
+	if text, ok := raw.(string); ok {
+		return &executor.Input{Text: text}
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		slog.Warn("ignoring malformed input parameter", "err", err)
+		return nil
+	}
+	return &executor.Input{Text: string(b), JSON: true}
+}
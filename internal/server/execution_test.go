@@ -0,0 +1,384 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hmsoft0815/wollmilchsau/internal/bundler"
+	"github.com/hmsoft0815/wollmilchsau/internal/executor"
+	"github.com/hmsoft0815/wollmilchsau/internal/lint"
+	"github.com/hmsoft0815/wollmilchsau/internal/typecheck"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestRunExecution_Timing(t *testing.T) {
+	ws := New("", false, "", WithPoolSize(0))
+	defer ws.Close()
+
+	call := func(args map[string]any) ExecutionResult {
+		var req mcp.CallToolRequest
+		req.Params.Arguments = args
+		res, err := ws.handleExecuteScript(context.Background(), req)
+		if err != nil || res.IsError {
+			t.Fatalf("execution failed: %v %+v", err, res)
+		}
+		return res.StructuredContent.(ExecutionResult)
+	}
+
+	code := "console.log(6 * 7);"
+	if meta := call(map[string]any{ParamCode: code}); meta.Timing != nil {
+		t.Errorf("timing reported without being requested: %+v", meta.Timing)
+	}
+	meta := call(map[string]any{ParamCode: code, ParamTiming: true})
+	if meta.Timing == nil || !meta.Timing.BundleCached || meta.Timing.TotalMs < meta.Timing.ExecuteMs {
+		t.Errorf("unexpected timing of the second run: %+v", meta.Timing)
+	}
+	if st := ws.Bundles.Stats(); st.Hits != 1 || st.Misses != 1 {
+		t.Errorf("unexpected cache stats: %+v", st)
+	}
+}
+
+func TestRunExecution_StackLimitIsServerOnly(t *testing.T) {
+	ws := New("", false, "", WithPoolSize(0))
+	defer ws.Close()
+
+	call := func(limits map[string]any) *mcp.CallToolResult {
+		var req mcp.CallToolRequest
+		req.Params.Arguments = map[string]any{ParamCode: "console.log(1);", ParamLimits: limits}
+		res, err := ws.handleExecuteScript(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	res := call(map[string]any{"maxStackKb": 100.0})
+	text, _ := res.Content[0].(mcp.TextContent)
+	if !res.IsError || !strings.Contains(text.Text, "maxStackKb is a server-only limit") {
+		t.Errorf("a per-call stack size was not rejected: %+v", res)
+	}
+	if res := call(map[string]any{"maxWallTimeMs": 5000.0}); res.IsError {
+		t.Errorf("tightening a limit failed: %+v", res)
+	}
+}
+
+func TestRunExecution_Modules(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "shout"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "shout", "package.json"), []byte(`{"version": "1.0.0"}`), 0o644)
+	os.WriteFile(filepath.Join(dir, "shout", "index.js"), []byte(`module.exports = (s) => s.toUpperCase() + "!";`), 0o644)
+	modules, err := bundler.OpenRegistry(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ws := New("", false, "", WithPoolSize(0), WithModules(modules))
+	defer ws.Close()
+
+	if desc := toolExecuteScript(false, modules).Description; !strings.Contains(desc, "Packages: ") || !strings.Contains(desc, "shout@1.0.0") {
+		t.Errorf("the description does not list the packages: %s", desc)
+	}
+
+	var req mcp.CallToolRequest
+	req.Params.Arguments = map[string]any{ParamCode: `import shout from "shout"; console.log(shout("hello"));`}
+	res, err := ws.handleExecuteScript(context.Background(), req)
+	if err != nil || res.IsError {
+		t.Fatalf("execution failed: %v %+v", err, res)
+	}
+	var out strings.Builder
+	for _, c := range res.Content {
+		if text, ok := c.(mcp.TextContent); ok {
+			out.WriteString(text.Text)
+		}
+	}
+	if !strings.Contains(out.String(), "HELLO!") {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}
+
+func TestStdlib(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "stats.ts"), []byte("export function mean(xs: number[]): number { return xs.reduce((a, b) => a + b, 0) / xs.length; }"), 0o644)
+	stdlib, err := bundler.OpenStdlib(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws := New("", false, "", WithPoolSize(0), WithStdlib(stdlib))
+	defer ws.Close()
+	ctx := context.Background()
+
+	prompt, err := ws.handlePromptUsage(ctx, mcp.GetPromptRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := prompt.Messages[0].Content.(mcp.TextContent).Text; !strings.Contains(text, "  - @std/stats: mean") {
+		t.Errorf("the usage prompt does not list the stdlib: %s", text)
+	}
+	if desc := toolCheckSyntax(stdlib, false).Description; !strings.Contains(desc, "export function mean(xs: number[]): number;") {
+		t.Errorf("the check_syntax description lacks the declarations: %s", desc)
+	}
+
+	var req mcp.CallToolRequest
+	req.Params.Arguments = map[string]any{ParamCode: `import { mean } from "@std/stats"; console.log(mean([1, 2]));`}
+	if res, err := ws.handleCheckSyntax(ctx, req); err != nil || res.IsError {
+		t.Errorf("check_syntax failed: %v %+v", err, res)
+	}
+}
+
+func TestCheckProject(t *testing.T) {
+	ws := New("", false, "", WithPoolSize(0))
+	defer ws.Close()
+	ctx := context.Background()
+
+	check := func(files ...string) *mcp.CallToolResult {
+		t.Helper()
+		var raw []any
+		for i := 0; i < len(files); i += 2 {
+			raw = append(raw, map[string]any{"name": files[i], "content": files[i+1]})
+		}
+		var req mcp.CallToolRequest
+		req.Params.Arguments = map[string]any{ParamFiles: raw, ParamEntryPoint: "main.ts"}
+		res, err := ws.handleCheckProject(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	res := check("main.ts", "import { a } from './a';\nconsole.log(a);", "a.ts", "export const a = 1;")
+	if meta, ok := res.StructuredContent.(CheckSyntaxResult); res.IsError || !ok || meta.Summary != "Project is valid" {
+		t.Errorf("unexpected result of a valid project: %+v", res)
+	}
+
+	res = check(
+		"main.ts", "import { a } from './a';\nimport './gone';\nconsole.log(a);",
+		"a.ts", "import { b } from './b';\nexport const a = b;",
+		"b.ts", "import { a } from './a';\nexport const b = () => a;",
+		"old.ts", "export const old = 1;",
+	)
+	meta := res.StructuredContent.(CheckSyntaxResult)
+	if !res.IsError || meta.Success || !strings.HasPrefix(meta.Summary, `Build Error: Could not resolve "./gone" in main.ts:2`) {
+		t.Errorf("unexpected result of a broken project: %+v", meta)
+	}
+	var got []string
+	for _, d := range meta.Diagnostics {
+		got = append(got, string(d.Severity)+" "+d.Source+" "+d.Message)
+	}
+	want := []string{
+		`error main.ts Could not resolve "./gone"`,
+		"warning b.ts Circular import: a.ts -> b.ts -> a.ts",
+		"warning old.ts Unused file: old.ts is not imported by main.ts or any file it imports",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("diagnostics = %q", got)
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("diagnostic %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	if res := check("lib.ts", ""); !res.IsError || res.StructuredContent != nil {
+		t.Errorf("a project without its entry point was checked: %+v", res)
+	}
+}
+
+// fakeTypeScript is a stand-in for the TypeScript compiler that reports
+// every `: number = "` as a type error.
+const fakeTypeScript = `var ts = {
+	version: 'test',
+	ScriptTarget: { ES2020: 7 },
+	ModuleKind: { ESNext: 99 },
+	ModuleResolutionKind: { NodeJs: 2 },
+	createSourceFile: (fileName, text) => ({
+		fileName, text,
+		getLineAndCharacterOfPosition: (pos) => ({ line: text.slice(0, pos).split('\n').length - 1, character: 0 }),
+	}),
+	createProgram: (roots, options, host) => ({ files: roots.map((name) => host.getSourceFile(name, options.target)) }),
+	getPreEmitDiagnostics: (program) => program.files.flatMap((file) => [...file.text.matchAll(/:\s*number\s*=\s*"/g)].map((m) =>
+		({ file, start: m.index, category: 1, code: 2322, messageText: "Type 'string' is not assignable to type 'number'." }))),
+	flattenDiagnosticMessageText: (m) => m,
+};`
+
+func TestLint(t *testing.T) {
+	ws := New("", false, "", WithPoolSize(0), WithLintRules(lint.Config{"no-unused-vars": lint.LevelOff}))
+	defer ws.Close()
+	ctx := context.Background()
+
+	if desc := toolLint(ws.LintRules).Description; strings.Contains(desc, "no-unused-vars") || !strings.Contains(desc, "- eqeqeq (warning)") {
+		t.Errorf("the description does not list the enabled rules: %s", desc)
+	}
+
+	code := "const unused = 1;\nconst x = 6 * 7;\nif (x == 42) console.log(x);"
+	var req mcp.CallToolRequest
+	req.Params.Arguments = map[string]any{ParamCode: code}
+	res, err := ws.handleLint(ctx, req)
+	if err != nil || res.IsError {
+		t.Fatalf("lint failed: %v %+v", err, res)
+	}
+	meta := res.StructuredContent.(CheckSyntaxResult)
+	if meta.Summary != "1 lint finding(s)" || len(meta.Diagnostics) != 1 || meta.Diagnostics[0].Line != 3 {
+		t.Errorf("unexpected lint result: %+v", meta)
+	}
+
+	req.Params.Arguments = map[string]any{}
+	if res, _ := ws.handleLint(ctx, req); !res.IsError {
+		t.Errorf("lint without code or files succeeded: %+v", res)
+	}
+
+	// The findings of an execution are warnings that do not stop it.
+	req.Params.Arguments = map[string]any{ParamCode: code, ParamLint: true}
+	res, err = ws.handleExecuteScript(ctx, req)
+	if err != nil || res.IsError {
+		t.Fatalf("execution failed: %v %+v", err, res)
+	}
+	exec := res.StructuredContent.(ExecutionResult)
+	found := false
+	for _, d := range exec.Diagnostics {
+		found = found || (d.Severity == executor.SeverityWarning && strings.HasSuffix(d.Message, "(eqeqeq)"))
+	}
+	if !exec.Success || !found {
+		t.Errorf("unexpected result of a linted execution: %+v", exec)
+	}
+}
+
+// readCountingStore counts the reads of an artifact store.
+type readCountingStore struct {
+	executor.ArtifactStore
+	reads int
+}
+
+func (r *readCountingStore) Read(ctx context.Context, idOrFilename, userID string) (*executor.Artifact, error) {
+	r.reads++
+	return r.ArtifactStore.Read(ctx, idOrFilename, userID)
+}
+
+func TestInput_Strings(t *testing.T) {
+	ws := New("", false, "", WithPoolSize(0))
+	defer ws.Close()
+
+	code := "wollmilchsau.return([typeof wollmilchsau.input, wollmilchsau.inputText]);"
+	for _, tc := range []struct {
+		input any
+		want  string
+	}{
+		{"123", "string 123"},
+		{`{"a": 1}`, `string {"a": 1}`},
+		{"plain", "string plain"},
+		{123.0, "number 123"},
+		{map[string]any{"a": 1.0}, `object {"a":1}`},
+	} {
+		var req mcp.CallToolRequest
+		req.Params.Arguments = map[string]any{ParamCode: code, ParamInput: tc.input}
+		res, err := ws.handleExecuteScript(context.Background(), req)
+		if err != nil || res.IsError {
+			t.Fatalf("execution failed: %v %+v", err, res)
+		}
+		var got []any
+		raw, _ := res.StructuredContent.(ExecutionResult).ReturnValue.(json.RawMessage)
+		json.Unmarshal(raw, &got)
+		if len(got) != 2 || fmt.Sprint(got[0], " ", got[1]) != tc.want {
+			t.Errorf("input %#v: got %v, want %s", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestInputArtifacts_SizeLimit(t *testing.T) {
+	store := &readCountingStore{ArtifactStore: executor.NewMemoryArtifactStore()}
+	ws := New("", true, "", WithPoolSize(0), WithArtifactStore(store), WithLimits(executor.Limits{MaxInputBytes: 100}))
+	defer ws.Close()
+	ctx := context.Background()
+
+	big, _ := store.Write(ctx, executor.ArtifactWrite{Filename: "big.txt", Content: []byte(strings.Repeat("x", 80))})
+	small, _ := store.Write(ctx, executor.ArtifactWrite{Filename: "small.txt", Content: []byte("hello")})
+
+	run := func(args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		var req mcp.CallToolRequest
+		req.Params.Arguments = args
+		res, err := ws.handleExecuteScript(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	text := func(res *mcp.CallToolResult) string {
+		var b strings.Builder
+		for _, c := range res.Content {
+			if tc, ok := c.(mcp.TextContent); ok {
+				b.WriteString(tc.Text)
+			}
+		}
+		return b.String()
+	}
+
+	res := run(map[string]any{ParamCode: "console.log(wollmilchsau.files['small.txt'].text());", ParamInputArtifacts: []any{small.ID, big.ID}})
+	if res.IsError || !strings.Contains(text(res), "hello") {
+		t.Fatalf("execution within the limit failed: %s", text(res))
+	}
+
+	// The input text and big.txt exceed the limit, which is noticed before big.txt is read.
+	store.reads = 0
+	res = run(map[string]any{ParamCode: "1", ParamInput: strings.Repeat("y", 30), ParamInputArtifacts: []any{big.ID}})
+	if !res.IsError || !strings.Contains(text(res), "80 bytes, but only 70 bytes of the input limit") || store.reads != 0 {
+		t.Errorf("unexpected result (%d reads): %s", store.reads, text(res))
+	}
+}
+
+func TestTypecheck(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "lib"), 0o755)
+	os.WriteFile(filepath.Join(dir, "lib", "typescript.js"), []byte(fakeTypeScript), 0o644)
+	os.WriteFile(filepath.Join(dir, "lib", "lib.es2020.d.ts"), nil, 0o644)
+	checker, err := typecheck.New(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer checker.Close()
+	ws := New("", false, "", WithPoolSize(0), WithTypeChecker(checker))
+	defer ws.Close()
+	ctx := context.Background()
+
+	if _, ok := toolExecuteProject(false, nil, true).InputSchema.Properties[ParamTypecheck]; !ok {
+		t.Error("execute_project does not offer the typecheck option")
+	}
+	if _, ok := toolCheckProject(nil, true).InputSchema.Properties[ParamTypecheck]; !ok {
+		t.Error("check_project does not offer the typecheck option")
+	}
+
+	code := "const n: number = \"a\";\nconsole.log(n);"
+	var req mcp.CallToolRequest
+	req.Params.Arguments = map[string]any{ParamCode: code}
+	if res, err := ws.handleCheckSyntax(ctx, req); err != nil || res.IsError {
+		t.Errorf("check_syntax without typecheck failed: %v %+v", err, res)
+	}
+	req.Params.Arguments = map[string]any{ParamCode: code, ParamTypecheck: true}
+	res, err := ws.handleCheckSyntax(ctx, req)
+	if err != nil || !res.IsError {
+		t.Fatalf("check_syntax with typecheck passed: %v %+v", err, res)
+	}
+	if meta := res.StructuredContent.(CheckSyntaxResult); meta.Summary != "Type Error: Type 'string' is not assignable to type 'number'. (TS2322) in check.ts:1" {
+		t.Errorf("unexpected check_syntax result: %+v", meta)
+	}
+
+	req.Params.Arguments = map[string]any{
+		ParamFiles:      []any{map[string]any{"name": "main.ts", "content": code}},
+		ParamEntryPoint: "main.ts",
+		ParamTypecheck:  true,
+	}
+	res, err = ws.handleExecuteProject(ctx, req)
+	if err != nil || !res.IsError {
+		t.Fatalf("execute_project with a type error ran: %v %+v", err, res)
+	}
+	if meta := res.StructuredContent.(ExecutionResult); len(meta.Diagnostics) != 1 || meta.Diagnostics[0].Source != "main.ts" || meta.DurationMs != 0 {
+		t.Errorf("unexpected execute_project result: %+v", meta)
+	}
+}
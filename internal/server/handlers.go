@@ -3,22 +3,25 @@ package server
 
 import (
 	"context"
-	"log/slog"
+	"fmt"
 
-	mlcartifact "github.com/hmsoft0815/mlcartifact/client"
 	"github.com/hmsoft0815/wollmilchsau/internal/bundler"
 	"github.com/hmsoft0815/wollmilchsau/internal/executor"
+	"github.com/hmsoft0815/wollmilchsau/internal/lint"
 	"github.com/hmsoft0815/wollmilchsau/internal/parser"
+	"github.com/hmsoft0815/wollmilchsau/internal/typecheck"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
 func (s *WollmilchsauServer) handlePromptUsage(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	imp := s.Bundles.Imports()
+	text := GetPromptUsageText(s.EnableArtifacts) + GetPackagesConstraint(imp.Modules) + GetStdlibUsage(imp.Stdlib)
 	return &mcp.GetPromptResult{
 		Description: "Instructions on when to offload thinking to wollmilchsau",
 		Messages: []mcp.PromptMessage{
 			{
 				Role:    "system",
-				Content: mcp.NewTextContent(GetPromptUsageText(s.EnableArtifacts)),
+				Content: mcp.NewTextContent(text),
 			},
 		},
 	}, nil
@@ -36,13 +39,9 @@ func (s *WollmilchsauServer) handleCheckSyntax(ctx context.Context, req mcp.Call
 	}
 
 	// We use the bundler just to see if it compiles
-	_, err := bundler.Bundle(plan)
+	_, _, err := s.Bundles.Bundle(plan)
 
-	meta := struct {
-		Success     bool                  `json:"success"`
-		Summary     string                `json:"summary"`
-		Diagnostics []executor.Diagnostic `json:"diagnostics,omitempty"`
-	}{
+	meta := CheckSyntaxResult{
 		Success: err == nil,
 	}
 
@@ -61,7 +60,25 @@ func (s *WollmilchsauServer) handleCheckSyntax(ctx context.Context, req mcp.Call
 		}, nil
 	}
 
-	meta.Summary = "Syntax is valid"
+	if args[ParamTypecheck] == true {
+		diags, err := s.typeCheck(ctx, plan)
+		if err != nil {
+			return mcp.NewToolResultErrorFromErr("Type check failed", err), nil
+		}
+		meta.Diagnostics = diags
+		if typecheck.HasErrors(diags) {
+			meta.Success = false
+			meta.Summary = typeFailResult(diags).Summary
+			return &mcp.CallToolResult{
+				Content:           []mcp.Content{mcp.NewTextContent("### Type Check Failed\n" + mustJSON(meta))},
+				StructuredContent: meta,
+				IsError:           true,
+			}, nil
+		}
+		meta.Summary = "Syntax and types are valid"
+	} else {
+		meta.Summary = "Syntax is valid"
+	}
 	return &mcp.CallToolResult{
 		Content:           []mcp.Content{mcp.NewTextContent("### Syntax Check Passed\n" + mustJSON(meta))},
 		StructuredContent: meta,
@@ -81,20 +98,89 @@ func (s *WollmilchsauServer) handleExecuteScript(ctx context.Context, req mcp.Ca
 		TimeoutMs:  int(timeout),
 	}
 
-	return s.runExecution(ctx, plan, ToolExecuteScript)
+	return s.runExecution(ctx, plan, callOptionsFromArgs(args), ToolExecuteScript)
 }
 
 func (s *WollmilchsauServer) handleExecuteProject(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args, _ := req.Params.Arguments.(map[string]any)
-	filesRaw, _ := args[ParamFiles].([]any)
-	entryPoint, _ := args[ParamEntryPoint].(string)
 	timeout, _ := args[ParamTimeoutMs].(float64)
 
-	plan := &parser.ExecutionPlan{
-		EntryPoint: entryPoint,
-		TimeoutMs:  int(timeout),
+	plan := projectPlanFromArgs(args)
+	plan.TimeoutMs = int(timeout)
+
+	return s.runExecution(ctx, plan, callOptionsFromArgs(args), ToolExecuteProject)
+}
+
+func (s *WollmilchsauServer) handleCheckProject(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, _ := req.Params.Arguments.(map[string]any)
+	plan := projectPlanFromArgs(args)
+	if err := parser.ValidatePlan(plan); err != nil {
+		res := mcp.NewToolResultText("validation error: " + err.Error())
+		res.IsError = true
+		return res, nil
+	}
+
+	report := bundler.Check(plan, s.Bundles.Imports())
+	meta := checkProjectResult(plan, report)
+	if meta.Success && args[ParamTypecheck] == true {
+		diags, err := s.typeCheck(ctx, plan)
+		if err != nil {
+			return mcp.NewToolResultErrorFromErr("Type check failed", err), nil
+		}
+		meta.Diagnostics = append(meta.Diagnostics, diags...)
+		if typecheck.HasErrors(diags) {
+			meta.Success = false
+			meta.Summary = typeFailResult(diags).Summary
+		}
+	}
+
+	if !meta.Success {
+		return &mcp.CallToolResult{
+			Content:           []mcp.Content{mcp.NewTextContent("### Project Check Failed\n" + mustJSON(meta))},
+			StructuredContent: meta,
+			IsError:           true,
+		}, nil
+	}
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{mcp.NewTextContent("### Project Check Passed\n" + mustJSON(meta))},
+		StructuredContent: meta,
+	}, nil
+}
+
+func (s *WollmilchsauServer) handleLint(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, _ := req.Params.Arguments.(map[string]any)
+	files := projectPlanFromArgs(args).Files
+	if code, _ := args[ParamCode].(string); code != "" {
+		files = append(files, parser.VirtualFile{Name: "lint.ts", Content: code})
+	}
+	if len(files) == 0 {
+		res := mcp.NewToolResultText("validation error: either code or files is required")
+		res.IsError = true
+		return res, nil
+	}
+
+	diags := lint.Lint(files, s.LintRules)
+	meta := CheckSyntaxResult{
+		Success:     true,
+		Summary:     "No lint findings",
+		Diagnostics: diags,
+	}
+	if len(diags) > 0 {
+		meta.Summary = fmt.Sprintf("%d lint finding(s)", len(diags))
 	}
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{mcp.NewTextContent("### Lint Result\n" + mustJSON(meta))},
+		StructuredContent: meta,
+	}, nil
+}
+
+// projectPlanFromArgs returns the plan of the 'files' and 'entryPoint'
+// arguments of execute_project, check_project and lint.
+func projectPlanFromArgs(args map[string]any) *parser.ExecutionPlan {
+	filesRaw, _ := args[ParamFiles].([]any)
+	entryPoint, _ := args[ParamEntryPoint].(string)
 
+	plan := &parser.ExecutionPlan{EntryPoint: entryPoint}
 	for _, f := range filesRaw {
 		fm, ok := f.(map[string]any)
 		if !ok {
@@ -104,8 +190,45 @@ func (s *WollmilchsauServer) handleExecuteProject(ctx context.Context, req mcp.C
 		content, _ := fm["content"].(string)
 		plan.Files = append(plan.Files, parser.VirtualFile{Name: name, Content: content})
 	}
+	return plan
+}
 
-	return s.runExecution(ctx, plan, ToolExecuteProject)
+// checkProjectResult converts the report of check_project into diagnostics:
+// build errors are errors, esbuild warnings, circular imports and unused
+// files are warnings.
+func checkProjectResult(plan *parser.ExecutionPlan, report *bundler.Report) CheckSyntaxResult {
+	var diags []executor.Diagnostic
+	add := func(sev executor.Severity, msgs []bundler.BundleMessage) {
+		for _, m := range msgs {
+			diags = append(diags, executor.Diagnostic{
+				Severity: sev,
+				Message:  m.Text,
+				Source:   m.Source,
+				Line:     m.Line,
+				Column:   m.Column,
+			})
+		}
+	}
+	add(executor.SeverityError, report.Errors)
+	add(executor.SeverityWarning, report.Warnings)
+	for _, name := range report.Unused {
+		diags = append(diags, executor.Diagnostic{
+			Severity: executor.SeverityWarning,
+			Message:  fmt.Sprintf("Unused file: %s is not imported by %s or any file it imports", name, plan.EntryPoint),
+			Source:   name,
+		})
+	}
+
+	meta := CheckSyntaxResult{Success: len(report.Errors) == 0, Diagnostics: diags}
+	switch {
+	case !meta.Success:
+		meta.Summary = buildFailResult(&bundler.BundleError{Messages: report.Errors}).Summary
+	case len(diags) > 0:
+		meta.Summary = fmt.Sprintf("Project is valid with %d warning(s)", len(diags))
+	default:
+		meta.Summary = "Project is valid"
+	}
+	return meta
 }
 
 func (s *WollmilchsauServer) handleExecuteArtifact(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -115,28 +238,12 @@ func (s *WollmilchsauServer) handleExecuteArtifact(ctx context.Context, req mcp.
 	userID, _ := args[ParamUserID].(string)
 
 	// 1. Fetch artifact from service
-	var cli *mlcartifact.Client
-	var err error
-	if s.ArtifactAddr != "" {
-		cli, err = mlcartifact.NewClientWithAddr(s.ArtifactAddr)
-	} else {
-		cli, err = mlcartifact.NewClient()
-	}
-	if err != nil {
-		return mcp.NewToolResultErrorFromErr("Failed to connect to artifact service", err), nil
-	}
-	defer func() {
-		if closeErr := cli.Close(); closeErr != nil {
-			slog.Error("Failed to close artifact client", "error", closeErr)
-		}
-	}()
-
-	opts := []mlcartifact.ReadOption{}
-	if userID != "" {
-		opts = append(opts, mlcartifact.WithReadUserID(userID))
+	store := s.artifactStore()
+	if store == nil {
+		return mcp.NewToolResultError("The artifact service is not available"), nil
 	}
 
-	res, err := cli.Read(ctx, artifactID, opts...)
+	res, err := store.Read(ctx, artifactID, userID)
 	if err != nil {
 		return mcp.NewToolResultErrorFromErr("Failed to read artifact", err), nil
 	}
@@ -149,5 +256,5 @@ func (s *WollmilchsauServer) handleExecuteArtifact(ctx context.Context, req mcp.
 		TimeoutMs:  int(timeout),
 	}
 
-	return s.runExecution(ctx, plan, ToolExecuteArtifact)
+	return s.runExecution(ctx, plan, callOptionsFromArgs(args), ToolExecuteArtifact)
 }
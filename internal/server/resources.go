@@ -0,0 +1,191 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/hmsoft0815/wollmilchsau/internal/executor"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// artifactResources remembers the artifacts created in each MCP session, so
+// that they can be read back as resources by that session only.
+type artifactResources struct {
+	mu       sync.Mutex
+	sessions map[string]map[string]executor.ArtifactRef // session ID → artifact ID → artifact
+}
+
+func newArtifactResources() *artifactResources {
+	return &artifactResources{sessions: make(map[string]map[string]executor.ArtifactRef)}
+}
+
+func (r *artifactResources) add(sessionID string, ref executor.ArtifactRef) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sessions[sessionID] == nil {
+		r.sessions[sessionID] = make(map[string]executor.ArtifactRef)
+	}
+	r.sessions[sessionID][ref.ID] = ref
+}
+
+func (r *artifactResources) lookup(sessionID, id string) (executor.ArtifactRef, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ref, ok := r.sessions[sessionID][id]
+	return ref, ok
+}
+
+// forget drops the artifacts of a closed session. The artifacts themselves
+// stay in the store.
+func (r *artifactResources) forget(sessionID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions, sessionID)
+}
+
+// sessionID returns the ID of the MCP session of ctx, or "" outside of a session.
+func sessionID(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+	return ""
+}
+
+// artifactResourceURI returns the MCP resource URI of a created artifact.
+func artifactResourceURI(ref executor.ArtifactRef) string {
+	return artifactResourcePrefix + url.PathEscape(ref.ID) + "/" + url.PathEscape(ref.Name)
+}
+
+// parseArtifactResourceURI returns the artifact ID of an artifact resource URI.
+func parseArtifactResourceURI(uri string) (string, bool) {
+	rest, ok := strings.CutPrefix(uri, artifactResourcePrefix)
+	if !ok {
+		return "", false
+	}
+	id, _, ok := strings.Cut(rest, "/")
+	if !ok {
+		return "", false
+	}
+	id, err := url.PathUnescape(id)
+	return id, err == nil && id != ""
+}
+
+// publishArtifacts registers the artifacts created by an execution as MCP
+// resources of the calling session and returns the resource_link items
+// pointing at them. Aborted openArtifact handles have no content and are
+// skipped.
+//
+// Sessions that cannot hold their own resources (stdio) get global ones;
+// such a transport only ever serves a single client.
+func (s *WollmilchsauServer) publishArtifacts(ctx context.Context, refs []executor.ArtifactRef) []mcp.Content {
+	sid := sessionID(ctx)
+	var links []mcp.Content
+	var resources []server.ServerResource
+	for _, ref := range refs {
+		if ref.Status == executor.ArtifactAborted || ref.ID == "" {
+			continue
+		}
+		s.resources.add(sid, ref)
+		uri := artifactResourceURI(ref)
+		resources = append(resources, server.ServerResource{
+			Resource: mcp.NewResource(uri, ref.Name,
+				mcp.WithResourceDescription("Artifact "+ref.ID+" ("+ref.URI+")"),
+				mcp.WithMIMEType(ref.MimeType),
+			),
+			Handler: s.handleReadArtifact,
+		})
+		links = append(links, mcp.ResourceLink{
+			Type:     "resource_link",
+			URI:      uri,
+			Name:     ref.Name,
+			MIMEType: ref.MimeType,
+		})
+	}
+	if len(resources) == 0 {
+		return nil
+	}
+
+	err := s.MCPServer.AddSessionResources(sid, resources...)
+	if errors.Is(err, server.ErrSessionDoesNotSupportResources) || errors.Is(err, server.ErrSessionNotFound) {
+		s.MCPServer.AddResources(resources...)
+	} else if err != nil {
+		slog.Warn("failed to register artifact resources", "err", err, "session", sid)
+	}
+	return links
+}
+
+// handleReadArtifact serves resources/read for artifact resources, both the
+// registered ones and those matched by ArtifactResourceTemplate. Only
+// artifacts created in the calling session can be read.
+func (s *WollmilchsauServer) handleReadArtifact(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	uri := req.Params.URI
+	id, ok := parseArtifactResourceURI(uri)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", server.ErrResourceNotFound, uri)
+	}
+	ref, ok := s.resources.lookup(sessionID(ctx), id)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", server.ErrResourceNotFound, uri)
+	}
+
+	store := s.artifactStore()
+	if store == nil {
+		return nil, errors.New("the artifact service is not available")
+	}
+	a, err := store.Read(ctx, id, "")
+	if err != nil {
+		return nil, fmt.Errorf("artifact %s: %w", id, err)
+	}
+
+	mimeType := a.MimeType
+	if mimeType == "" {
+		mimeType = ref.MimeType
+	}
+	if isTextMimeType(mimeType) && utf8.Valid(a.Content) {
+		return []mcp.ResourceContents{mcp.TextResourceContents{URI: uri, MIMEType: mimeType, Text: string(a.Content)}}, nil
+	}
+	return []mcp.ResourceContents{mcp.BlobResourceContents{
+		URI:      uri,
+		MIMEType: mimeType,
+		Blob:     base64.StdEncoding.EncodeToString(a.Content),
+	}}, nil
+}
+
+// handleReadTypes serves the declarations of the sandbox globals.
+func (s *WollmilchsauServer) handleReadTypes(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	return []mcp.ResourceContents{mcp.TextResourceContents{
+		URI:      TypesResourceURI,
+		MIMEType: mimeTypeTypeScript,
+		Text:     GetDeclarations(s.EnableArtifacts, s.Bundles.Imports().Stdlib),
+	}}, nil
+}
+
+// isTextMimeType reports whether content of the given MIME type is returned
+// as text rather than as a base64 blob.
+func isTextMimeType(mimeType string) bool {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "application/typescript", "application/x-ndjson":
+		return true
+	}
+	return false
+}
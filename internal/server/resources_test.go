@@ -0,0 +1,113 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hmsoft0815/wollmilchsau/internal/executor"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// otherSession is a client session that did not create any artifacts.
+type otherSession struct{}
+
+func (otherSession) SessionID() string                                   { return "other" }
+func (otherSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return nil }
+func (otherSession) Initialize()                                         {}
+func (otherSession) Initialized() bool                                   { return true }
+
+func TestArtifactResources(t *testing.T) {
+	ws := New("", true, "", WithPoolSize(0), WithArtifactStore(executor.NewMemoryArtifactStore()))
+	defer ws.Close()
+	ctx := context.Background()
+
+	var req mcp.CallToolRequest
+	req.Params.Arguments = map[string]any{ParamCode: `
+		const txt = wollmilchsau.openArtifact("notes.txt", "text/plain");
+		txt.write("hello");
+		await txt.close();
+		const bin = wollmilchsau.openArtifact("data.bin");
+		bin.write(new Uint8Array([0, 255]));
+		await bin.close();
+	`}
+	res, err := ws.handleExecuteScript(ctx, req)
+	if err != nil || res.IsError {
+		t.Fatalf("execution failed: %v %+v", err, res)
+	}
+	var links []mcp.ResourceLink
+	for _, c := range res.Content {
+		if link, ok := c.(mcp.ResourceLink); ok {
+			links = append(links, link)
+		}
+	}
+	if len(links) != 2 || !strings.HasPrefix(links[0].URI, artifactResourcePrefix) || !strings.HasSuffix(links[0].URI, "/notes.txt") {
+		t.Fatalf("unexpected links: %+v", links)
+	}
+
+	list := ws.MCPServer.HandleMessage(ctx, json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"resources/list"}`))
+	if b, _ := json.Marshal(list); !strings.Contains(string(b), links[1].URI) {
+		t.Errorf("resources/list does not contain %s: %s", links[1].URI, b)
+	}
+
+	read := func(ctx context.Context, uri string) (mcp.ResourceContents, error) {
+		var req mcp.ReadResourceRequest
+		req.Params.URI = uri
+		contents, err := ws.handleReadArtifact(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return contents[0], nil
+	}
+	if c, err := read(ctx, links[0].URI); err != nil || c.(mcp.TextResourceContents).Text != "hello" {
+		t.Errorf("read %s = %+v, %v", links[0].URI, c, err)
+	}
+	if c, err := read(ctx, links[1].URI); err != nil || c.(mcp.BlobResourceContents).Blob != base64.StdEncoding.EncodeToString([]byte{0, 255}) {
+		t.Errorf("read %s = %+v, %v", links[1].URI, c, err)
+	}
+
+	// Other sessions cannot read the artifacts, not even via the template.
+	other := ws.MCPServer.WithContext(ctx, otherSession{})
+	if _, err := read(other, links[0].URI); err == nil {
+		t.Error("another session could read the artifact")
+	}
+}
+
+func TestIsTextMimeType(t *testing.T) {
+	for mimeType, want := range map[string]bool{
+		"text/csv":                  true,
+		"text/plain; charset=utf-8": true,
+		"application/json":          true,
+		"image/svg+xml":             true,
+		"image/png":                 false,
+		"application/octet-stream":  false,
+		"":                          false,
+	} {
+		if got := isTextMimeType(mimeType); got != want {
+			t.Errorf("isTextMimeType(%q) = %v, want %v", mimeType, got, want)
+		}
+	}
+}
+
+func TestTypesResource(t *testing.T) {
+	for _, enableArtifacts := range []bool{false, true} {
+		ws := New("", enableArtifacts, "", WithPoolSize(0), WithArtifactStore(executor.NewMemoryArtifactStore()))
+		var req mcp.ReadResourceRequest
+		req.Params.URI = TypesResourceURI
+		contents, err := ws.handleReadTypes(context.Background(), req)
+		ws.Close()
+		if err != nil || len(contents) != 1 {
+			t.Fatalf("reading the types failed: %v %v", err, contents)
+		}
+		text := contents[0].(mcp.TextResourceContents).Text
+		if !strings.Contains(text, "declare var wollmilchsau: Wollmilchsau;") {
+			t.Errorf("the declarations lack the namespace:\n%s", text)
+		}
+		if got := strings.Contains(text, "declare var artifact:"); got != enableArtifacts {
+			t.Errorf("artifacts enabled = %v, but artifact declared = %v", enableArtifacts, got)
+		}
+	}
+}
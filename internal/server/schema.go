@@ -10,9 +10,39 @@ type ExecutionResult struct {
 	ExitCode    int                   `json:"exitCode"`
 	DurationMs  int64                 `json:"durationMs,omitempty"`
 	Diagnostics []executor.Diagnostic `json:"diagnostics,omitempty"`
+
+	// ReturnValue is the JSON value passed to wollmilchsau.return() or
+	// exported as default by the entry module.
+	ReturnValue any `json:"returnValue,omitempty" jsonschema:"description=JSON value from wollmilchsau.return() or the default export of the entry file"`
+
+	// Set when stdout, stderr or the return value exceeded the output
+	// budget; the sizes are those of the complete output. A return value
+	// over the budget is left out and only its size is reported.
+	Truncated        bool `json:"truncated,omitempty"`
+	StdoutBytes      int  `json:"stdoutBytes,omitempty"`
+	StderrBytes      int  `json:"stderrBytes,omitempty"`
+	ReturnValueBytes int  `json:"returnValueBytes,omitempty"`
+
+	// Timing is only set if the call asked for it.
+	Timing *Timing `json:"timing,omitempty"`
+
+	// CreatedArtifacts lists the artifacts written by the script with their
+	// backend URIs; the resource_link items point at the MCP resources.
+	CreatedArtifacts []executor.ArtifactRef `json:"createdArtifacts,omitempty"`
+}
+
+// Timing breaks down the duration of an execution tool call.
+type Timing struct {
+	BundleMs     int64 `json:"bundleMs"`              // bundling, or looking up the bundle cache
+	BundleCached bool  `json:"bundleCached"`          // the bundle came from the cache
+	TypecheckMs  int64 `json:"typecheckMs,omitempty"` // type checking, if requested
+	InputFetchMs int64 `json:"inputFetchMs"`          // reading the input artifacts
+	ExecuteMs    int64 `json:"executeMs"`             // running the script (incl. sandbox setup)
+	TotalMs      int64 `json:"totalMs"`               // the whole tool call
 }
 
-// CheckSyntaxResult represents the structured output of the check_syntax tool.
+// CheckSyntaxResult represents the structured output of the check_syntax and
+// check_project tools.
 type CheckSyntaxResult struct {
 	Success     bool                  `json:"success"`
 	Summary     string                `json:"summary"`
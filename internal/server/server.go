@@ -4,7 +4,12 @@ package server
 
 import (
 	"context"
+	"log/slog"
 
+	"github.com/hmsoft0815/wollmilchsau/internal/bundler"
+	"github.com/hmsoft0815/wollmilchsau/internal/executor"
+	"github.com/hmsoft0815/wollmilchsau/internal/lint"
+	"github.com/hmsoft0815/wollmilchsau/internal/typecheck"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
@@ -15,6 +20,86 @@ type WollmilchsauServer struct {
 	LogDir          string
 	EnableArtifacts bool
 	ArtifactAddr    string
+	Artifacts       executor.ArtifactStore // artifact backend shared by all executions; the mlcartifact service at ArtifactAddr by default
+	Pool            *executor.Pool
+	Bundles         *bundler.Cache     // recently built bundles
+	Limits          executor.Limits    // operator limits; tool calls may only tighten them
+	SpillOutput     bool               // save output exceeding the budget as an artifact
+	TypeChecker     *typecheck.Checker // runs the typecheck option of the check tools and execute_project, nil if not available
+	LintRules       lint.Config        // levels of the lint rules of the lint tool and option
+
+	resources *artifactResources // artifacts served as MCP resources, per session
+}
+
+// Option configures optional features of the WollmilchsauServer.
+type Option func(*config)
+
+type config struct {
+	poolSize    int
+	bundleCache int
+	modules     *bundler.Registry
+	stdlib      *bundler.Stdlib
+	typeChecker *typecheck.Checker
+	lintRules   lint.Config
+	limits      executor.Limits
+	spillOutput bool
+	artifacts   executor.ArtifactStore
+}
+
+// WithPoolSize sets the number of warm V8 isolates kept ready for execution.
+// A size of 0 disables pooling.
+func WithPoolSize(size int) Option {
+	return func(c *config) { c.poolSize = size }
+}
+
+// WithBundleCacheSize sets the number of bundles kept for repeated runs of
+// the same project. A size of 0 disables the cache.
+func WithBundleCacheSize(size int) Option {
+	return func(c *config) { c.bundleCache = size }
+}
+
+// WithModules lets scripts import the packages of an offline module registry.
+// The packages are listed in the tool descriptions.
+func WithModules(modules *bundler.Registry) Option {
+	return func(c *config) { c.modules = modules }
+}
+
+// WithStdlib lets scripts import the operator's helper modules under
+// bundler.StdPrefix. They are listed in the usage prompt and their
+// declarations in the check_syntax description.
+func WithStdlib(stdlib *bundler.Stdlib) Option {
+	return func(c *config) { c.stdlib = stdlib }
+}
+
+// WithTypeChecker enables the typecheck option of check_syntax,
+// check_project and execute_project. The checker is owned by the caller.
+func WithTypeChecker(checker *typecheck.Checker) Option {
+	return func(c *config) { c.typeChecker = checker }
+}
+
+// WithLintRules configures the rules of the lint tool and of the lint option
+// of the execution tools. Rules that are not configured use their default
+// level.
+func WithLintRules(rules lint.Config) Option {
+	return func(c *config) { c.lintRules = rules }
+}
+
+// WithLimits sets the operator resource limits. Unset fields use executor.DefaultLimits.
+func WithLimits(limits executor.Limits) Option {
+	return func(c *config) { c.limits = limits }
+}
+
+// WithSpillOutput saves the complete stdout/stderr and return value as an
+// artifact whenever they exceed the output budget. It only has an effect with artifacts enabled.
+func WithSpillOutput(enabled bool) Option {
+	return func(c *config) { c.spillOutput = enabled }
+}
+
+// WithArtifactStore replaces the mlcartifact service by another artifact
+// backend, e.g. executor.NewDirArtifactStore or executor.NewMemoryArtifactStore.
+// The store is shared by all executions and closed by Close.
+func WithArtifactStore(store executor.ArtifactStore) Option {
+	return func(c *config) { c.artifacts = store }
 }
 
 // serverIcon is the default icon for the wollmilchsau server (a "terminal/code" glyph).
@@ -24,18 +109,28 @@ var serverIcon = mcp.Icon{
 }
 
 // New creates a new MCP server wrapper for TypeScript execution.
-func New(logDir string, enableArtifacts bool, artifactAddr string) *WollmilchsauServer {
+func New(logDir string, enableArtifacts bool, artifactAddr string, opts ...Option) *WollmilchsauServer {
+	cfg := config{poolSize: DefaultPoolSize, bundleCache: bundler.DefaultCacheSize}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	resources := newArtifactResources()
 	hooks := &server.Hooks{}
 	hooks.AddAfterInitialize(func(_ context.Context, _ any, _ *mcp.InitializeRequest, result *mcp.InitializeResult) {
 		result.ServerInfo.Title = ServerTitle
 		result.ServerInfo.Icons = []mcp.Icon{serverIcon}
 	})
+	hooks.AddOnUnregisterSession(func(_ context.Context, session server.ClientSession) {
+		resources.forget(session.SessionID())
+	})
 
 	s := server.NewMCPServer(
 		ServerName,
 		ServerVersion,
 		server.WithToolCapabilities(true),
 		server.WithPromptCapabilities(true),
+		server.WithResourceCapabilities(false, true),
 		server.WithHooks(hooks),
 	)
 
@@ -44,16 +139,68 @@ func New(logDir string, enableArtifacts bool, artifactAddr string) *Wollmilchsau
 		LogDir:          logDir,
 		EnableArtifacts: enableArtifacts,
 		ArtifactAddr:    artifactAddr,
+		Artifacts:       cfg.artifacts,
+		Pool:            executor.NewPool(cfg.poolSize),
+		Bundles:         bundler.NewCache(cfg.bundleCache, bundler.Imports{Modules: cfg.modules, Stdlib: cfg.stdlib}),
+		Limits:          cfg.limits.WithDefaults(),
+		SpillOutput:     cfg.spillOutput,
+		TypeChecker:     cfg.typeChecker,
+		LintRules:       cfg.lintRules,
+		resources:       resources,
 	}
 
-	s.AddTool(toolExecuteScript(enableArtifacts), ws.handleExecuteScript)
-	s.AddTool(toolExecuteProject(enableArtifacts), ws.handleExecuteProject)
+	// One connection to the mlcartifact service serves the whole server. It
+	// is only dialed when artifacts are used; the initial ping just reports
+	// whether the service is reachable.
+	if enableArtifacts && ws.Artifacts == nil {
+		svc, err := executor.NewServiceArtifactStore(artifactAddr)
+		if err != nil {
+			slog.Error("failed to create artifact client", "err", err)
+		} else {
+			ws.Artifacts = svc
+			go func() {
+				if err := svc.Ping(context.Background()); err == nil {
+					slog.Info("artifact service reachable", "addr", svc.Addr())
+				}
+			}()
+		}
+	}
+
+	s.AddTool(toolExecuteScript(enableArtifacts, cfg.modules), ws.handleExecuteScript)
+	typecheckEnabled := cfg.typeChecker != nil
+	s.AddTool(toolExecuteProject(enableArtifacts, cfg.modules, typecheckEnabled), ws.handleExecuteProject)
+	if enableArtifacts {
+		s.AddTool(toolExecuteArtifact(enableArtifacts, cfg.modules), ws.handleExecuteArtifact)
+	}
+	s.AddTool(toolCheckSyntax(cfg.stdlib, typecheckEnabled), ws.handleCheckSyntax)
+	s.AddTool(toolCheckProject(cfg.modules, typecheckEnabled), ws.handleCheckProject)
+	s.AddTool(toolLint(cfg.lintRules), ws.handleLint)
+
+	// Artifacts created by scripts are served back as resources, so the
+	// resource_link items in the results resolve for every client.
 	if enableArtifacts {
-		s.AddTool(toolExecuteArtifact(enableArtifacts), ws.handleExecuteArtifact)
+		s.AddResourceTemplate(mcp.NewResourceTemplate(ArtifactResourceTemplate, artifactResourceTemplateName,
+			mcp.WithTemplateDescription(artifactResourceTemplateDescription),
+		), ws.handleReadArtifact)
 	}
-	s.AddTool(toolCheckSyntax(), ws.handleCheckSyntax)
+
+	s.AddResource(mcp.NewResource(TypesResourceURI, typesResourceName,
+		mcp.WithResourceDescription(typesResourceDescription),
+		mcp.WithMIMEType(mimeTypeTypeScript),
+	), ws.handleReadTypes)
 
 	s.AddPrompt(mcp.NewPrompt(PromptUsage, mcp.WithPromptDescription(PromptUsageDescription)), ws.handlePromptUsage)
 
 	return ws
 }
+
+// Close releases all resources held by the server, e.g. the warm isolates
+// and the connection to the artifact service.
+func (ws *WollmilchsauServer) Close() {
+	ws.Pool.Close()
+	if ws.Artifacts != nil {
+		if err := ws.Artifacts.Close(); err != nil {
+			slog.Error("failed to close artifact store", "err", err)
+		}
+	}
+}
@@ -2,26 +2,33 @@
 package server
 
 import (
+	"github.com/hmsoft0815/wollmilchsau/internal/bundler"
+	"github.com/hmsoft0815/wollmilchsau/internal/lint"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
 // GetTools returns the definitions of all tools registered in this server.
-func GetTools(enableArtifacts bool) []mcp.Tool {
+// The packages and stdlib modules of imp are described in the tools; the
+// typecheck option is only offered if typecheck is set. The lint tool lists
+// the rules enabled in lintRules.
+func GetTools(enableArtifacts bool, imp bundler.Imports, typecheck bool, lintRules lint.Config) []mcp.Tool {
 	tools := []mcp.Tool{
-		toolExecuteScript(enableArtifacts),
-		toolExecuteProject(enableArtifacts),
-		toolCheckSyntax(),
+		toolExecuteScript(enableArtifacts, imp.Modules),
+		toolExecuteProject(enableArtifacts, imp.Modules, typecheck),
+		toolCheckSyntax(imp.Stdlib, typecheck),
+		toolCheckProject(imp.Modules, typecheck),
+		toolLint(lintRules),
 	}
 	if enableArtifacts {
-		tools = append(tools, toolExecuteArtifact(enableArtifacts))
+		tools = append(tools, toolExecuteArtifact(enableArtifacts, imp.Modules))
 	}
 	return tools
 }
 
-func toolCheckSyntax() mcp.Tool {
-	return mcp.NewTool(
+func toolCheckSyntax(stdlib *bundler.Stdlib, typecheck bool) mcp.Tool {
+	tool := mcp.NewTool(
 		ToolCheckSyntax,
-		mcp.WithDescription(ToolCheckSyntaxDescription),
+		mcp.WithDescription(ToolCheckSyntaxDescription+GetStdlibDeclarations(stdlib)),
 		mcp.WithString(ParamCode,
 			mcp.Required(),
 			mcp.Description(ParamCodeDescription),
@@ -32,12 +39,16 @@ func toolCheckSyntax() mcp.Tool {
 		}),
 		mcp.WithOutputSchema[CheckSyntaxResult](),
 	)
+	if typecheck {
+		withTypecheckParam()(&tool)
+	}
+	return tool
 }
 
-func toolExecuteScript(enableArtifacts bool) mcp.Tool {
-	return mcp.NewTool(
+func toolExecuteScript(enableArtifacts bool, modules *bundler.Registry) mcp.Tool {
+	tool := mcp.NewTool(
 		ToolExecuteScript,
-		mcp.WithDescription(GetToolExecuteScriptDescription(enableArtifacts)),
+		mcp.WithDescription(GetToolExecuteScriptDescription(enableArtifacts)+GetPackagesConstraint(modules)),
 		mcp.WithString(ParamCode,
 			mcp.Required(),
 			mcp.Description(ParamCodeDescription),
@@ -45,45 +56,45 @@ func toolExecuteScript(enableArtifacts bool) mcp.Tool {
 		mcp.WithNumber(ParamTimeoutMs,
 			mcp.Description(ParamTimeoutMsDescription),
 		),
+		withInputParam(),
+		withLimitsParam(),
+		withTimingParam(),
+		withLintParam(),
 		mcp.WithToolIcons(mcp.Icon{
 			Src:      "data:image/svg+xml;base64,PHN2ZyB4bWxucz0iaHR0cDovL3d3dy53My5vcmcvMjAwMC9zdmciIHdpZHRoPSIyNCIgaGVpZ2h0PSIyNCIgdmlld0JveD0iMCAwIDI0IDI0IiBmaWxsPSJub25lIiBzdHJva2U9ImN1cnJlbnRDb2xvciIgc3Ryb2tlLXdpZHRoPSIyIiBzdHJva2UtbGluZWNhcD0icm91bmQiIHN0cm9rZS1saW5lam9pbj0icm91bmQiPjxwYXRoIGQ9Ik0xNiAxOGwtMiAybC0yLTIybTQtOGw0IDRsLTQgNE0yMiAxOXYtMk0xNSA1aC0yTTUgNWgtMk01IDE1aC0yTTUgMTloLTJNMjIgNXYtMk0yMiAxOXYtMk05IDVoLTJNOSAxOWgtMk0xMyA1aC0yTTEzIDE5aC0yTTE3IDVoLTJNMjIgOXYtMiIvPjwvc3ZnPg==",
 			MIMEType: mimeTypeSVG,
 		}),
 		mcp.WithOutputSchema[ExecutionResult](),
 	)
+	if enableArtifacts {
+		withInputArtifactsParam()(&tool)
+	}
+	return tool
 }
 
-func toolExecuteProject(enableArtifacts bool) mcp.Tool {
+func toolExecuteProject(enableArtifacts bool, modules *bundler.Registry, typecheck bool) mcp.Tool {
 	tool := mcp.NewTool(
 		ToolExecuteProject,
-		mcp.WithDescription(GetToolExecuteProjectDescription(enableArtifacts)),
+		mcp.WithDescription(GetToolExecuteProjectDescription(enableArtifacts)+GetPackagesConstraint(modules)),
 	)
 
-	// Manually add the complex 'files' property since helper functions are limited
-	tool.InputSchema.Properties[ParamFiles] = map[string]any{
-		"type": "array",
-		"items": map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"name":    map[string]any{"type": "string", "description": "Filename (e.g. main.ts)"},
-				"content": map[string]any{"type": "string", "description": "File content"},
-			},
-			"required": []string{"name", "content"},
-		},
-		"description": ParamFilesDescription,
-	}
-	tool.InputSchema.Required = append(tool.InputSchema.Required, ParamFiles)
-
-	// Add simpler properties using helpers
-	mcp.WithString(ParamEntryPoint,
-		mcp.Required(),
-		mcp.Description(ParamEntryPointDescription),
-	)(&tool)
+	withProjectParams()(&tool)
 
 	mcp.WithNumber(ParamTimeoutMs,
 		mcp.Description(ParamTimeoutMsDescription),
 	)(&tool)
 
+	withInputParam()(&tool)
+	if enableArtifacts {
+		withInputArtifactsParam()(&tool)
+	}
+	withLimitsParam()(&tool)
+	withTimingParam()(&tool)
+	withLintParam()(&tool)
+	if typecheck {
+		withTypecheckParam()(&tool)
+	}
+
 	mcp.WithToolIcons(mcp.Icon{
 		Src:      "data:image/svg+xml;base64,PHN2ZyB4bWxucz0iaHR0cDovL3d3dy53My5vcmcvMjAwMC9zdmciIHdpZHRoPSIyNCIgaGVpZ2h0PSIyNCIgdmlld0JveD0iMCAwIDI0IDI0IiBmaWxsPSJub25lIiBzdHJva2U9ImN1cnJlbnRDb2xvciIgc3Ryb2tlLXdpZHRoPSIyIiBzdHJva2UtbGluZWNhcD0icm91bmQiIHN0cm9rZS1saW5lam9pbj0icm91bmQiPjxwYXRoIGQ9Ik0xMiAyTDQgNnYxMmwxIDguNWwtOC00VjZ6TTEyIDIybDgtNGwtOC00TC04IDR6TTQgNmw4IDRsOC00TTIgMTV2MkwxMiAyMmw4LTUtMnYtMiIvPjwvc3ZnPg==",
 		MIMEType: mimeTypeSVG,
@@ -94,10 +105,43 @@ func toolExecuteProject(enableArtifacts bool) mcp.Tool {
 	return tool
 }
 
-func toolExecuteArtifact(enableArtifacts bool) mcp.Tool {
+func toolCheckProject(modules *bundler.Registry, typecheck bool) mcp.Tool {
+	tool := mcp.NewTool(
+		ToolCheckProject,
+		mcp.WithDescription(ToolCheckProjectDescription+GetPackagesConstraint(modules)),
+		withProjectParams(),
+		mcp.WithToolIcons(mcp.Icon{
+			Src:      "data:image/svg+xml;base64,PHN2ZyB4bWxucz0iaHR0cDovL3d3dy53My5vcmcvMjAwMC9zdmciIHdpZHRoPSIyNCIgaGVpZ2h0PSIyNCIgdmlld0JveD0iMCAwIDI0IDI0IiBmaWxsPSJub25lIiBzdHJva2U9ImN1cnJlbnRDb2xvciIgc3Ryb2tlLXdpZHRoPSIyIiBzdHJva2UtbGluZWNhcD0icm91bmQiIHN0cm9rZS1saW5lam9pbj0icm91bmQiPjxwYXRoIGQ9Ik05IDExbDMgMyA4LTgtMi0yLTEwIDEwem0tMyAwbC00IDQgNCA0IDItMi00LTQtMi0yek0wIDI0aDI0Ii8+PC9zdmc+",
+			MIMEType: mimeTypeSVG,
+		}),
+		mcp.WithOutputSchema[CheckSyntaxResult](),
+	)
+	if typecheck {
+		withTypecheckParam()(&tool)
+	}
+	return tool
+}
+
+func toolLint(rules lint.Config) mcp.Tool {
+	return mcp.NewTool(
+		ToolLint,
+		mcp.WithDescription(ToolLintDescription+GetLintRules(rules)),
+		mcp.WithString(ParamCode,
+			mcp.Description(ParamLintCodeDescription),
+		),
+		withFilesParam(ParamLintFilesDescription),
+		mcp.WithToolIcons(mcp.Icon{
+			Src:      "data:image/svg+xml;base64,PHN2ZyB4bWxucz0iaHR0cDovL3d3dy53My5vcmcvMjAwMC9zdmciIHdpZHRoPSIyNCIgaGVpZ2h0PSIyNCIgdmlld0JveD0iMCAwIDI0IDI0IiBmaWxsPSJub25lIiBzdHJva2U9ImN1cnJlbnRDb2xvciIgc3Ryb2tlLXdpZHRoPSIyIiBzdHJva2UtbGluZWNhcD0icm91bmQiIHN0cm9rZS1saW5lam9pbj0icm91bmQiPjxwYXRoIGQ9Ik05IDExbDMgMyA4LTgtMi0yLTEwIDEwem0tMyAwbC00IDQgNCA0IDItMi00LTQtMi0yek0wIDI0aDI0Ii8+PC9zdmc+",
+			MIMEType: mimeTypeSVG,
+		}),
+		mcp.WithOutputSchema[CheckSyntaxResult](),
+	)
+}
+
+func toolExecuteArtifact(enableArtifacts bool, modules *bundler.Registry) mcp.Tool {
 	return mcp.NewTool(
 		ToolExecuteArtifact,
-		mcp.WithDescription(GetToolExecuteArtifactDescription(enableArtifacts)),
+		mcp.WithDescription(GetToolExecuteArtifactDescription(enableArtifacts)+GetPackagesConstraint(modules)),
 		mcp.WithString(ParamArtifactID,
 			mcp.Required(),
 			mcp.Description(ParamArtifactIDDescription),
@@ -108,6 +152,11 @@ func toolExecuteArtifact(enableArtifacts bool) mcp.Tool {
 		mcp.WithNumber(ParamTimeoutMs,
 			mcp.Description(ParamTimeoutMsDescription),
 		),
+		withInputParam(),
+		withInputArtifactsParam(),
+		withLimitsParam(),
+		withTimingParam(),
+		withLintParam(),
 		mcp.WithToolIcons(mcp.Icon{
 			Src:      "data:image/svg+xml;base64,PHN2ZyB4bWxucz0iaHR0cDovL3d3dy53My5vcmcvMjAwMC9zdmciIHdpZHRoPSIyNCIgaGVpZ2h0PSIyNCIgdmlld0JveD0iMCAwIDI0IDI0IiBmaWxsPSJub25lIiBzdHJva2U9ImN1cnJlbnRDb2xvciIgc3Ryb2tlLXdpZHRoPSIyIiBzdHJva2UtbGluZWNhcD0icm91bmQiIHN0cm9rZS1saW5lam9pbj0icm91bmQiPjxwYXRoIGQ9Ik0xNCAydkg2YTIgMiAwIDAgMC0yIDJ2MTZhMiAyIDAgMCAwIDIgMmgxMmEyIDIgMCAwIDAgMi0yVjhsLTYtNnoiLz48cG9seWxpbmUgcG9pbnRzPSIxNCAyIDE0IDggMjAgOCIvPjwvc3ZnPg==",
 			MIMEType: mimeTypeSVG,
@@ -115,3 +164,100 @@ func toolExecuteArtifact(enableArtifacts bool) mcp.Tool {
 		mcp.WithOutputSchema[ExecutionResult](),
 	)
 }
+
+// withProjectParams adds the 'files' and 'entryPoint' parameters shared by
+// execute_project and check_project.
+func withProjectParams() mcp.ToolOption {
+	return func(tool *mcp.Tool) {
+		withFilesParam(ParamFilesDescription)(tool)
+		tool.InputSchema.Required = append(tool.InputSchema.Required, ParamFiles)
+
+		mcp.WithString(ParamEntryPoint,
+			mcp.Required(),
+			mcp.Description(ParamEntryPointDescription),
+		)(tool)
+	}
+}
+
+// withFilesParam adds the 'files' list of virtual files.
+func withFilesParam(description string) mcp.ToolOption {
+	return func(tool *mcp.Tool) {
+		// Manually add the complex 'files' property since helper functions are limited
+		tool.InputSchema.Properties[ParamFiles] = map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name":    map[string]any{"type": "string", "description": "Filename (e.g. main.ts)"},
+					"content": map[string]any{"type": "string", "description": "File content"},
+				},
+				"required": []string{"name", "content"},
+			},
+			"description": description,
+		}
+	}
+}
+
+// withLintParam adds the optional 'lint' flag shared by all execution tools.
+func withLintParam() mcp.ToolOption {
+	return mcp.WithBoolean(ParamLint,
+		mcp.Description(ParamLintDescription),
+	)
+}
+
+// withInputParam adds the optional 'input' value shared by all execution tools.
+func withInputParam() mcp.ToolOption {
+	return mcp.WithAny(ParamInput,
+		mcp.Description(ParamInputDescription),
+	)
+}
+
+// withInputArtifactsParam adds the optional 'inputArtifacts' list. It is only
+// offered when the artifact service is enabled.
+func withInputArtifactsParam() mcp.ToolOption {
+	return mcp.WithArray(ParamInputArtifacts,
+		mcp.Description(ParamInputArtifactsDescription),
+		mcp.Items(map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"id":     map[string]any{"type": "string", "description": "Artifact ID or filename"},
+				"alias":  map[string]any{"type": "string", "description": "Key in wollmilchsau.files (default: the artifact filename)"},
+				"userId": map[string]any{"type": "string", "description": "Optional user ID to scope the artifact lookup"},
+			},
+			"required": []string{"id"},
+		}),
+	)
+}
+
+// withTimingParam adds the optional 'timing' flag shared by all execution tools.
+func withTimingParam() mcp.ToolOption {
+	return mcp.WithBoolean(ParamTiming,
+		mcp.Description(ParamTimingDescription),
+	)
+}
+
+// withTypecheckParam adds the optional 'typecheck' flag. It is only offered
+// when the server has a TypeScript compiler.
+func withTypecheckParam() mcp.ToolOption {
+	return mcp.WithBoolean(ParamTypecheck,
+		mcp.Description(ParamTypecheckDescription),
+	)
+}
+
+// withLimitsParam adds the optional 'limits' object shared by all execution tools.
+func withLimitsParam() mcp.ToolOption {
+	return mcp.WithObject(ParamLimits,
+		mcp.Description(ParamLimitsDescription),
+		mcp.Properties(map[string]any{
+			"maxHeapBytes":      map[string]any{"type": "number", "description": "Maximum V8 heap size in bytes"},
+			"maxStdoutBytes":    map[string]any{"type": "number", "description": "Maximum captured stdout in bytes"},
+			"maxStderrBytes":    map[string]any{"type": "number", "description": "Maximum captured stderr in bytes"},
+			"maxWallTimeMs":     map[string]any{"type": "number", "description": "Maximum wall time in milliseconds, including waits for timers"},
+			"maxCpuTimeMs":      map[string]any{"type": "number", "description": "Maximum time actively executing JavaScript in milliseconds"},
+			"outputBudgetBytes": map[string]any{"type": "number", "description": "Bytes of stdout/stderr returned per stream; the rest is truncated"},
+			"outputBudgetLines": map[string]any{"type": "number", "description": "Lines of stdout/stderr returned per stream; the rest is truncated"},
+			"maxInputBytes":     map[string]any{"type": "number", "description": "Maximum size of the input in bytes"},
+			"maxArtifactBytes":  map[string]any{"type": "number", "description": "Maximum size written to one openArtifact handle in bytes"},
+		}),
+	)
+}
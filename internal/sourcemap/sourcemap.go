@@ -149,24 +149,7 @@ func (sm *SourceMap) Resolve(generatedLine, generatedColumn int) *OriginalPositi
 		return nil
 	}
 
-	// Strip the internal tmpdir prefix from the source path to return only the virtual file name.
 	src := sm.sources[best.sourceIdx]
-	const pattern = "/ts_mcp_"
-	if idx := strings.LastIndex(src, pattern); idx >= 0 {
-		// Find the next slash after the temporary identifier segment
-		rest := src[idx+len(pattern):]
-		if slash := strings.Index(rest, "/"); slash >= 0 {
-			src = rest[slash+1:]
-		} else {
-			// If no further slash, it might be just the ID followed by the filename
-			// but usually MkdirTemp with * suffix puts everything in that folder.
-			// Let's fallback to Base if we can't find a clear relative path.
-			src = rest
-		}
-	} else if strings.Contains(src, "/tmp/") || strings.Contains(src, "\\Temp\\") {
-		// General fallback for other temp paths
-		src = strings.Split(src, "/")[len(strings.Split(src, "/"))-1]
-	}
 
 	return &OriginalPosition{
 		Source: src,
@@ -77,7 +77,7 @@ func TestExtractFromBase64(t *testing.T) {
 	// Mappings "AAAA" = [0,0,0,0] (genCol=0, srcIdx=0, srcLine=0, srcCol=0)
 	mapObj := map[string]any{
 		"version":  3,
-		"sources":  []string{"/tmp/ts_mcp_123/main.ts"},
+		"sources":  []string{"main.ts"},
 		"mappings": "AAAA",
 	}
 	mapJSON, _ := json.Marshal /* nolint:errcheck */ (mapObj)
@@ -94,7 +94,6 @@ func TestExtractFromBase64(t *testing.T) {
 	if orig == nil {
 		t.Fatal("expected a resolved position, got nil")
 	}
-	// Source path should have tmpdir stripped → "main.ts"
 	if orig.Source != "main.ts" {
 		t.Errorf("expected source 'main.ts', got %q", orig.Source)
 	}
@@ -0,0 +1,93 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package typecheck
+
+// driverJS provides __wms_check(input), which type checks the files of a
+// request (see request) with the global ts of typescript.js and returns the
+// diagnostics as JSON (see diagnostic).
+//
+// The compiler sees a small virtual file system: the request files, the lib
+// files under /lib and the packages under /project/node_modules. The latter
+// two are read through __wms_read and __wms_stat and never change, so their
+// parsed source files are kept for the next checks.
+const driverJS = `(function() {
+	const cache = new Map();
+	const has = (obj, key) => Object.prototype.hasOwnProperty.call(obj, key);
+
+	function options(req) {
+		const resolution = ts.ModuleResolutionKind;
+		const detection = ts.ModuleDetectionKind;
+		return {
+			target: ts.ScriptTarget.ES2022 !== undefined ? ts.ScriptTarget.ES2022 : ts.ScriptTarget.ES2020,
+			module: ts.ModuleKind.ESNext,
+			moduleResolution: resolution.Bundler !== undefined ? resolution.Bundler : resolution.NodeJs,
+			// Every file is a module, as for esbuild, so files do not share
+			// top-level names and may use top-level await.
+			moduleDetection: detection ? detection.Force : undefined,
+			lib: [req.lib],
+			types: [],
+			strict: true,
+			noImplicitAny: false,
+			noEmit: true,
+			skipLibCheck: true,
+			allowJs: true,
+			resolveJsonModule: true,
+			esModuleInterop: true,
+			allowImportingTsExtensions: true,
+		};
+	}
+
+	function host(req) {
+		const files = req.files;
+		const dirs = new Set(['/lib']);
+		for (const name of Object.keys(files)) {
+			for (let d = name.slice(0, name.lastIndexOf('/')); d; d = d.slice(0, d.lastIndexOf('/'))) dirs.add(d);
+		}
+		return {
+			getSourceFile(name, languageVersion) {
+				if (has(files, name)) return ts.createSourceFile(name, files[name], languageVersion);
+				let file = cache.get(name);
+				if (file === undefined) {
+					const text = __wms_read(name);
+					if (text === undefined) return undefined;
+					file = ts.createSourceFile(name, text, languageVersion);
+					cache.set(name, file);
+				}
+				return file;
+			},
+			getDefaultLibFileName: () => '/lib/' + req.lib,
+			getDefaultLibLocation: () => '/lib',
+			writeFile() {},
+			getCurrentDirectory: () => req.projectDir,
+			getDirectories: () => [],
+			getCanonicalFileName: (name) => name,
+			useCaseSensitiveFileNames: () => true,
+			getNewLine: () => '\n',
+			fileExists: (name) => has(files, name) || __wms_stat(name) === 1,
+			readFile: (name) => has(files, name) ? files[name] : __wms_read(name),
+			directoryExists: (name) => dirs.has(name.replace(/\/$/, '')) || __wms_stat(name) === 2,
+			realpath: (name) => name,
+		};
+	}
+
+	globalThis.__wms_check = function(input) {
+		const req = JSON.parse(input);
+		const program = ts.createProgram(req.roots, options(req), host(req));
+		// Only the request files are reported, not the lib files or packages.
+		const diags = ts.getPreEmitDiagnostics(program).filter((d) => !d.file || has(req.files, d.file.fileName));
+		return JSON.stringify(diags.map((d) => {
+			const out = {
+				category: d.category,
+				code: d.code,
+				message: ts.flattenDiagnosticMessageText(d.messageText, '\n'),
+			};
+			if (d.file && d.start !== undefined) {
+				const pos = d.file.getLineAndCharacterOfPosition(d.start);
+				out.file = d.file.fileName;
+				out.line = pos.line;
+				out.column = pos.character;
+			}
+			return out;
+		}));
+	};
+})();
+`
@@ -0,0 +1,381 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+// Package typecheck type checks scripts with the TypeScript compiler, which
+// runs inside a dedicated V8 isolate.
+package typecheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hmsoft0815/wollmilchsau/internal/executor"
+	"github.com/hmsoft0815/wollmilchsau/internal/parser"
+	v8 "rogchap.com/v8go"
+)
+
+// DefaultTimeout bounds a Check whose context has no deadline.
+const DefaultTimeout = 30 * time.Second
+
+// maxHeapBytes is the heap size above which the isolate is recreated after a
+// check. Parsed lib files are cached in the isolate, so it normally stays
+// far below.
+const maxHeapBytes = 512 << 20
+
+// Paths of the virtual file system seen by the compiler.
+const (
+	projectDir   = "/project/"
+	libDir       = "/lib/"
+	globalsFile  = "/wollmilchsau/globals.d.ts"
+	nodeModules  = projectDir + "node_modules/"
+	compilerFile = "lib/typescript.js"
+)
+
+// ErrNotConfigured is returned by the methods of a nil Checker.
+var ErrNotConfigured = errors.New("type checking is not available: the server has no TypeScript compiler")
+
+// libCandidates are the lib files for the ES built-ins, newest first; the
+// newest one shipped with the compiler is used.
+var libCandidates = []string{"lib.es2023.d.ts", "lib.es2022.d.ts", "lib.es2021.d.ts", "lib.es2020.d.ts"}
+
+// Checker type checks scripts with the TypeScript compiler of an npm
+// "typescript" package, e.g. installed with "npm install typescript". The
+// compiler is loaded once into an isolate that serves one check at a time.
+type Checker struct {
+	dir        string // the typescript package
+	modulesDir string // node_modules-like directory of importable packages, optional
+	source     string // lib/typescript.js
+	lib        string // lib file of the ES built-ins
+	version    string
+
+	sem chan struct{} // held while the isolate is in use
+	iso *v8.Isolate
+	ctx *v8.Context
+}
+
+// Input is a set of files to type check.
+type Input struct {
+	Files []parser.VirtualFile
+
+	// Declarations are ambient declarations of everything the sandbox adds
+	// to the ECMAScript built-ins, see executor.Declarations.
+	Declarations string
+}
+
+// New loads the compiler of the typescript package in dir. Imports of npm
+// packages are resolved in modulesDir, which may be "".
+func New(dir, modulesDir string) (*Checker, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("typescript %s: %w", dir, err)
+	}
+	src, err := os.ReadFile(filepath.Join(abs, filepath.FromSlash(compilerFile)))
+	if err != nil {
+		return nil, fmt.Errorf("typescript %s: %w", dir, err)
+	}
+	c := &Checker{dir: abs, modulesDir: modulesDir, source: string(src), sem: make(chan struct{}, 1)}
+	for _, lib := range libCandidates {
+		if _, err := os.Stat(filepath.Join(abs, "lib", lib)); err == nil {
+			c.lib = lib
+			break
+		}
+	}
+	if c.lib == "" {
+		return nil, fmt.Errorf("typescript %s: no lib file of ES2020 or later found", dir)
+	}
+	if err := c.start(); err != nil {
+		return nil, fmt.Errorf("typescript %s: %w", dir, err)
+	}
+	version, err := c.ctx.RunScript("ts.version", "version.js")
+	if err != nil {
+		c.Close()
+		return nil, fmt.Errorf("typescript %s: %w", dir, err)
+	}
+	c.version = version.String()
+	return c, nil
+}
+
+// Dir returns the absolute path of the typescript package.
+func (c *Checker) Dir() string {
+	return c.dir
+}
+
+// Version returns the version of the compiler, or "" for a nil Checker.
+func (c *Checker) Version() string {
+	if c == nil {
+		return ""
+	}
+	return c.version
+}
+
+// Close disposes the isolate.
+func (c *Checker) Close() {
+	if c == nil {
+		return
+	}
+	c.sem <- struct{}{}
+	defer func() { <-c.sem }()
+	c.stop()
+}
+
+// start creates the isolate and loads the compiler and the driver into it.
+func (c *Checker) start() error {
+	start := time.Now()
+	iso := v8.NewIsolate()
+	global := v8.NewObjectTemplate(iso)
+	read := v8.NewFunctionTemplate(iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
+		text, ok := c.readFile(info.Args()[0].String())
+		if !ok {
+			return v8.Undefined(iso)
+		}
+		val, _ := v8.NewValue(iso, text)
+		return val
+	})
+	stat := v8.NewFunctionTemplate(iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
+		val, _ := v8.NewValue(iso, int32(c.stat(info.Args()[0].String())))
+		return val
+	})
+	if err := global.Set("__wms_read", read); err != nil {
+		iso.Dispose()
+		return err
+	}
+	if err := global.Set("__wms_stat", stat); err != nil {
+		iso.Dispose()
+		return err
+	}
+	ctx := v8.NewContext(iso, global)
+	for _, script := range []struct{ source, name string }{
+		{c.source, "typescript.js"},
+		{driverJS, "driver.js"},
+	} {
+		if _, err := ctx.RunScript(script.source, script.name); err != nil {
+			ctx.Close()
+			iso.Dispose()
+			return fmt.Errorf("loading %s: %w", script.name, err)
+		}
+	}
+	c.iso, c.ctx = iso, ctx
+	slog.Debug("typescript compiler loaded", "dir", c.dir, "duration", time.Since(start))
+	return nil
+}
+
+func (c *Checker) stop() {
+	if c.iso == nil {
+		return
+	}
+	c.ctx.Close()
+	c.iso.Dispose()
+	c.iso, c.ctx = nil, nil
+}
+
+// hostPath maps a path of the virtual file system to the file it is read
+// from. Only the lib files of the compiler and the packages are on disk.
+func (c *Checker) hostPath(p string) (string, bool) {
+	if name, ok := strings.CutPrefix(p, libDir); ok {
+		if name == "" || strings.Contains(name, "/") {
+			return "", false
+		}
+		return filepath.Join(c.dir, "lib", name), true
+	}
+	if rest, ok := strings.CutPrefix(p, nodeModules); ok && c.modulesDir != "" {
+		// Clean keeps the path inside the packages, "../x" becomes "x".
+		return filepath.Join(c.modulesDir, filepath.FromSlash(path.Clean("/"+rest))), true
+	}
+	return "", false
+}
+
+func (c *Checker) readFile(p string) (string, bool) {
+	hp, ok := c.hostPath(p)
+	if !ok {
+		return "", false
+	}
+	data, err := os.ReadFile(hp)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// stat returns 1 for a file, 2 for a directory and 0 otherwise.
+func (c *Checker) stat(p string) int {
+	p = strings.TrimSuffix(p, "/")
+	if p+"/" == nodeModules && c.modulesDir != "" {
+		return 2
+	}
+	hp, ok := c.hostPath(p)
+	if !ok {
+		return 0
+	}
+	fi, err := os.Stat(hp)
+	switch {
+	case err != nil:
+		return 0
+	case fi.IsDir():
+		return 2
+	}
+	return 1
+}
+
+// request is the input of the driver's __wms_check.
+type request struct {
+	Files      map[string]string `json:"files"`
+	Roots      []string          `json:"roots"`
+	Lib        string            `json:"lib"`
+	ProjectDir string            `json:"projectDir"`
+}
+
+// diagnostic is a diagnostic reported by the driver.
+type diagnostic struct {
+	Category int    `json:"category"` // ts.DiagnosticCategory
+	Code     int    `json:"code"`
+	Message  string `json:"message"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`   // 0-based
+	Column   int    `json:"column"` // 0-based
+}
+
+// Check type checks the files of in and returns the diagnostics of the
+// compiler. Positions refer to the original files, the names are those of
+// in.Files. A check that does not finish in time is an error.
+func (c *Checker) Check(ctx context.Context, in Input) ([]executor.Diagnostic, error) {
+	if c == nil {
+		return nil, ErrNotConfigured
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+		defer cancel()
+	}
+	select {
+	case c.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-c.sem }()
+
+	if c.iso == nil {
+		if err := c.start(); err != nil {
+			return nil, err
+		}
+	}
+
+	req := request{
+		Files:      map[string]string{globalsFile: in.Declarations},
+		Roots:      []string{globalsFile},
+		Lib:        c.lib,
+		ProjectDir: strings.TrimSuffix(projectDir, "/"),
+	}
+	for _, f := range in.Files {
+		p := projectDir + strings.TrimPrefix(path.Clean("/"+f.Name), "/")
+		req.Files[p] = f.Content
+		req.Roots = append(req.Roots, p)
+	}
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := c.run(ctx, string(input))
+	if err != nil {
+		return nil, fmt.Errorf("type check: %w", err)
+	}
+	var diags []diagnostic
+	if err := json.Unmarshal([]byte(out), &diags); err != nil {
+		return nil, fmt.Errorf("type check: %w", err)
+	}
+	return toDiagnostics(diags), nil
+}
+
+// run calls the driver with input. The compiler cannot be interrupted other
+// than by terminating the isolate, which is then recreated by the next check.
+func (c *Checker) run(ctx context.Context, input string) (string, error) {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	iso := c.iso
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			iso.TerminateExecution()
+		case <-done:
+		}
+	}()
+
+	out, err := c.call(input)
+	close(done)
+	<-exited
+	switch {
+	case ctx.Err() != nil:
+		c.stop()
+		return "", ctx.Err()
+	case err != nil:
+		// The isolate may be in an arbitrary state after the compiler failed.
+		c.stop()
+		return "", err
+	case c.iso.GetHeapStatistics().UsedHeapSize > maxHeapBytes:
+		c.stop()
+	}
+	return out, nil
+}
+
+func (c *Checker) call(input string) (string, error) {
+	check, err := c.ctx.Global().Get("__wms_check")
+	if err != nil {
+		return "", err
+	}
+	fn, err := check.AsFunction()
+	if err != nil {
+		return "", err
+	}
+	arg, err := v8.NewValue(c.iso, input)
+	if err != nil {
+		return "", err
+	}
+	out, err := fn.Call(v8.Undefined(c.iso), arg)
+	if err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// toDiagnostics converts the compiler diagnostics. Diagnostics of the
+// declarations are reported without a file.
+func toDiagnostics(diags []diagnostic) []executor.Diagnostic {
+	result := make([]executor.Diagnostic, 0, len(diags))
+	for _, d := range diags {
+		sev := executor.SeverityInfo
+		switch d.Category {
+		case 0:
+			sev = executor.SeverityWarning
+		case 1:
+			sev = executor.SeverityError
+		}
+		diag := executor.Diagnostic{
+			Severity: sev,
+			Message:  fmt.Sprintf("%s (TS%d)", d.Message, d.Code),
+		}
+		if name, ok := strings.CutPrefix(d.File, projectDir); ok {
+			diag.Source = name
+			diag.Line = d.Line + 1
+			diag.Column = d.Column + 1
+		}
+		result = append(result, diag)
+	}
+	return result
+}
+
+// HasErrors reports whether diags contain an error.
+func HasErrors(diags []executor.Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == executor.SeverityError {
+			return true
+		}
+	}
+	return false
+}
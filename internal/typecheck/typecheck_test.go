@@ -0,0 +1,210 @@
+// Copyright (c) 2026 Michael Lechner. All rights reserved.
+package typecheck
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hmsoft0815/wollmilchsau/internal/executor"
+	"github.com/hmsoft0815/wollmilchsau/internal/parser"
+)
+
+// fakeTypeScript stands in for lib/typescript.js. It implements the part of
+// the compiler API used by the driver and reports
+//   - TS2322 for every `: number = "`,
+//   - TS2304 for wollmilchsau if the globals do not declare it,
+//   - TS2307 for packages without an index.d.ts,
+//   - TS6053 if the lib file cannot be read,
+//
+// and loops forever on "while (true) {}".
+const fakeTypeScript = `var ts = (function() {
+	function createSourceFile(fileName, text) {
+		return {
+			fileName, text,
+			getLineAndCharacterOfPosition(pos) {
+				const lines = text.slice(0, pos).split('\n');
+				return { line: lines.length - 1, character: lines[lines.length - 1].length };
+			},
+		};
+	}
+	function createProgram(roots, options, host) {
+		const files = roots.map((name) => host.getSourceFile(name, options.target));
+		const lib = host.getSourceFile(host.getDefaultLibLocation() + '/' + options.lib[0], options.target);
+		return { files, lib, host };
+	}
+	function getPreEmitDiagnostics(program) {
+		const diags = [];
+		if (!program.lib) diags.push({ category: 1, code: 6053, messageText: 'File not found: lib' });
+		const globals = program.files.some((f) => f.text.includes('declare var wollmilchsau'));
+		for (const file of program.files) {
+			const text = file.text;
+			for (const m of text.matchAll(/:\s*number\s*=\s*"/g)) {
+				diags.push({ file, start: m.index, category: 1, code: 2322,
+					messageText: { messageText: "Type 'string' is not assignable to type 'number'." } });
+			}
+			if (!globals && text.includes('wollmilchsau.')) {
+				diags.push({ file, start: text.indexOf('wollmilchsau.'), category: 1, code: 2304, messageText: "Cannot find name 'wollmilchsau'." });
+			}
+			for (const m of text.matchAll(/from "([^.][^"]*)"/g)) {
+				if (!program.host.fileExists('/project/node_modules/' + m[1] + '/index.d.ts')) {
+					diags.push({ file, start: m.index + 5, category: 1, code: 2307, messageText: "Cannot find module '" + m[1] + "'." });
+				}
+			}
+			if (text.includes('while (true) {}')) while (true) {}
+		}
+		return diags;
+	}
+	return {
+		version: '0.0.0-test',
+		ScriptTarget: { ES2020: 7 },
+		ModuleKind: { ESNext: 99 },
+		ModuleResolutionKind: { NodeJs: 2 },
+		createSourceFile,
+		createProgram,
+		getPreEmitDiagnostics,
+		flattenDiagnosticMessageText: (m) => typeof m === 'string' ? m : m.messageText,
+	};
+})();
+`
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func testChecker(t *testing.T) *Checker {
+	t.Helper()
+	tsDir, modulesDir := t.TempDir(), t.TempDir()
+	writeFiles(t, tsDir, map[string]string{
+		"lib/typescript.js":   fakeTypeScript,
+		"lib/lib.es2020.d.ts": "interface Array<T> {}",
+	})
+	writeFiles(t, modulesDir, map[string]string{"typed/index.d.ts": "export const x: number;"})
+	c, err := New(tsDir, modulesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestCheck(t *testing.T) {
+	c := testChecker(t)
+	if c.Version() != "0.0.0-test" {
+		t.Errorf("Version() = %q", c.Version())
+	}
+
+	in := Input{
+		Files: []parser.VirtualFile{
+			{Name: "main.ts", Content: "import { x } from \"typed\";\nimport { y } from \"untyped\";\nconst n: number = \"a\";\n"},
+			{Name: "lib/util.ts", Content: "export const ok = wollmilchsau.input;"},
+		},
+		Declarations: executor.Declarations(false),
+	}
+	diags, err := c.Check(context.Background(), in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range diags {
+		got = append(got, fmt.Sprintf("%s %s:%d:%d %s", d.Severity, d.Source, d.Line, d.Column, d.Message))
+	}
+	want := []string{
+		"error main.ts:3:8 Type 'string' is not assignable to type 'number'. (TS2322)",
+		"error main.ts:2:19 Cannot find module 'untyped'. (TS2307)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("diagnostics =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if !HasErrors(diags) {
+		t.Error("HasErrors = false")
+	}
+
+	// Without the declarations the sandbox globals are unknown.
+	in.Declarations = ""
+	diags, err = c.Check(context.Background(), in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diags) != 3 || diags[2].Source != "lib/util.ts" || !strings.Contains(diags[2].Message, "TS2304") {
+		t.Errorf("diagnostics without declarations = %+v", diags)
+	}
+
+	var nilChecker *Checker
+	if _, err := nilChecker.Check(context.Background(), in); err != ErrNotConfigured {
+		t.Errorf("nil Checker = %v", err)
+	}
+}
+
+func TestCheck_Timeout(t *testing.T) {
+	c := testChecker(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	loop := Input{Files: []parser.VirtualFile{{Name: "main.ts", Content: "while (true) {}"}}}
+	if _, err := c.Check(ctx, loop); err == nil || !strings.Contains(err.Error(), "deadline exceeded") {
+		t.Fatalf("Check of a looping compiler = %v", err)
+	}
+
+	// The next check gets a fresh isolate.
+	diags, err := c.Check(context.Background(), Input{Files: []parser.VirtualFile{{Name: "main.ts", Content: "const n = 1;"}}})
+	if err != nil || len(diags) != 0 {
+		t.Errorf("Check after a timeout = %v, %v", diags, err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(t.TempDir(), ""); err == nil || !strings.Contains(err.Error(), "typescript.js") {
+		t.Errorf("New without a compiler = %v", err)
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"lib/typescript.js": fakeTypeScript})
+	if _, err := New(dir, ""); err == nil || !strings.Contains(err.Error(), "no lib file") {
+		t.Errorf("New without lib files = %v", err)
+	}
+}
+
+// TestTypeScriptIntegration checks with the real compiler of the typescript
+// package in TYPESCRIPT_DIR, e.g. node_modules/typescript.
+func TestTypeScriptIntegration(t *testing.T) {
+	dir := os.Getenv("TYPESCRIPT_DIR")
+	if dir == "" {
+		t.Skip("Skipping integration test: TYPESCRIPT_DIR not set")
+	}
+	c, err := New(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	diags, err := c.Check(context.Background(), Input{
+		Files: []parser.VirtualFile{
+			{Name: "main.ts", Content: "import { twice } from \"./util\";\nconst x: number = \"a\";\n[1].nope();\nconsole.log(twice(x), wollmilchsau.inputText);\nawait Promise.resolve();\n"},
+			{Name: "util.ts", Content: "export const twice = (n: number) => n * 2;\n"},
+		},
+		Declarations: executor.Declarations(true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range diags {
+		got = append(got, fmt.Sprintf("%s:%d:%d %s", d.Source, d.Line, d.Column, d.Message))
+	}
+	if len(got) != 2 || !strings.HasPrefix(got[0], "main.ts:2:7 ") || !strings.Contains(got[0], "TS2322") ||
+		!strings.HasPrefix(got[1], "main.ts:3:5 ") || !strings.Contains(got[1], "TS2339") {
+		t.Errorf("diagnostics of %s =\n%s", c.Version(), strings.Join(got, "\n"))
+	}
+}